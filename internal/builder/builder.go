@@ -3,64 +3,94 @@ package builder
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/petroprotsakh/go-provider-mirror/internal/downloader"
+	"github.com/petroprotsakh/go-provider-mirror/internal/httpclient"
 	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
 	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
 	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
 	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
 	"github.com/petroprotsakh/go-provider-mirror/internal/resolver"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
 )
 
 type Config struct {
-	ManifestPath string
-	OutputDir    string
-	CacheDir     string
-	NoCache      bool
-	Concurrency  int
-	Retries      int
-	MaxBackoff   int // seconds
+	ManifestPath  string
+	ManifestPaths []string          // additional manifests merged with ManifestPath
+	Vars          map[string]string // values for ${VAR} references in manifests
+	OutputDir     string
+	CacheDir      string
+	NoCache       bool
+	Concurrency   int
+	Retries       int
+	MaxBackoff    int // seconds
+	Transport     httpclient.TransportConfig
+	Signer        signing.Signer // signs each version's SHA256SUMS; nil skips signing
 }
 
 type Builder struct {
-	config   Config
-	manifest *manifest.Manifest
-	client   *registry.Client
-	log      *logging.Logger
+	config    Config
+	manifest  *manifest.Manifest
+	hostAuth  map[string]httpclient.HostAuth
+	transport *httpclient.Transport
+	client    *registry.Client
+	log       *logging.Logger
 }
 
 // New creates a new builder
 func New(config Config) (*Builder, error) {
-	m, err := manifest.Load(config.ManifestPath)
+	m, err := manifest.LoadWithOptions(manifest.Options{Vars: config.Vars}, config.manifestPaths()...)
 	if err != nil {
 		return nil, fmt.Errorf("loading manifest: %w", err)
 	}
+	hostAuth, err := m.HostAuth()
+	if err != nil {
+		return nil, err
+	}
+	transport, err := config.Transport.Load()
+	if err != nil {
+		return nil, err
+	}
 
 	return &Builder{
-		config:   config,
-		manifest: m,
+		config:    config,
+		manifest:  m,
+		hostAuth:  hostAuth,
+		transport: transport,
 		client: registry.NewClient(&registry.Config{
 			Retries:    config.Retries,
 			MaxBackoff: time.Duration(config.MaxBackoff) * time.Second,
+			HostAuth:   hostAuth,
+			Transport:  transport,
 		}),
 		log: logging.Default(),
 	}, nil
 }
 
+// manifestPaths returns all manifest files to load
+func (c Config) manifestPaths() []string {
+	var paths []string
+	if c.ManifestPath != "" {
+		paths = append(paths, c.ManifestPath)
+	}
+	return append(paths, c.ManifestPaths...)
+}
+
 // Build executes the complete build process
 func (b *Builder) Build(ctx context.Context) error {
 	log := b.log
 
 	// Header info
 	if log.IsNormal() {
-		log.Print("Building mirror from %s\n", b.config.ManifestPath)
+		log.Print("Building mirror from %s\n", strings.Join(b.config.manifestPaths(), ", "))
 		log.Print("Output directory: %s\n", b.config.OutputDir)
 		log.Print("Providers: %d\n", len(b.manifest.Providers))
 		log.Println()
 	} else {
 		log.Info("starting mirror build",
-			"manifest", b.config.ManifestPath,
+			"manifest", b.config.manifestPaths(),
 			"output", b.config.OutputDir,
 			"providers", len(b.manifest.Providers),
 		)
@@ -105,6 +135,17 @@ func (b *Builder) Build(ctx context.Context) error {
 		)
 	}
 
+	for _, w := range resolution.Warnings {
+		if log.IsNormal() {
+			log.Print("  Warning: %s\n", w)
+		} else {
+			log.Warn(w)
+		}
+	}
+	if log.IsNormal() && len(resolution.Warnings) > 0 {
+		log.Println()
+	}
+
 	// Log resolved versions in verbose mode
 	for _, p := range resolution.Providers {
 		for _, v := range p.Versions {
@@ -125,16 +166,7 @@ func (b *Builder) Build(ctx context.Context) error {
 
 	startDownload := time.Now()
 
-	dl := downloader.New(
-		downloader.Config{
-			CacheDir:     b.config.CacheDir,
-			NoCache:      b.config.NoCache,
-			Concurrency:  b.config.Concurrency,
-			Retries:      b.config.Retries,
-			MaxBackoff:   time.Duration(b.config.MaxBackoff) * time.Second,
-			ShowProgress: log.ShowProgress(),
-		}, b.client,
-	)
+	dl := downloader.New(b.downloaderConfig(), b.client)
 
 	results, err := dl.Download(ctx, resolution)
 
@@ -196,7 +228,7 @@ func (b *Builder) Build(ctx context.Context) error {
 
 	startWrite := time.Now()
 
-	writer := mirror.NewWriter(b.config.OutputDir)
+	writer := mirror.NewWriter(b.config.OutputDir).WithSigner(b.config.Signer)
 	if err := writer.Write(ctx, results); err != nil {
 		// Check for cancellation
 		if ctx.Err() != nil {
@@ -217,7 +249,11 @@ func (b *Builder) Build(ctx context.Context) error {
 	if log.IsNormal() {
 		log.Println("Mirror contents:")
 		for _, p := range resolution.Providers {
-			log.Print("  %s\n", p.Source.String())
+			if p.PublishAs != (manifest.ProviderSource{}) {
+				log.Print("  %s (from %s)\n", p.PublishAs.String(), p.Source.String())
+			} else {
+				log.Print("  %s\n", p.Source.String())
+			}
 			for _, v := range p.Versions {
 				log.Print("    %s (%d platforms)\n", v.Version, len(v.Platforms))
 			}
@@ -227,7 +263,7 @@ func (b *Builder) Build(ctx context.Context) error {
 		for _, p := range resolution.Providers {
 			for _, v := range p.Versions {
 				log.Verbose("mirror includes",
-					"provider", p.Source.String(),
+					"provider", p.Address().String(),
 					"version", v.Version,
 					"platforms", len(v.Platforms),
 				)
@@ -243,3 +279,17 @@ func (b *Builder) Build(ctx context.Context) error {
 
 	return nil
 }
+
+// downloaderConfig returns the configuration of the archive downloader
+func (b *Builder) downloaderConfig() downloader.Config {
+	return downloader.Config{
+		CacheDir:     b.config.CacheDir,
+		NoCache:      b.config.NoCache,
+		Concurrency:  b.config.Concurrency,
+		Retries:      b.config.Retries,
+		MaxBackoff:   time.Duration(b.config.MaxBackoff) * time.Second,
+		ShowProgress: b.log.ShowProgress(),
+		HostAuth:     b.hostAuth,
+		Transport:    b.transport,
+	}
+}
@@ -1,11 +1,21 @@
 package builder
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/httpclient"
 )
 
 // --- Config tests ---
@@ -377,6 +387,38 @@ providers:
 
 // --- Context cancellation tests ---
 
+func TestNew_ArchiveHostAuth(t *testing.T) {
+	tmpDir := t.TempDir()
+	manifestPath := filepath.Join(tmpDir, "manifest.yaml")
+
+	content := `
+defaults:
+  engines: [terraform]
+  platforms: [linux_amd64]
+auth:
+  artifacts.corp.example:
+    token_env: ARTIFACTS_TOKEN
+providers:
+  - source: hashicorp/null
+    versions: ["3.2.4"]
+`
+	if err := os.WriteFile(manifestPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	t.Setenv("ARTIFACTS_TOKEN", "secret")
+
+	b, err := New(Config{ManifestPath: manifestPath})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Archives are downloaded with the credentials, not only registry requests
+	auth, ok := b.downloaderConfig().HostAuth["artifacts.corp.example"]
+	if !ok || auth != httpclient.BearerAuth("secret") {
+		t.Errorf("expected archive credentials in the downloader config, got %+v", b.downloaderConfig().HostAuth)
+	}
+}
+
 func TestBuild_ContextCancelled(t *testing.T) {
 	tmpDir := t.TempDir()
 	manifestPath := filepath.Join(tmpDir, "manifest.yaml")
@@ -414,3 +456,99 @@ providers:
 		t.Errorf("expected context.Canceled, got %v", err)
 	}
 }
+
+// --- Build tests ---
+
+func TestBuild_ArchiveHostAuthAndTLS(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, _ := zw.Create("terraform-provider-null_v3.2.4")
+	_, _ = f.Write([]byte("binary"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+	sum := sha256.Sum256(archive)
+
+	// The archive host only accepts the configured credentials
+	var archiveAuth string
+	archives := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		archiveAuth = r.Header.Get("Authorization")
+		if archiveAuth != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(archive)
+	}))
+	defer archives.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"providers.v1": "/v1/providers/"}`))
+	})
+	mux.HandleFunc("/v1/providers/hashicorp/null/versions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions": [{"version": "3.2.4", "protocols": ["5.0"],
+			"platforms": [{"os": "linux", "arch": "amd64"}]}]}`))
+	})
+	mux.HandleFunc("/v1/providers/hashicorp/null/3.2.4/download/linux/amd64", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"os":           "linux",
+			"arch":         "amd64",
+			"filename":     "terraform-provider-null_3.2.4_linux_amd64.zip",
+			"download_url": archives.URL + "/terraform-provider-null_3.2.4_linux_amd64.zip",
+			"shasum":       hex.EncodeToString(sum[:]),
+		})
+	})
+	registry := httptest.NewTLSServer(mux)
+	defer registry.Close()
+
+	// Both test servers use the same certificate
+	tmpDir := t.TempDir()
+	caFile := filepath.Join(tmpDir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: registry.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	registryHost := registry.Listener.Addr().String()
+	archiveHost := archives.Listener.Addr().String()
+	manifestPath := filepath.Join(tmpDir, "manifest.yaml")
+	content := `
+defaults:
+  engines: [terraform]
+  platforms: [linux_amd64]
+auth:
+  "` + archiveHost + `":
+    token_env: ARTIFACTS_TOKEN
+providers:
+  - source: ` + registryHost + `/hashicorp/null
+    versions: ["3.2.4"]
+`
+	if err := os.WriteFile(manifestPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	t.Setenv("ARTIFACTS_TOKEN", "secret")
+
+	outputDir := filepath.Join(tmpDir, "mirror")
+	b, err := New(Config{
+		ManifestPath: manifestPath,
+		OutputDir:    outputDir,
+		CacheDir:     filepath.Join(tmpDir, "cache"),
+		Retries:      1,
+		Transport:    httpclient.TransportConfig{TLS: httpclient.TLSConfig{CAFile: caFile}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := b.Build(context.Background()); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if archiveAuth != "Bearer secret" {
+		t.Errorf("expected the archive host to receive the credentials, got %q", archiveAuth)
+	}
+	archivePath := filepath.Join(outputDir, registryHost, "hashicorp", "null", "terraform-provider-null_3.2.4_linux_amd64.zip")
+	if _, err := os.Stat(archivePath); err != nil {
+		t.Errorf("expected the archive in the mirror: %v", err)
+	}
+}
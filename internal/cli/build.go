@@ -13,13 +13,16 @@ import (
 )
 
 type buildOptions struct {
-	manifestPath string
-	outputDir    string
-	cacheDir     string
-	noCache      bool
-	concurrency  int
-	retries      int
-	maxBackoff   int
+	manifestPaths []string
+	outputDir     string
+	cacheDir      string
+	noCache       bool
+	concurrency   int
+	retries       int
+	maxBackoff    int
+	vars          varOptions
+	network       networkOptions
+	signing       signingOptions
 }
 
 func newBuildCommand() *cobra.Command {
@@ -32,10 +35,18 @@ func newBuildCommand() *cobra.Command {
 binaries, and generating the filesystem layout.
 
 The build is atomic: either it succeeds completely or produces no output.
-Downloads are cached for efficient re-runs.`,
+Downloads are cached for efficient re-runs.
+
+Upstream SHA256SUMS signatures are verified and kept in the mirror. With
+--signing-key or --gpg-key, each version also gets a SHA256SUMS file signed
+with the organization key, whose ID is recorded in mirror.lock, and
+mirror.lock itself gets a detached signature (mirror.lock.sig).`,
 		Example: `  # Build a mirror from manifest
   provider-mirror build --manifest mirror.yaml --output ./mirror
 
+  # Merge several manifests into one mirror
+  provider-mirror build --manifest base.yaml --manifest teams/net.yaml --output ./mirror
+
   # Build with custom cache directory
   provider-mirror build --manifest mirror.yaml --output ./mirror --cache-dir /tmp/provider-cache
 
@@ -43,18 +54,26 @@ Downloads are cached for efficient re-runs.`,
   provider-mirror build --manifest mirror.yaml --output ./mirror --no-cache
 
   # Build with increased parallelism
-  provider-mirror build --manifest mirror.yaml --output ./mirror --concurrency 8`,
+  provider-mirror build --manifest mirror.yaml --output ./mirror --concurrency 8
+
+  # Trust a corporate CA and authenticate to an internal registry with mutual TLS
+  provider-mirror build --ca-file corp-ca.pem \
+    --host-client-cert registry.corp.example=client.pem \
+    --host-client-key registry.corp.example=client-key.pem
+
+  # Sign the mirrored checksums with a key held by the gpg agent
+  provider-mirror build --manifest mirror.yaml --output ./mirror --gpg-key releases@corp.example`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runBuild(cmd.Context(), opts)
 		},
 	}
 
-	cmd.Flags().StringVarP(
-		&opts.manifestPath,
+	cmd.Flags().StringSliceVarP(
+		&opts.manifestPaths,
 		"manifest",
 		"m",
-		"mirror.yaml",
-		"Path to the manifest file",
+		[]string{"mirror.yaml"},
+		"Path to the manifest file (repeat to merge several manifests)",
 	)
 	cmd.Flags().StringVarP(
 		&opts.outputDir,
@@ -78,6 +97,9 @@ Downloads are cached for efficient re-runs.`,
 	cmd.Flags().IntVar(&opts.concurrency, "concurrency", 8, "Number of parallel downloads")
 	cmd.Flags().IntVar(&opts.retries, "retries", 3, "Number of retries for failed downloads")
 	cmd.Flags().IntVar(&opts.maxBackoff, "max-backoff", 60, "Maximum backoff time in seconds")
+	opts.vars.addFlags(cmd)
+	opts.network.addFlags(cmd)
+	opts.signing.addFlags(cmd, "signing the mirrored SHA256SUMS and mirror.lock")
 
 	return cmd
 }
@@ -86,14 +108,32 @@ func runBuild(ctx context.Context, opts *buildOptions) error {
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	manifestOpts, err := opts.vars.manifestOptions()
+	if err != nil {
+		return err
+	}
+
+	transport, err := opts.network.transportConfig()
+	if err != nil {
+		return err
+	}
+
+	signer, err := opts.signing.signer()
+	if err != nil {
+		return err
+	}
+
 	cfg := builder.Config{
-		ManifestPath: opts.manifestPath,
-		OutputDir:    opts.outputDir,
-		CacheDir:     opts.cacheDir,
-		NoCache:      opts.noCache,
-		Concurrency:  opts.concurrency,
-		Retries:      opts.retries,
-		MaxBackoff:   opts.maxBackoff,
+		ManifestPaths: opts.manifestPaths,
+		Vars:          manifestOpts.Vars,
+		OutputDir:     opts.outputDir,
+		CacheDir:      opts.cacheDir,
+		NoCache:       opts.noCache,
+		Concurrency:   opts.concurrency,
+		Retries:       opts.retries,
+		MaxBackoff:    opts.maxBackoff,
+		Transport:     transport,
+		Signer:        signer,
 	}
 
 	b, err := builder.New(cfg)
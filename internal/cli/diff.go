@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/lockdiff"
+	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+)
+
+func newDiffCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff <old> <new>",
+		Short: "Compare two mirror lock files",
+		Long: `Diff lists the providers, versions and platforms added to or removed from
+a mirror between two lock files. Each argument is a mirror.lock file or a
+mirror directory.
+
+An archive whose sha256 or h1 hash changed for the same version means the
+release was published again upstream. Such changes are marked with "!" and
+make the command fail.
+
+The generation time and manifest sources are ignored.`,
+		Example: `  # Review a rebuilt mirror
+  provider-mirror diff old/mirror.lock new/mirror.lock
+
+  # Compare mirror directories
+  provider-mirror diff ./mirror ./mirror.new`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiff(args[0], args[1])
+		},
+	}
+}
+
+func runDiff(oldPath, newPath string) error {
+	oldLock, err := readLockFileArg(oldPath)
+	if err != nil {
+		return err
+	}
+	newLock, err := readLockFileArg(newPath)
+	if err != nil {
+		return err
+	}
+
+	d := lockdiff.Compare(oldLock, newLock)
+
+	log := logging.Default()
+	if log.IsNormal() {
+		for _, line := range formatLockDiff(d) {
+			if _, err := fmt.Fprintln(os.Stdout, line); err != nil {
+				return err
+			}
+		}
+		if d.Empty() {
+			log.Println("✓ No differences")
+		}
+	} else {
+		logLockDiff(d)
+	}
+
+	if n := len(d.ChecksumChanges()); n > 0 {
+		return fmt.Errorf("%d checksum(s) changed for versions present in both lock files", n)
+	}
+	return nil
+}
+
+// readLockFileArg reads a lock file, given its path or its mirror directory
+func readLockFileArg(path string) (*mirror.LockFile, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, mirror.LockFileName)
+	}
+	return mirror.ReadLockFile(path)
+}
+
+// formatLockDiff renders a lock file diff as text, one line per change
+func formatLockDiff(d *lockdiff.Diff) []string {
+	var lines []string
+	for _, p := range d.Providers {
+		lines = append(lines, fmt.Sprintf("%s %s", statusMark(p.Status), p.Source))
+
+		for _, v := range p.Versions {
+			switch v.Status {
+			case lockdiff.StatusAdded, lockdiff.StatusRemoved:
+				lines = append(
+					lines, fmt.Sprintf("    %s %s (%s)", statusMark(v.Status), v.Version, strings.Join(v.Platforms, ", ")),
+				)
+				continue
+			}
+
+			var platforms []string
+			for _, platform := range v.AddedPlatforms {
+				platforms = append(platforms, "+"+platform)
+			}
+			for _, platform := range v.RemovedPlatforms {
+				platforms = append(platforms, "-"+platform)
+			}
+			if len(platforms) > 0 {
+				lines = append(lines, fmt.Sprintf("    ~ %s: %s", v.Version, strings.Join(platforms, " ")))
+			}
+			for _, c := range v.Checksums {
+				lines = append(
+					lines, fmt.Sprintf("    ! %s %s %s changed: %s → %s", v.Version, c.Platform, c.Field, c.Old, c.New),
+				)
+			}
+		}
+	}
+	return lines
+}
+
+// logLockDiff logs a lock file diff as structured entries
+func logLockDiff(d *lockdiff.Diff) {
+	log := logging.Default()
+	for _, p := range d.Providers {
+		for _, v := range p.Versions {
+			log.Info(
+				"lock difference",
+				"provider", p.Source,
+				"version", v.Version,
+				"status", v.Status,
+				"platforms", v.Platforms,
+				"added_platforms", v.AddedPlatforms,
+				"removed_platforms", v.RemovedPlatforms,
+			)
+			for _, c := range v.Checksums {
+				log.Warn(
+					"checksum changed",
+					"provider", p.Source,
+					"version", v.Version,
+					"platform", c.Platform,
+					"field", c.Field,
+					"old", c.Old,
+					"new", c.New,
+				)
+			}
+		}
+	}
+}
+
+// statusMark returns the diff marker for a status
+func statusMark(s lockdiff.Status) string {
+	switch s {
+	case lockdiff.StatusAdded:
+		return "+"
+	case lockdiff.StatusRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
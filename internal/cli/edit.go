@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+)
+
+// addManifestFlag registers the --manifest flag of the editing commands
+func addManifestFlag(cmd *cobra.Command, path *string) {
+	cmd.Flags().StringVarP(
+		path,
+		"manifest",
+		"m",
+		"mirror.yaml",
+		"Path to the manifest file to edit",
+	)
+}
+
+type addOptions struct {
+	manifestPath string
+	versions     []string
+	platforms    []string
+	engines      []string
+}
+
+func newAddCommand() *cobra.Command {
+	opts := &addOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "add <source>",
+		Short: "Add a provider to the manifest",
+		Long: `Add a provider block to a YAML manifest, preserving its comments and layout.
+
+If the provider is already in the manifest, the given version constraints
+are added to its existing block.`,
+		Example: `  # Add a provider
+  provider-mirror add hashicorp/google --version "~> 6.0" --platform linux_amd64
+
+  # Add another version line to an existing provider
+  provider-mirror add hashicorp/aws --version "~> 4.0"`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAdd(args[0], opts)
+		},
+	}
+
+	addManifestFlag(cmd, &opts.manifestPath)
+	cmd.Flags().StringArrayVar(&opts.versions, "version", nil, "Version constraint (repeatable, required)")
+	cmd.Flags().StringArrayVar(&opts.platforms, "platform", nil, "Platform, pattern or platform set (repeatable)")
+	cmd.Flags().StringArrayVar(&opts.engines, "engine", nil, "Engine: terraform or opentofu (repeatable)")
+	_ = cmd.MarkFlagRequired("version")
+
+	return cmd
+}
+
+func runAdd(source string, opts *addOptions) error {
+	e, err := manifest.OpenEditor(opts.manifestPath)
+	if err != nil {
+		return err
+	}
+
+	p := manifest.Provider{
+		Source:    source,
+		Versions:  opts.versions,
+		Platforms: opts.platforms,
+	}
+	for _, engine := range opts.engines {
+		p.Engines = append(p.Engines, manifest.Engine(engine))
+	}
+
+	if err := e.AddProvider(p); err != nil {
+		return err
+	}
+	if err := e.Save(); err != nil {
+		return err
+	}
+
+	log := logging.Default()
+	if log.IsNormal() {
+		log.Print("✓ Added %s to %s\n", source, opts.manifestPath)
+	} else {
+		log.Info("provider added", "source", source, "manifest", opts.manifestPath)
+	}
+	return nil
+}
+
+type removeOptions struct {
+	manifestPath string
+}
+
+func newRemoveCommand() *cobra.Command {
+	opts := &removeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "remove <source>...",
+		Short: "Remove providers from the manifest",
+		Long: `Remove every block of the given providers from a YAML manifest,
+together with the comments directly above them.`,
+		Example: `  # Remove a provider
+  provider-mirror remove hashicorp/null`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRemove(args, opts)
+		},
+	}
+
+	addManifestFlag(cmd, &opts.manifestPath)
+
+	return cmd
+}
+
+func runRemove(sources []string, opts *removeOptions) error {
+	e, err := manifest.OpenEditor(opts.manifestPath)
+	if err != nil {
+		return err
+	}
+
+	log := logging.Default()
+
+	for _, source := range sources {
+		n, err := e.RemoveProvider(source)
+		if err != nil {
+			return err
+		}
+		if log.IsNormal() {
+			log.Print("✓ Removed %s (%d block(s))\n", source, n)
+		} else {
+			log.Info("provider removed", "source", source, "blocks", n)
+		}
+	}
+
+	return e.Save()
+}
+
+type pinOptions struct {
+	manifestPath string
+	mirrorDir    string
+	lockPath     string
+}
+
+func newPinCommand() *cobra.Command {
+	opts := &pinOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "pin [source...]",
+		Short: "Pin manifest constraints to the versions in mirror.lock",
+		Long: `Rewrite each version constraint in a YAML manifest to the exact version
+currently recorded in mirror.lock.
+
+Without arguments every provider is pinned. A constraint that resolved to
+different versions on different registries is left unchanged and reported.`,
+		Example: `  # Pin every provider to the mirrored versions
+  provider-mirror pin --mirror ./mirror
+
+  # Pin a single provider
+  provider-mirror pin hashicorp/aws`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPin(args, opts)
+		},
+	}
+
+	addManifestFlag(cmd, &opts.manifestPath)
+	cmd.Flags().StringVar(&opts.mirrorDir, "mirror", "./mirror", "Path to the mirror directory")
+	cmd.Flags().StringVar(&opts.lockPath, "lock", "", "Path to the lock file (default <mirror>/mirror.lock)")
+
+	return cmd
+}
+
+func runPin(sources []string, opts *pinOptions) error {
+	lockPath := opts.lockPath
+	if lockPath == "" {
+		lockPath = filepath.Join(opts.mirrorDir, mirror.LockFileName)
+	}
+
+	lockFile, err := mirror.ReadLockFile(lockPath)
+	if err != nil {
+		return err
+	}
+
+	e, err := manifest.OpenEditor(opts.manifestPath)
+	if err != nil {
+		return err
+	}
+
+	changes, pinErr := e.RewriteVersions(lockFile.PinnedVersion, sources...)
+	if len(changes) > 0 {
+		if err := e.Save(); err != nil {
+			return err
+		}
+	}
+
+	log := logging.Default()
+	for _, c := range changes {
+		if log.IsNormal() {
+			log.Print("  %s: %s → %s\n", c.Source, c.From, c.To)
+		} else {
+			log.Info("constraint pinned", "source", c.Source, "from", c.From, "to", c.To)
+		}
+	}
+
+	if pinErr != nil {
+		return fmt.Errorf("some constraints were not pinned:\n%w", pinErr)
+	}
+
+	if log.IsNormal() {
+		log.Print("✓ Pinned %d constraint(s) in %s\n", len(changes), opts.manifestPath)
+	}
+	return nil
+}
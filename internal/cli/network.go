@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/httpclient"
+)
+
+// networkOptions holds the proxy and TLS flags shared by commands that
+// download from registries
+type networkOptions struct {
+	proxy          string
+	caFile         string
+	clientCert     string
+	clientKey      string
+	hostCAFiles    []string
+	hostClientCert []string
+	hostClientKey  []string
+}
+
+func (o *networkOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&o.proxy, "proxy", "",
+		"Proxy URL for all requests (default: HTTPS_PROXY, HTTP_PROXY and NO_PROXY)",
+	)
+	cmd.Flags().StringVar(&o.caFile, "ca-file", "", "PEM CA bundle trusted in addition to the system roots")
+	cmd.Flags().StringVar(&o.clientCert, "client-cert", "", "PEM client certificate for mutual TLS")
+	cmd.Flags().StringVar(&o.clientKey, "client-key", "", "PEM private key of the client certificate")
+	cmd.Flags().StringArrayVar(
+		&o.hostCAFiles, "host-ca-file", nil,
+		"CA bundle for one host (host=path, repeatable)",
+	)
+	cmd.Flags().StringArrayVar(
+		&o.hostClientCert, "host-client-cert", nil,
+		"Client certificate for one host (host=path, repeatable)",
+	)
+	cmd.Flags().StringArrayVar(
+		&o.hostClientKey, "host-client-key", nil,
+		"Client key for one host (host=path, repeatable)",
+	)
+}
+
+// transport loads the proxy and TLS settings for the given flags
+func (o *networkOptions) transport() (*httpclient.Transport, error) {
+	cfg, err := o.transportConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Load()
+}
+
+// transportConfig returns the transport configuration for the given flags
+func (o *networkOptions) transportConfig() (httpclient.TransportConfig, error) {
+	cfg := httpclient.TransportConfig{
+		Proxy: o.proxy,
+		TLS: httpclient.TLSConfig{
+			CAFile:   o.caFile,
+			CertFile: o.clientCert,
+			KeyFile:  o.clientKey,
+		},
+	}
+
+	hostFlags := []struct {
+		name   string
+		values []string
+		set    func(*httpclient.TLSConfig, string)
+	}{
+		{"--host-ca-file", o.hostCAFiles, func(c *httpclient.TLSConfig, path string) { c.CAFile = path }},
+		{"--host-client-cert", o.hostClientCert, func(c *httpclient.TLSConfig, path string) { c.CertFile = path }},
+		{"--host-client-key", o.hostClientKey, func(c *httpclient.TLSConfig, path string) { c.KeyFile = path }},
+	}
+	for _, flag := range hostFlags {
+		for _, kv := range flag.values {
+			host, path, ok := strings.Cut(kv, "=")
+			if !ok || host == "" || path == "" {
+				return cfg, fmt.Errorf("invalid %s %q: expected host=path", flag.name, kv)
+			}
+			if cfg.HostTLS == nil {
+				cfg.HostTLS = make(map[string]httpclient.TLSConfig)
+			}
+			hostTLS := cfg.HostTLS[strings.ToLower(host)]
+			flag.set(&hostTLS, path)
+			cfg.HostTLS[strings.ToLower(host)] = hostTLS
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+	"github.com/petroprotsakh/go-provider-mirror/internal/outdated"
+)
+
+type outdatedOptions struct {
+	manifestPaths []string
+	mirrorDir     string
+	json          bool
+	vars          varOptions
+	network       networkOptions
+}
+
+func newOutdatedCommand() *cobra.Command {
+	opts := &outdatedOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "outdated",
+		Short: "Report providers with newer upstream versions",
+		Long: `Outdated compares the versions in the mirror with the upstream registries.
+
+For every version constraint in the manifest it shows:
+- CURRENT: the newest matching version in the mirror's lock file
+- WANTED:  the newest upstream version satisfying the constraint
+- LATEST:  the newest upstream release, even outside the constraint
+
+The report is written to stdout.`,
+		Example: `  # Show which providers are falling behind
+  provider-mirror outdated --manifest mirror.yaml --mirror ./mirror
+
+  # Machine-readable report
+  provider-mirror outdated --manifest mirror.yaml --json > outdated.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOutdated(cmd.Context(), opts, cmd.Flags().Changed("mirror"))
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(
+		&opts.manifestPaths,
+		"manifest",
+		"m",
+		[]string{"mirror.yaml"},
+		"Path to the manifest file (repeat to merge several manifests)",
+	)
+	cmd.Flags().StringVar(&opts.mirrorDir, "mirror", "./mirror", "Path to the mirror directory")
+	cmd.Flags().BoolVar(&opts.json, "json", false, "Write the report as JSON")
+	opts.vars.addFlags(cmd)
+	opts.network.addFlags(cmd)
+
+	return cmd
+}
+
+func runOutdated(ctx context.Context, opts *outdatedOptions, mirrorRequired bool) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	manifestOpts, err := opts.vars.manifestOptions()
+	if err != nil {
+		return err
+	}
+	transport, err := opts.network.transport()
+	if err != nil {
+		return err
+	}
+
+	// Without a mirror every constraint is reported as not mirrored,
+	// unless the mirror was asked for explicitly
+	lockFile, err := mirror.ReadLockFile(filepath.Join(opts.mirrorDir, mirror.LockFileName))
+	if err != nil {
+		if mirrorRequired || !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		lockFile = nil
+	}
+
+	c, err := outdated.New(manifestOpts, transport, opts.manifestPaths...)
+	if err != nil {
+		return err
+	}
+
+	report, err := c.Check(ctx, lockFile)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return writeJSON(report)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PROVIDER\tCONSTRAINT\tCURRENT\tWANTED\tLATEST\tSTATUS")
+	for _, e := range report.Providers {
+		_, _ = fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Source, e.Constraint, orDash(e.Current), orDash(e.Wanted), orDash(e.Latest), e.Status,
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	log := logging.Default()
+	if log.IsNormal() {
+		log.Print("\n%d of %d constraint(s) are not up to date\n", report.Outdated(), len(report.Providers))
+	} else {
+		log.Info("outdated check complete", "constraints", len(report.Providers), "outdated", report.Outdated())
+	}
+
+	return nil
+}
+
+// orDash returns s, or "-" if it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -2,18 +2,29 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/spf13/cobra"
 
+	"github.com/petroprotsakh/go-provider-mirror/internal/lockdiff"
 	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
 	"github.com/petroprotsakh/go-provider-mirror/internal/planner"
+	"github.com/petroprotsakh/go-provider-mirror/internal/resolver"
 )
 
 type planOptions struct {
-	manifestPath string
+	manifestPaths []string
+	against       string
+	json          bool
+	vars          varOptions
+	network       networkOptions
 }
 
 func newPlanCommand() *cobra.Command {
@@ -25,21 +36,45 @@ func newPlanCommand() *cobra.Command {
 		Long: `Plan resolves provider versions and shows what would be downloaded
 without actually downloading anything.
 
-Use this to preview the build before committing to it.`,
+Use this to preview the build before committing to it.
+
+--json writes the plan with a resolution trace: for every manifest constraint
+on every registry, the versions the registry returned, the versions matching,
+the rejected versions with reasons and the selected version. The trace is
+also written when resolution fails, and logged at -vv. With --against, the
+report includes the changes under "changes".`,
 		Example: `  # Preview what will be downloaded
-  provider-mirror plan --manifest mirror.yaml`,
+  provider-mirror plan --manifest mirror.yaml
+
+  # Preview with manifest variables
+  provider-mirror plan --manifest mirror.yaml --var-file prod.yaml --var AWS_VERSION=5.1.0
+
+  # Write a detailed report with the resolution trace
+  provider-mirror plan --manifest mirror.yaml --json > plan.json
+
+  # Show what a rebuild would change in an existing mirror
+  provider-mirror plan --manifest mirror.yaml --against ./mirror`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runPlan(cmd.Context(), opts)
 		},
 	}
 
-	cmd.Flags().StringVarP(
-		&opts.manifestPath,
+	cmd.Flags().StringSliceVarP(
+		&opts.manifestPaths,
 		"manifest",
 		"m",
-		"mirror.yaml",
-		"Path to the manifest file",
+		[]string{"mirror.yaml"},
+		"Path to the manifest file (repeat to merge several manifests)",
 	)
+	cmd.Flags().StringVar(
+		&opts.against,
+		"against",
+		"",
+		"Compare the plan with an existing mirror directory or mirror.lock file",
+	)
+	cmd.Flags().BoolVar(&opts.json, "json", false, "Write the plan and resolution trace as JSON to stdout")
+	opts.vars.addFlags(cmd)
+	opts.network.addFlags(cmd)
 
 	return cmd
 }
@@ -48,25 +83,105 @@ func runPlan(ctx context.Context, opts *planOptions) error {
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	p, err := planner.New(opts.manifestPath)
+	manifestOpts, err := opts.vars.manifestOptions()
+	if err != nil {
+		return err
+	}
+	transport, err := opts.network.transport()
 	if err != nil {
 		return err
 	}
 
-	plan, err := p.Plan(ctx)
+	var current *mirror.LockFile
+	if opts.against != "" {
+		if current, err = readLockFileArg(opts.against); err != nil {
+			return err
+		}
+	}
+
+	p, err := planner.New(manifestOpts, transport, opts.manifestPaths...)
 	if err != nil {
 		return err
 	}
 
 	log := logging.Default()
+
+	plan, err := p.Plan(ctx)
+	if err != nil {
+		// Show how far resolution got before failing
+		var resErr *resolver.ResolutionError
+		if errors.As(err, &resErr) {
+			trace := planner.NewTrace(resErr.Decisions)
+			logTrace(trace)
+			if opts.json {
+				report := struct {
+					Error string               `json:"error"`
+					Trace []planner.TraceEntry `json:"trace"`
+				}{err.Error(), trace}
+				if jsonErr := writeJSON(report); jsonErr != nil {
+					return jsonErr
+				}
+			}
+		}
+		return err
+	}
+	logTrace(plan.Trace)
+
+	for _, w := range plan.Warnings {
+		if log.IsNormal() {
+			log.Print("Warning: %s\n", w)
+		} else {
+			log.Warn(w)
+		}
+	}
+	if log.IsNormal() && len(plan.Warnings) > 0 {
+		log.Println()
+	}
+
+	var changes *lockdiff.Diff
+	if current != nil {
+		changes = lockdiff.Compare(current, plan.LockFile())
+	}
+
+	if opts.json {
+		if changes == nil {
+			return writeJSON(plan)
+		}
+		if changes.Providers == nil {
+			changes.Providers = []lockdiff.ProviderDiff{}
+		}
+		report := struct {
+			*planner.Plan
+			Changes *lockdiff.Diff `json:"changes"`
+		}{plan, changes}
+		return writeJSON(report)
+	}
+
 	if log.IsNormal() {
 		log.Print("Plan: %d providers, %d versions, %d downloads\n\n",
 			len(plan.Providers), plan.TotalVersions, plan.TotalDownloads)
 
 		for _, prov := range plan.Providers {
-			log.Print("  %s\n", prov.Source)
+			if prov.PublishAs != "" {
+				log.Print("  %s (from %s)\n", prov.PublishAs, prov.Source)
+			} else {
+				log.Print("  %s\n", prov.Source)
+			}
 			for _, v := range prov.Versions {
 				log.Print("    %s (%d platforms)\n", v.Version, len(v.Platforms))
+				if len(v.MissingPlatforms) > 0 {
+					log.Print("      missing platforms: %s\n", strings.Join(v.MissingPlatforms, ", "))
+				}
+				if len(v.FallbackFrom) > 0 {
+					log.Print("      fallback from: %s\n", strings.Join(v.FallbackFrom, ", "))
+				}
+			}
+			for _, sv := range prov.Skipped {
+				status := "skipped"
+				if sv.HeldBack {
+					status = "held back"
+				}
+				log.Print("    %s %s: %s\n", sv.Version, status, sv.Reason)
 			}
 		}
 	} else {
@@ -80,12 +195,74 @@ func runPlan(ctx context.Context, opts *planOptions) error {
 			for _, v := range prov.Versions {
 				log.Verbose("would download",
 					"provider", prov.Source,
+					"publish_as", prov.PublishAs,
 					"version", v.Version,
 					"platforms", v.Platforms,
+					"missing_platforms", v.MissingPlatforms,
+					"fallback_from", v.FallbackFrom,
+				)
+			}
+			for _, sv := range prov.Skipped {
+				log.Info("skipped version",
+					"provider", prov.Source,
+					"version", sv.Version,
+					"constraint", sv.Constraint,
+					"held_back", sv.HeldBack,
+					"reason", sv.Reason,
 				)
 			}
 		}
 	}
 
+	if changes != nil {
+		if log.IsNormal() {
+			log.Print("\nChanges against %s:\n", opts.against)
+			for _, line := range formatLockDiff(changes) {
+				log.Print("  %s\n", line)
+			}
+			if changes.Empty() {
+				log.Println("  none")
+			}
+		} else {
+			logLockDiff(changes)
+		}
+	}
+
 	return nil
 }
+
+// logTrace logs a resolution trace at debug level
+func logTrace(trace []planner.TraceEntry) {
+	log := logging.Default()
+	if !log.IsDebug() {
+		return
+	}
+
+	for _, te := range trace {
+		var rejected []string
+		for _, rv := range te.Rejected {
+			rejected = append(rejected, fmt.Sprintf("%s (%s: %s)", rv.Version, rv.Kind, rv.Reason))
+		}
+		log.Debug(
+			"resolution trace",
+			"provider", te.Provider,
+			"manifest_source", te.ManifestSource,
+			"engine", te.Engine,
+			"constraint", te.Constraint,
+			"versions_returned", len(te.VersionsReturned),
+			"versions_matching", te.VersionsMatching,
+			"rejected", rejected,
+			"selected", te.Selected,
+			"platforms", te.Platforms,
+			"error", te.Error,
+		)
+	}
+}
+
+// writeJSON writes v as indented JSON to stdout
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
@@ -54,6 +54,16 @@ generates a filesystem mirror consumable by both Terraform and OpenTofu.`,
 	rootCmd.AddCommand(newBuildCommand())
 	rootCmd.AddCommand(newVerifyCommand())
 	rootCmd.AddCommand(newPlanCommand())
+	rootCmd.AddCommand(newValidateCommand())
+	rootCmd.AddCommand(newSchemaCommand())
+	rootCmd.AddCommand(newAddCommand())
+	rootCmd.AddCommand(newRemoveCommand())
+	rootCmd.AddCommand(newPinCommand())
+	rootCmd.AddCommand(newOutdatedCommand())
+	rootCmd.AddCommand(newUpgradeCommand())
+	rootCmd.AddCommand(newDiffCommand())
+	rootCmd.AddCommand(newWhyCommand())
+	rootCmd.AddCommand(newServeCommand())
 
 	return rootCmd
 }
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+)
+
+func newSchemaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema for manifest files",
+		Long: `Print the JSON Schema describing YAML and JSON manifests.
+
+Point editors or config linters at the schema to get completion and
+validation while writing manifests.`,
+		Example: `  # Save the schema for editor integration
+  provider-mirror schema > provider-mirror.schema.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := os.Stdout.Write(manifest.JSONSchema())
+			return err
+		},
+	}
+}
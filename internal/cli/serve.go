@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/downloader"
+	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
+	"github.com/petroprotsakh/go-provider-mirror/internal/server"
+)
+
+type serveOptions struct {
+	mirrorDir string
+	listen    string
+	tlsCert   string
+	tlsKey    string
+	upstreams []string
+	allow     []string
+	platforms []string
+	cacheDir  string
+	retries   int
+	registry  string
+	signing   signingOptions
+	network   networkOptions
+}
+
+func newServeCommand() *cobra.Command {
+	opts := &serveOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a mirror over the provider network mirror protocol",
+		Long: `Serve a mirror directory over the provider network mirror protocol.
+
+With --upstream, provider versions missing from the mirror are fetched from
+the upstream registries on first request, verified against their checksums,
+added to the mirror and served. Only providers matching --allow are fetched.
+
+With --registry, the providers mirrored from that hostname are also served
+over the provider registry protocol, so the server can be used as the
+hostname in provider source addresses. Registry downloads come with a
+SHA256SUMS file signed with --signing-key or --gpg-key, which also sign the
+versions fetched from upstream like 'build' does.
+
+Terraform and OpenTofu require network mirrors to use HTTPS; pass
+--tls-cert and --tls-key unless a proxy terminates TLS.`,
+		Example: `  # Serve a mirror built with 'build'
+  provider-mirror serve --mirror ./mirror --tls-cert cert.pem --tls-key key.pem
+
+  # Populate the mirror on demand from the public registries
+  provider-mirror serve --mirror ./mirror \
+    --upstream registry.terraform.io --upstream registry.opentofu.org \
+    --allow hashicorp --allow registry.terraform.io/integrations/github \
+    --platform linux_amd64 --platform darwin_arm64
+
+  # Act as a private registry for the providers mirrored from registry.terraform.io
+  provider-mirror serve --mirror ./mirror --tls-cert cert.pem --tls-key key.pem \
+    --registry registry.terraform.io --signing-key signing-key.asc`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.mirrorDir, "mirror", "./mirror", "Path to the mirror directory")
+	cmd.Flags().StringVar(&opts.listen, "listen", ":8443", "Address to listen on")
+	cmd.Flags().StringVar(&opts.tlsCert, "tls-cert", "", "PEM certificate to serve HTTPS with")
+	cmd.Flags().StringVar(&opts.tlsKey, "tls-key", "", "PEM private key of the certificate")
+	cmd.Flags().StringArrayVar(
+		&opts.upstreams, "upstream", nil,
+		"Registry hostname to fetch missing providers from (repeatable)",
+	)
+	cmd.Flags().StringArrayVar(
+		&opts.allow, "allow", nil,
+		"Provider that may be fetched: namespace, namespace/name or hostname/namespace/name (repeatable)",
+	)
+	cmd.Flags().StringArrayVar(
+		&opts.platforms, "platform", nil,
+		"Platform to fetch, wildcards allowed (repeatable; default: all published)",
+	)
+	cmd.Flags().StringVar(&opts.cacheDir, "cache-dir", "", "Cache directory for downloads (default: system temp)")
+	cmd.Flags().IntVar(&opts.retries, "retries", 3, "Number of retries for failed downloads")
+	cmd.Flags().StringVar(
+		&opts.registry, "registry", "",
+		"Mirrored hostname to also serve over the provider registry protocol",
+	)
+	opts.signing.addFlags(cmd, "signing SHA256SUMS of registry and fetched versions")
+	opts.network.addFlags(cmd)
+
+	return cmd
+}
+
+func runServe(ctx context.Context, opts *serveOptions) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if (opts.tlsCert == "") != (opts.tlsKey == "") {
+		return errors.New("--tls-cert and --tls-key must be used together")
+	}
+	if opts.registry != "" && !opts.signing.enabled() {
+		return errors.New("--registry requires --signing-key or --gpg-key")
+	}
+	if err := os.MkdirAll(opts.mirrorDir, 0o755); err != nil {
+		return fmt.Errorf("creating mirror directory: %w", err)
+	}
+
+	transport, err := opts.network.transport()
+	if err != nil {
+		return err
+	}
+
+	signer, err := opts.signing.signer()
+	if err != nil {
+		return err
+	}
+
+	client := registry.NewClient(&registry.Config{Retries: opts.retries, Transport: transport})
+	dl := downloader.New(
+		downloader.Config{
+			CacheDir:  opts.cacheDir,
+			Retries:   opts.retries,
+			Transport: transport,
+		}, client,
+	)
+
+	handler, err := server.New(
+		server.Config{
+			MirrorDir: opts.mirrorDir,
+			Upstreams: opts.upstreams,
+			Allow:     opts.allow,
+			Platforms: opts.platforms,
+			Registry:  opts.registry,
+			Signer:    signer,
+		}, client, dl,
+	)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:              opts.listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log := logging.Default()
+	if log.IsNormal() {
+		log.Print("Serving %s on %s\n", opts.mirrorDir, opts.listen)
+		for _, upstream := range opts.upstreams {
+			log.Print("  Fetching missing providers from %s\n", upstream)
+		}
+		if opts.registry != "" {
+			log.Print("  Serving %s providers over the registry protocol (key %s)\n", opts.registry, signer.KeyID())
+		}
+	} else {
+		log.Info("serving mirror",
+			"mirror", opts.mirrorDir,
+			"listen", opts.listen,
+			"upstreams", opts.upstreams,
+			"registry", opts.registry,
+		)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		if opts.tlsCert != "" {
+			errc <- srv.ListenAndServeTLS(opts.tlsCert, opts.tlsKey)
+		} else {
+			errc <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer shutdownCancel()
+	return srv.Shutdown(shutdownCtx)
+}
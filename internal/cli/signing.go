@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+)
+
+// signingOptions holds the flags selecting the organization signing key
+type signingOptions struct {
+	keyFile string
+	gpgKey  string
+}
+
+func (o *signingOptions) addFlags(cmd *cobra.Command, usage string) {
+	cmd.Flags().StringVar(
+		&o.keyFile, "signing-key", "",
+		"ASCII-armored GPG private key "+usage+" (passphrase from "+signing.PassphraseEnv+")",
+	)
+	cmd.Flags().StringVar(
+		&o.gpgKey, "gpg-key", "",
+		"Key ID, fingerprint or user ID of a gpg keyring key "+usage+", signing through gpg and its agent",
+	)
+}
+
+// enabled returns true if a signing key was selected
+func (o *signingOptions) enabled() bool {
+	return o.keyFile != "" || o.gpgKey != ""
+}
+
+// signer loads the selected signing key, or returns nil if none was selected
+func (o *signingOptions) signer() (signing.Signer, error) {
+	switch {
+	case o.keyFile != "" && o.gpgKey != "":
+		return nil, errors.New("--signing-key and --gpg-key cannot be used together")
+	case o.keyFile != "":
+		return signing.LoadKey(o.keyFile)
+	case o.gpgKey != "":
+		return signing.NewGPGSigner(o.gpgKey)
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+	"github.com/petroprotsakh/go-provider-mirror/internal/outdated"
+	"github.com/petroprotsakh/go-provider-mirror/internal/textdiff"
+)
+
+type upgradeOptions struct {
+	manifestPaths []string
+	policy        string
+	providers     []string
+	write         bool
+	patchPath     string
+	vars          varOptions
+	network       networkOptions
+}
+
+func newUpgradeCommand() *cobra.Command {
+	opts := &upgradeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Bump manifest version constraints to newer releases",
+		Long: `Upgrade rewrites version constraints in the manifest, and the YAML files it
+includes, to allow the newest upstream releases permitted by a policy:
+
+- patch: newer patch releases of the same minor version
+- minor: newer minor and patch releases of the same major version
+- major: any newer release
+
+Exact versions are replaced, and "~>" constraints are moved keeping their
+precision. Other constraints are reported if they would need to change.
+
+The change is shown as a diff and only applied with --write.`,
+		Example: `  # Show which constraints would move to newer minor releases
+  provider-mirror upgrade --manifest mirror.yaml --policy minor
+
+  # Upgrade a single provider in place, then review the plan
+  provider-mirror upgrade --policy major --provider hashicorp/aws --write
+  provider-mirror plan --manifest mirror.yaml
+
+  # Save the change as a patch for review
+  provider-mirror upgrade --policy minor --patch upgrade.patch`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUpgrade(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(
+		&opts.manifestPaths,
+		"manifest",
+		"m",
+		[]string{"mirror.yaml"},
+		"Path to the manifest file (repeat to merge several manifests)",
+	)
+	cmd.Flags().StringVar(&opts.policy, "policy", string(outdated.PolicyMinor), "Upgrade policy: patch, minor or major")
+	cmd.Flags().StringArrayVar(&opts.providers, "provider", nil, "Only upgrade this provider (repeatable)")
+	cmd.Flags().BoolVar(&opts.write, "write", false, "Write the upgraded constraints to the manifest files")
+	cmd.Flags().StringVar(&opts.patchPath, "patch", "", "Write the change as a unified diff to this file")
+	opts.vars.addFlags(cmd)
+	opts.network.addFlags(cmd)
+
+	return cmd
+}
+
+func runUpgrade(ctx context.Context, opts *upgradeOptions) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	policy, err := outdated.ParsePolicy(opts.policy)
+	if err != nil {
+		return err
+	}
+
+	manifestOpts, err := opts.vars.manifestOptions()
+	if err != nil {
+		return err
+	}
+	transport, err := opts.network.transport()
+	if err != nil {
+		return err
+	}
+
+	c, err := outdated.New(manifestOpts, transport, opts.manifestPaths...)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.Upgrade(ctx, policy, opts.providers...)
+	if err != nil {
+		return err
+	}
+	files := result.Files
+
+	log := logging.Default()
+	for _, w := range result.Warnings {
+		if log.IsNormal() {
+			log.Print("Warning: %s\n", w)
+		} else {
+			log.Warn(w)
+		}
+	}
+	if log.IsNormal() && len(result.Warnings) > 0 {
+		log.Println()
+	}
+
+	var diff strings.Builder
+	for _, f := range files {
+		name := filepath.ToSlash(f.Path)
+		diff.WriteString(textdiff.Unified("a/"+name, "b/"+name, f.Before, f.After()))
+	}
+
+	if len(files) == 0 {
+		if log.IsNormal() {
+			log.Print("✓ All constraints allow the newest %s releases\n", policy)
+		} else {
+			log.Info("no constraints to upgrade", "policy", policy)
+		}
+		return nil
+	}
+
+	if _, err := os.Stdout.WriteString(diff.String()); err != nil {
+		return err
+	}
+
+	if opts.patchPath != "" {
+		if err := os.WriteFile(opts.patchPath, []byte(diff.String()), 0644); err != nil {
+			return fmt.Errorf("writing patch: %w", err)
+		}
+	}
+
+	changed := 0
+	for _, f := range files {
+		changed += len(f.Changes)
+		for _, ch := range f.Changes {
+			log.Info("constraint upgraded", "file", f.Path, "source", ch.Source, "from", ch.From, "to", ch.To)
+		}
+		if opts.write {
+			if err := f.Save(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if log.IsNormal() {
+		switch {
+		case opts.write:
+			log.Print("\n✓ Upgraded %d constraint(s) in %d file(s)\n", changed, len(files))
+		case opts.patchPath != "":
+			log.Print("\n✓ Wrote %d constraint upgrade(s) to %s\n", changed, opts.patchPath)
+		default:
+			log.Print("\n%d constraint(s) can be upgraded; run with --write to apply\n", changed)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+)
+
+type validateOptions struct {
+	manifestPath string
+	vars         varOptions
+}
+
+func newValidateCommand() *cobra.Command {
+	opts := &validateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "validate [manifest...]",
+		Short: "Validate manifest files without contacting any registry",
+		Long: `Validate one or more manifest files offline.
+
+This command checks:
+- The file (YAML, JSON or HCL) parses and contains no unknown keys
+- Provider sources, version constraints and exclusions parse
+- Engines, platforms, platform sets and policies are valid
+- Provider blocks do not repeat a constraint with conflicting settings
+- Every ${VAR} reference resolves
+
+All problems are reported with their line and column, which makes the
+command suitable as a pre-commit hook.`,
+		Example: `  # Validate the default manifest
+  provider-mirror validate
+
+  # Validate several manifests
+  provider-mirror validate mirror.yaml teams/*.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths := args
+			if len(paths) == 0 {
+				paths = []string{opts.manifestPath}
+			}
+			manifestOpts, err := opts.vars.manifestOptions()
+			if err != nil {
+				return err
+			}
+			return runValidate(paths, manifestOpts)
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&opts.manifestPath,
+		"manifest",
+		"m",
+		"mirror.yaml",
+		"Path to the manifest file (when no files are given as arguments)",
+	)
+	opts.vars.addFlags(cmd)
+
+	return cmd
+}
+
+func runValidate(paths []string, manifestOpts manifest.Options) error {
+	log := logging.Default()
+
+	invalid := 0
+	for _, path := range paths {
+		_, err := manifest.LoadWithOptions(manifestOpts, path)
+		if err == nil {
+			if log.IsNormal() {
+				log.Print("✓ %s\n", path)
+			} else {
+				log.Info("manifest is valid", "path", path)
+			}
+			continue
+		}
+
+		invalid++
+
+		problems := []error{err}
+		var verrs manifest.ValidationErrors
+		if errors.As(err, &verrs) {
+			problems = problems[:0]
+			for _, ve := range verrs {
+				problems = append(problems, ve)
+			}
+		}
+
+		if log.IsNormal() {
+			log.Print("✗ %s:\n", path)
+			for _, p := range problems {
+				log.Print("  - %s\n", p)
+			}
+		} else {
+			for _, p := range problems {
+				log.Error("manifest problem", "path", path, "error", p)
+			}
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d manifest(s) invalid", invalid, len(paths))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+)
+
+// varOptions holds the manifest variable flags shared by several commands
+type varOptions struct {
+	vars     []string
+	varFiles []string
+}
+
+func (o *varOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(
+		&o.vars, "var", nil,
+		"Set a manifest variable (key=value, repeatable)",
+	)
+	cmd.Flags().StringArrayVar(
+		&o.varFiles, "var-file", nil,
+		"Read manifest variables from a YAML or JSON file (repeatable)",
+	)
+}
+
+// manifestOptions returns the manifest options for the given flags.
+// Later files override earlier ones, and --var overrides all files.
+func (o *varOptions) manifestOptions() (manifest.Options, error) {
+	vars := make(map[string]string)
+
+	for _, path := range o.varFiles {
+		fileVars, err := manifest.LoadVars(path)
+		if err != nil {
+			return manifest.Options{}, err
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+
+	for _, kv := range o.vars {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return manifest.Options{}, fmt.Errorf("invalid --var %q: expected key=value", kv)
+		}
+		if !manifest.ValidVarName(k) {
+			return manifest.Options{}, fmt.Errorf("invalid --var %q: invalid variable name %q", kv, k)
+		}
+		vars[k] = v
+	}
+
+	return manifest.Options{Vars: vars}, nil
+}
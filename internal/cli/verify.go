@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/spf13/cobra"
@@ -14,7 +15,8 @@ import (
 )
 
 type verifyOptions struct {
-	mirrorDir string
+	mirrorDir   string
+	trustedKeys []string
 }
 
 func newVerifyCommand() *cobra.Command {
@@ -28,15 +30,27 @@ func newVerifyCommand() *cobra.Command {
 This command validates:
 - All expected provider files are present
 - All checksums match the recorded values
-- The mirror structure is valid for both Terraform and OpenTofu`,
+- The mirror structure is valid for both Terraform and OpenTofu
+- Signed SHA256SUMS files match mirror.lock
+
+With --trusted-key, mirror.lock must carry a detached signature by one of the
+given public keys. An unsigned or mis-signed lock file is refused before
+anything else is checked.`,
 		Example: `  # Verify a mirror
-  provider-mirror verify --mirror ./mirror`,
+  provider-mirror verify --mirror ./mirror
+
+  # Verify a mirror carried across an air gap against the organization key
+  provider-mirror verify --mirror ./mirror --trusted-key release-key.asc`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runVerify(cmd.Context(), opts)
 		},
 	}
 
 	cmd.Flags().StringVar(&opts.mirrorDir, "mirror", "./mirror", "Path to the mirror directory")
+	cmd.Flags().StringArrayVar(
+		&opts.trustedKeys, "trusted-key", nil,
+		"ASCII-armored public key mirror.lock must be signed by (repeatable)",
+	)
 
 	return cmd
 }
@@ -46,6 +60,17 @@ func runVerify(ctx context.Context, opts *verifyOptions) error {
 	defer cancel()
 
 	v := verifier.New(opts.mirrorDir)
+	if len(opts.trustedKeys) > 0 {
+		var keys []string
+		for _, path := range opts.trustedKeys {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("reading trusted key: %w", err)
+			}
+			keys = append(keys, string(data))
+		}
+		v.WithTrustedKeys(strings.Join(keys, "\n"))
+	}
 
 	result, err := v.Verify(ctx)
 	if err != nil {
@@ -73,11 +98,15 @@ func runVerify(ctx context.Context, opts *verifyOptions) error {
 		log.Print("  Providers: %d\n", result.ProviderCount)
 		log.Print("  Versions:  %d\n", result.VersionCount)
 		log.Print("  Files:     %d\n", result.FileCount)
+		if result.SignedBy != "" {
+			log.Print("  Signed by: %s\n", result.SignedBy)
+		}
 	} else {
 		log.Info("mirror verified successfully",
 			"providers", result.ProviderCount,
 			"versions", result.VersionCount,
 			"files", result.FileCount,
+			"signed_by", result.SignedBy,
 		)
 	}
 
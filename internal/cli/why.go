@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/cobra"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+	"github.com/petroprotsakh/go-provider-mirror/internal/planner"
+	"github.com/petroprotsakh/go-provider-mirror/internal/resolver"
+)
+
+type whyOptions struct {
+	manifestPaths []string
+	vars          varOptions
+	network       networkOptions
+}
+
+func newWhyCommand() *cobra.Command {
+	opts := &whyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "why <provider> [version]",
+		Short: "Explain why a provider version is in the mirror",
+		Long: `Why resolves the manifest and explains which provider blocks, engines and
+version constraints select a provider version, which newer candidates were
+passed over and why, and which platforms each block contributes.
+
+Without a version, every selected version of the provider is explained.
+A provider without a hostname matches it on every registry.`,
+		Example: `  # Explain a single version
+  provider-mirror why registry.terraform.io/hashicorp/aws 5.100.0
+
+  # Explain every mirrored version of a provider
+  provider-mirror why hashicorp/aws`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := ""
+			if len(args) == 2 {
+				v = args[1]
+			}
+			return runWhy(cmd.Context(), args[0], v, opts)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(
+		&opts.manifestPaths,
+		"manifest",
+		"m",
+		[]string{"mirror.yaml"},
+		"Path to the manifest file (repeat to merge several manifests)",
+	)
+	opts.vars.addFlags(cmd)
+	opts.network.addFlags(cmd)
+
+	return cmd
+}
+
+func runWhy(ctx context.Context, source, v string, opts *whyOptions) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	src, err := manifest.ParseProviderSource(source)
+	if err != nil {
+		return err
+	}
+
+	manifestOpts, err := opts.vars.manifestOptions()
+	if err != nil {
+		return err
+	}
+	transport, err := opts.network.transport()
+	if err != nil {
+		return err
+	}
+
+	p, err := planner.New(manifestOpts, transport, opts.manifestPaths...)
+	if err != nil {
+		return err
+	}
+
+	plan, err := p.Plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Versions published under the requested address are explained through
+	// the upstream provider they are downloaded from
+	publishedFrom := make(map[string]planner.PlannedProvider)
+	for _, pp := range plan.Providers {
+		if pp.PublishAs == "" {
+			continue
+		}
+		if published, err := manifest.ParseProviderSource(pp.PublishAs); err == nil && matchesSource(src, published) {
+			publishedFrom[strings.ToLower(pp.Source)] = pp
+		}
+	}
+
+	// Decisions per registry, in resolution order
+	byProvider := make(map[string][]resolver.Decision)
+	publishedVersions := make(map[string][]string)
+	var providers []string
+	for _, d := range plan.Decisions {
+		key := d.Provider.String()
+		if pp, ok := publishedFrom[strings.ToLower(key)]; ok {
+			key = fmt.Sprintf("%s (from %s)", pp.PublishAs, pp.Source)
+			if publishedVersions[key] == nil {
+				for _, pv := range pp.Versions {
+					publishedVersions[key] = append(publishedVersions[key], pv.Version)
+				}
+			}
+		} else if !matchesSource(src, d.Provider) {
+			continue
+		}
+		if byProvider[key] == nil {
+			providers = append(providers, key)
+		}
+		byProvider[key] = append(byProvider[key], d)
+	}
+	if len(providers) == 0 {
+		return fmt.Errorf("provider %s is not in the manifest", source)
+	}
+
+	log := logging.Default()
+	for _, provider := range providers {
+		decisions := byProvider[provider]
+
+		versions := []string{v}
+		if v == "" {
+			versions = selectedVersions(decisions)
+			if published, ok := publishedVersions[provider]; ok {
+				// Only the versions published under the requested address
+				versions = slices.DeleteFunc(versions, func(ver string) bool {
+					return !slices.Contains(published, ver)
+				})
+			}
+		}
+
+		for _, ver := range versions {
+			if log.IsNormal() {
+				printWhy(provider, ver, decisions)
+			} else {
+				logWhy(provider, ver, decisions)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchesSource reports whether addr is the provider asked about; a source
+// without a hostname matches every registry
+func matchesSource(src, addr manifest.ProviderSource) bool {
+	if !strings.EqualFold(addr.Namespace, src.Namespace) || !strings.EqualFold(addr.Name, src.Name) {
+		return false
+	}
+	return src.Hostname == "" || strings.EqualFold(addr.Hostname, src.Hostname)
+}
+
+// printWhy explains a provider version in human-readable form
+func printWhy(provider, v string, decisions []resolver.Decision) {
+	log := logging.Default()
+
+	var platforms []string
+	for _, d := range decisions {
+		if d.Selected == v {
+			platforms = append(platforms, d.Platforms...)
+		}
+	}
+
+	if len(platforms) > 0 {
+		log.Print("%s %s is mirrored for %s\n", provider, v, strings.Join(sortedSet(platforms), ", "))
+	} else {
+		log.Print("%s %s is not mirrored\n", provider, v)
+	}
+
+	for _, d := range decisions {
+		block := d.ManifestSource
+		if d.Engine != "" {
+			block += ", engine " + string(d.Engine)
+		}
+		log.Print("\n  %s\n", block)
+
+		if d.Selected != v {
+			log.Print("    constraint %q: %s %s\n", d.Constraint, v, d.Explain(v))
+			continue
+		}
+
+		log.Print("    constraint %q matched %d version(s); %s was selected\n", d.Constraint, len(d.Candidates), v)
+		for _, sv := range d.Rejected {
+			log.Print("      %s %s\n", sv.Version, d.Explain(sv.Version))
+		}
+		log.Print("    requested platforms: %s\n", orDash(strings.Join(d.RequestedPlatforms, ", ")))
+		log.Print("    mirrored platforms:  %s\n", orDash(strings.Join(d.Platforms, ", ")))
+		if len(d.MissingPlatforms) > 0 {
+			log.Print("    missing platforms:   %s\n", strings.Join(d.MissingPlatforms, ", "))
+		}
+	}
+	log.Println()
+}
+
+// logWhy explains a provider version as structured log entries
+func logWhy(provider, v string, decisions []resolver.Decision) {
+	log := logging.Default()
+	for _, d := range decisions {
+		log.Info(
+			"version decision",
+			"provider", provider,
+			"version", v,
+			"manifest_source", d.ManifestSource,
+			"engine", d.Engine,
+			"constraint", d.Constraint,
+			"selected", d.Selected,
+			"outcome", d.Explain(v),
+			"requested_platforms", d.RequestedPlatforms,
+			"platforms", d.Platforms,
+		)
+	}
+}
+
+// selectedVersions returns the versions selected by any decision, newest first
+func selectedVersions(decisions []resolver.Decision) []string {
+	var selected []string
+	for _, d := range decisions {
+		selected = append(selected, d.Selected)
+	}
+	selected = sortedSet(selected)
+
+	sort.SliceStable(
+		selected, func(i, j int) bool {
+			vi, errI := version.NewVersion(selected[i])
+			vj, errJ := version.NewVersion(selected[j])
+			return errI == nil && errJ == nil && vi.GreaterThan(vj)
+		},
+	)
+	return selected
+}
+
+// sortedSet returns the sorted unique values of a list
+func sortedSet(list []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
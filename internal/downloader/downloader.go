@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
 	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
 	"github.com/petroprotsakh/go-provider-mirror/internal/resolver"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
 )
 
 // Config configures the downloader behavior.
@@ -30,6 +32,8 @@ type Config struct {
 	Retries      int
 	MaxBackoff   time.Duration
 	ShowProgress bool
+	HostAuth     map[string]httpclient.HostAuth // credentials for archive hosts
+	Transport    *httpclient.Transport          // proxy and TLS settings
 }
 
 // DefaultConfig returns sensible defaults.
@@ -49,6 +53,9 @@ type Downloader struct {
 	client     *registry.Client
 	httpClient *httpclient.Client
 	log        *logging.Logger
+
+	mu       sync.Mutex
+	checksum map[string]*checksumFetch // by SHA256SUMS URL
 }
 
 // New creates a new downloader.
@@ -72,10 +79,14 @@ func New(config Config, client *registry.Client) *Downloader {
 		client: client,
 		httpClient: httpclient.New(
 			httpclient.Config{
-				Timeout: 5 * time.Minute, // longer timeout for downloads
+				Timeout:    5 * time.Minute, // longer timeout for downloads
+				MaxBackoff: config.MaxBackoff,
+				HostAuth:   config.HostAuth,
+				Transport:  config.Transport,
 			},
 		),
-		log: logging.Default(),
+		log:      logging.Default(),
+		checksum: make(map[string]*checksumFetch),
 	}
 }
 
@@ -106,10 +117,20 @@ type DownloadResult struct {
 	DownloadURL string
 	Filename    string
 	SHA256Sum   string
+	Upstream    *UpstreamChecksums // nil if the registry publishes no signed checksums
 	Error       error
 	FromCache   bool
 }
 
+// UpstreamChecksums is the signed SHA256SUMS file a registry publishes for a
+// provider version
+type UpstreamChecksums struct {
+	SHA256Sums []byte
+	Signature  []byte
+	PublicKeys string // ASCII-armored keys advertised by the registry
+	KeyID      string // key that made the signature
+}
+
 // Download downloads all providers from the resolution.
 func (d *Downloader) Download(
 	ctx context.Context,
@@ -283,6 +304,13 @@ func (d *Downloader) downloadTask(
 	result.Filename = info.Filename
 	result.SHA256Sum = info.SHA256Sum
 
+	upstream, err := d.upstreamChecksums(ctx, info)
+	if err != nil {
+		result.Error = fmt.Errorf("verifying upstream signature: %w", err)
+		return result
+	}
+	result.Upstream = upstream
+
 	cachePath := d.cachePath(task, info.Filename)
 	if d.checkCache(cachePath, info.SHA256Sum) {
 		d.log.Debug("cache hit", "path", cachePath)
@@ -309,6 +337,112 @@ func (d *Downloader) downloadTask(
 	return result
 }
 
+// checksumFetch is a SHA256SUMS file fetched once for all platforms of a
+// version
+type checksumFetch struct {
+	once      sync.Once
+	checksums *UpstreamChecksums
+	err       error
+}
+
+// upstreamChecksums fetches the SHA256SUMS file of a download, verifies its
+// signature with the keys the registry advertises and checks that it lists
+// the archive checksum. It returns nil if the registry publishes none or
+// advertises no keys to verify them with.
+func (d *Downloader) upstreamChecksums(ctx context.Context, info *registry.DownloadInfo) (*UpstreamChecksums, error) {
+	if info.SHA256SumsURL == "" || info.SHA256SumsSignature == "" {
+		d.log.Debug("registry publishes no signed checksums", "filename", info.Filename)
+		return nil, nil
+	}
+
+	d.mu.Lock()
+	fetch, ok := d.checksum[info.SHA256SumsURL]
+	if !ok {
+		fetch = &checksumFetch{}
+		d.checksum[info.SHA256SumsURL] = fetch
+	}
+	d.mu.Unlock()
+
+	fetch.once.Do(func() {
+		fetch.checksums, fetch.err = d.fetchChecksums(ctx, info)
+	})
+	if fetch.err != nil {
+		// Let a later download retry the fetch
+		d.mu.Lock()
+		if d.checksum[info.SHA256SumsURL] == fetch {
+			delete(d.checksum, info.SHA256SumsURL)
+		}
+		d.mu.Unlock()
+		return nil, fetch.err
+	}
+	if fetch.checksums == nil {
+		return nil, nil
+	}
+
+	if sum := signing.ParseSHA256Sums(fetch.checksums.SHA256Sums)[info.Filename]; sum != strings.ToLower(info.SHA256Sum) {
+		return nil, fmt.Errorf("SHA256SUMS lists %q for %s, registry reported %q", sum, info.Filename, info.SHA256Sum)
+	}
+	return fetch.checksums, nil
+}
+
+// fetchChecksums downloads and verifies a signed SHA256SUMS file
+func (d *Downloader) fetchChecksums(ctx context.Context, info *registry.DownloadInfo) (*UpstreamChecksums, error) {
+	var keys []string
+	for _, key := range info.SigningKeys.GPGPublicKeys {
+		keys = append(keys, key.ASCIIArmor)
+	}
+	if len(keys) == 0 {
+		// Many providers, notably on the OpenTofu registry, are published unsigned
+		if d.log.IsNormal() {
+			d.log.Print("Warning: registry advertises no signing keys for %s; upstream checksums are not verified\n", info.SHA256SumsURL)
+		} else {
+			d.log.Warn("registry advertises no signing keys", "sha256sums", info.SHA256SumsURL)
+		}
+		return nil, nil
+	}
+
+	sums, err := d.fetch(ctx, info.SHA256SumsURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching SHA256SUMS: %w", err)
+	}
+	sig, err := d.fetch(ctx, info.SHA256SumsSignature)
+	if err != nil {
+		return nil, fmt.Errorf("fetching SHA256SUMS signature: %w", err)
+	}
+
+	publicKeys := strings.Join(keys, "\n")
+	keyID, err := signing.Verify(publicKeys, sums, sig)
+	if err != nil {
+		return nil, fmt.Errorf("SHA256SUMS: %w", err)
+	}
+
+	return &UpstreamChecksums{
+		SHA256Sums: sums,
+		Signature:  sig,
+		PublicKeys: publicKeys,
+		KeyID:      keyID,
+	}, nil
+}
+
+// fetch downloads a small file into memory
+func (d *Downloader) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := d.httpClient.Do(req, httpclient.WithRetry())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpclient.NewHTTPError(resp)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
 // cachePath returns the cache path for a download.
 func (d *Downloader) cachePath(task DownloadTask, filename string) string {
 	return filepath.Join(
@@ -0,0 +1,115 @@
+package downloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/httpclient"
+	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing/signingtest"
+)
+
+// --- Archive host credentials tests ---
+
+func TestDownloadFile_SendsHostAuth(t *testing.T) {
+	archive := []byte("archive")
+	sum := sha256.Sum256(archive)
+
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		if gotAuth != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(archive)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	d := New(Config{
+		CacheDir: t.TempDir(),
+		HostAuth: map[string]httpclient.HostAuth{u.Host: httpclient.BearerAuth("secret")},
+	}, nil)
+
+	dest := filepath.Join(t.TempDir(), "archive.zip")
+	err := d.downloadFile(context.Background(), srv.URL+"/archive.zip", dest, hex.EncodeToString(sum[:]), "archive", nil)
+	if err != nil {
+		t.Fatalf("downloadFile() error = %v", err)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("expected the archive host to receive the credentials, got %q", gotAuth)
+	}
+}
+
+// --- Upstream checksum tests ---
+
+// newChecksumServer serves a SHA256SUMS file and a signature
+func newChecksumServer(t *testing.T, sums, sig []byte) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/SHA256SUMS":
+			_, _ = w.Write(sums)
+		case "/SHA256SUMS.sig":
+			_, _ = w.Write(sig)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUpstreamChecksums_NoSigningKeys(t *testing.T) {
+	sum := sha256.Sum256([]byte("archive"))
+	sums := signing.SHA256Sums(map[string]string{"archive.zip": hex.EncodeToString(sum[:])})
+	srv := newChecksumServer(t, sums, []byte("not a signature"))
+
+	d := New(Config{CacheDir: t.TempDir()}, nil)
+	checksums, err := d.upstreamChecksums(context.Background(), &registry.DownloadInfo{
+		Filename:            "archive.zip",
+		SHA256Sum:           hex.EncodeToString(sum[:]),
+		SHA256SumsURL:       srv.URL + "/SHA256SUMS",
+		SHA256SumsSignature: srv.URL + "/SHA256SUMS.sig",
+	})
+	if err != nil {
+		t.Fatalf("upstreamChecksums() error = %v", err)
+	}
+	if checksums != nil {
+		t.Errorf("expected no upstream checksums without signing keys, got %+v", checksums)
+	}
+}
+
+func TestUpstreamChecksums_BadSignature(t *testing.T) {
+	signer := signingtest.NewSigner(t)
+	sum := sha256.Sum256([]byte("archive"))
+	sums := signing.SHA256Sums(map[string]string{"archive.zip": hex.EncodeToString(sum[:])})
+	sig, err := signer.Sign([]byte("other content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newChecksumServer(t, sums, sig)
+
+	d := New(Config{CacheDir: t.TempDir()}, nil)
+	_, err = d.upstreamChecksums(context.Background(), &registry.DownloadInfo{
+		Filename:            "archive.zip",
+		SHA256Sum:           hex.EncodeToString(sum[:]),
+		SHA256SumsURL:       srv.URL + "/SHA256SUMS",
+		SHA256SumsSignature: srv.URL + "/SHA256SUMS.sig",
+		SigningKeys: registry.SigningKeys{
+			GPGPublicKeys: []registry.GPGPublicKey{{KeyID: signer.KeyID(), ASCIIArmor: signer.PublicKey()}},
+		},
+	})
+	if err == nil {
+		t.Error("expected an error for a signature that does not match")
+	}
+}
@@ -23,6 +23,22 @@ type Config struct {
 	MaxBackoff          time.Duration
 	MaxConnsPerHost     int
 	MaxIdleConnsPerHost int
+
+	// Transport holds proxy and TLS settings. Nil uses the proxy from the
+	// environment and the system certificate roots.
+	Transport *Transport
+
+	// Limiter schedules requests per host. Nil uses a limiter shared by all
+	// clients in the process.
+	Limiter *Limiter
+
+	// HostAuth holds headers sent to archive and other hosts, by hostname or
+	// host:port. They are never sent to another host, even on redirect.
+	HostAuth map[string]HostAuth
+
+	// CLIConfigPaths lists the Terraform and OpenTofu CLI configuration files
+	// read for credentials. Nil reads the default locations.
+	CLIConfigPaths []string
 }
 
 // DefaultConfig returns sensible defaults.
@@ -39,7 +55,8 @@ func DefaultConfig() Config {
 // Client is a shared HTTP client with retry and auth support.
 type Client struct {
 	http        *http.Client
-	credentials map[string]string // hostname -> token
+	credentials *credentials
+	limiter     *Limiter
 	retries     int
 	maxBackoff  time.Duration
 	userAgent   string
@@ -64,20 +81,29 @@ func New(cfg Config) *Client {
 	if cfg.MaxIdleConnsPerHost <= 0 {
 		cfg.MaxIdleConnsPerHost = defaults.MaxIdleConnsPerHost
 	}
+	if cfg.Limiter == nil {
+		cfg.Limiter = sharedLimiter
+	}
+	if cfg.CLIConfigPaths == nil {
+		cfg.CLIConfigPaths = CLIConfigPaths()
+	}
 
 	// Configure transport with higher connection limits for parallel downloads
-	transport := &http.Transport{
-		MaxConnsPerHost:     cfg.MaxConnsPerHost,
-		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
-		MaxIdleConns:        cfg.MaxConnsPerHost * 2, // Allow more total idle connections
+	transport := cfg.Transport.roundTripper(cfg)
+
+	hostAuth := make(map[string]HostAuth, len(cfg.HostAuth))
+	for host, auth := range cfg.HostAuth {
+		hostAuth[strings.ToLower(host)] = auth
 	}
 
 	return &Client{
 		http: &http.Client{
-			Timeout:   cfg.Timeout,
-			Transport: transport,
+			Timeout:       cfg.Timeout,
+			Transport:     &hostAuthTransport{base: transport, auth: hostAuth},
+			CheckRedirect: checkRedirect,
 		},
-		credentials: loadCredentials(),
+		credentials: newCredentials(cfg.CLIConfigPaths),
+		limiter:     cfg.Limiter,
 		retries:     cfg.Retries,
 		maxBackoff:  cfg.MaxBackoff,
 		userAgent:   version.UserAgent(),
@@ -161,7 +187,26 @@ func (c *Client) Do(req *http.Request, opts ...RequestOption) (*http.Response, e
 	if o.hostname != "" {
 		c.addAuth(req, o.hostname)
 	}
-	return c.http.Do(req)
+	return c.send(req)
+}
+
+// send performs a request once the limiter allows it and reports the
+// response status to the limiter. The wait is not counted in the timeout.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if err := c.limiter.acquire(req.Context(), host); err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	c.limiter.release(host)
+	if err != nil {
+		return nil, err
+	}
+
+	// Redirects are attributed to the host that answered
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+	c.limiter.observe(resp.Request.URL.Host, resp.StatusCode, retryAfter, c.maxBackoff)
+	return resp, nil
 }
 
 func (c *Client) doWithRetry(
@@ -195,7 +240,7 @@ func (c *Client) doWithRetry(
 			c.addAuth(reqClone, hostname)
 		}
 
-		resp, err := c.http.Do(reqClone)
+		resp, err := c.send(reqClone)
 		if err != nil {
 			lastErr = &RetryableError{Err: fmt.Errorf("request failed: %w", err)}
 			continue
@@ -226,7 +271,7 @@ func (c *Client) addUserAgent(req *http.Request) {
 
 // addAuth adds authorization header if credentials exist for the hostname.
 func (c *Client) addAuth(req *http.Request, hostname string) {
-	if token, ok := c.credentials[hostname]; ok {
+	if token := c.credentials.token(req.Context(), hostname); token != "" {
 		req.Header.Set("Authorization", "Bearer "+token)
 	}
 }
@@ -247,12 +292,21 @@ func isRetryableStatus(code int) bool {
 
 // parseRetryAfter parses the Retry-After header value.
 func parseRetryAfter(value string) time.Duration {
+	return parseRetryAfterAt(value, time.Now())
+}
+
+// parseRetryAfterAt parses a Retry-After value in seconds or as an HTTP date
+// relative to now. Dates in the past mean no wait.
+func parseRetryAfterAt(value string, now time.Time) time.Duration {
 	if value == "" {
 		return 0
 	}
 	if seconds, err := strconv.Atoi(value); err == nil {
 		return time.Duration(seconds) * time.Second
 	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0)
+	}
 	return 0
 }
 
@@ -112,6 +112,29 @@ func TestParseRetryAfter(t *testing.T) {
 	}
 }
 
+func TestParseRetryAfterAt_HTTPDate(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"future date", "Sun, 18 Oct 2026 12:00:30 GMT", 30 * time.Second},
+		{"past date", "Sun, 18 Oct 2026 11:59:00 GMT", 0},
+		{"RFC 850 date", "Sunday, 18-Oct-26 12:02:00 GMT", 2 * time.Minute},
+		{"malformed date", "Sun, 18 Oct 2026", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfterAt(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfterAt(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
 // --- Backoff tests ---
 
 func TestBackoff_ExponentialGrowth(t *testing.T) {
@@ -0,0 +1,343 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+)
+
+// credentials resolves registry tokens per hostname. Sources are checked in
+// the order Terraform uses: environment variables, credentials blocks in the
+// CLI configuration (including credentials.tfrc.json written by
+// `terraform login`), then the configured credentials helper.
+type credentials struct {
+	env    map[string]string // PM_TOKEN_ and TF_TOKEN_ variables
+	config map[string]string // credentials blocks
+	helper *credentialsHelper
+
+	mu     sync.Mutex
+	helped map[string]string // tokens from the helper, including misses
+}
+
+// newCredentials loads credentials from the environment and the CLI
+// configuration files. Unreadable configuration is reported and skipped.
+func newCredentials(configPaths []string) *credentials {
+	c := &credentials{
+		env:    loadCredentials(),
+		config: make(map[string]string),
+		helped: make(map[string]string),
+	}
+
+	for _, path := range configPaths {
+		cfg, err := readCLIConfig(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				warn("ignoring CLI configuration "+path, err, "path", path)
+			}
+			continue
+		}
+
+		for _, block := range cfg.Credentials {
+			host := normalizeHost(block.Host)
+			if _, exists := c.config[host]; !exists && block.Token != "" {
+				c.config[host] = block.Token
+			}
+		}
+		if c.helper == nil && len(cfg.CredentialsHelpers) > 0 {
+			h := cfg.CredentialsHelpers[0]
+			c.helper = &credentialsHelper{name: h.Name, args: h.Args, dirs: helperDirs(path)}
+		}
+	}
+
+	return c
+}
+
+// token returns the token for a hostname, or an empty string
+func (c *credentials) token(ctx context.Context, hostname string) string {
+	host := normalizeHost(hostname)
+	if token, ok := c.env[host]; ok {
+		return token
+	}
+	if token, ok := c.env[hostname]; ok {
+		return token
+	}
+	if token, ok := c.config[host]; ok {
+		return token
+	}
+	if c.helper == nil {
+		return ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if token, ok := c.helped[host]; ok {
+		return token
+	}
+	token, err := c.helper.get(ctx, host)
+	if err != nil {
+		warn("credentials helper failed for "+host, err, "helper", c.helper.name, "hostname", host)
+	}
+	c.helped[host] = token
+	return token
+}
+
+// cliConfig holds the parts of a Terraform or OpenTofu CLI configuration
+// file that concern credentials
+type cliConfig struct {
+	Credentials        []credentialsBlock       `hcl:"credentials,block"`
+	CredentialsHelpers []credentialsHelperBlock `hcl:"credentials_helper,block"`
+	Remain             hcl.Body                 `hcl:",remain"`
+}
+
+type credentialsBlock struct {
+	Host   string   `hcl:"host,label"`
+	Token  string   `hcl:"token,optional"`
+	Remain hcl.Body `hcl:",remain"`
+}
+
+type credentialsHelperBlock struct {
+	Name string   `hcl:"name,label"`
+	Args []string `hcl:"args,optional"`
+}
+
+// readCLIConfig parses a CLI configuration file in HCL or, for .json files,
+// JSON syntax
+func readCLIConfig(path string) (*cliConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if strings.HasSuffix(path, ".json") {
+		file, diags = parser.ParseJSON(data, path)
+	} else {
+		file, diags = parser.ParseHCL(data, path)
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	var cfg cliConfig
+	if diags := gohcl.DecodeBody(file.Body, nil, &cfg); diags.HasErrors() {
+		return nil, diags
+	}
+	return &cfg, nil
+}
+
+// CLIConfigPaths returns the CLI configuration files credentials are read
+// from, most important first. TF_CLI_CONFIG_FILE and TOFU_CLI_CONFIG_FILE
+// replace the default .terraformrc and .tofurc files. credentials.tfrc.json
+// is always read last.
+func CLIConfigPaths() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = ""
+	}
+
+	var paths []string
+	for _, name := range []string{"TOFU_CLI_CONFIG_FILE", "TF_CLI_CONFIG_FILE"} {
+		if path := os.Getenv(name); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 && home != "" {
+		if runtime.GOOS == "windows" {
+			if appData := os.Getenv("APPDATA"); appData != "" {
+				paths = append(paths, filepath.Join(appData, "tofu.rc"), filepath.Join(appData, "terraform.rc"))
+			}
+		} else {
+			paths = append(paths, filepath.Join(home, ".tofurc"), filepath.Join(home, ".terraformrc"))
+		}
+	}
+
+	if dir := cliConfigDir(home); dir != "" {
+		paths = append(paths, filepath.Join(dir, "credentials.tfrc.json"))
+	}
+	return paths
+}
+
+// cliConfigDir returns the directory where `terraform login` stores credentials
+func cliConfigDir(home string) string {
+	if runtime.GOOS == "windows" {
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "terraform.d")
+		}
+		return ""
+	}
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".terraform.d")
+}
+
+// credentialsHelper runs an external program that provides tokens, following
+// Terraform's credentials helper protocol
+type credentialsHelper struct {
+	name string
+	args []string
+	dirs []string // directories searched for the program before PATH
+}
+
+// get asks the helper for the token of a hostname. A helper that knows no
+// credentials for the host prints an empty object.
+func (h *credentialsHelper) get(ctx context.Context, hostname string) (string, error) {
+	program, err := h.program()
+	if err != nil {
+		return "", err
+	}
+
+	args := append(append([]string{}, h.args...), "get", hostname)
+	cmd := exec.CommandContext(ctx, program, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return "", fmt.Errorf("parsing helper output: %w", err)
+	}
+	return result.Token, nil
+}
+
+// program locates the helper executable, terraform-credentials-<name>
+func (h *credentialsHelper) program() (string, error) {
+	name := "terraform-credentials-" + h.name
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	for _, dir := range h.dirs {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("credentials helper %s not found", name)
+	}
+	return path, nil
+}
+
+// helperDirs returns the directories searched for credentials helpers
+// declared in a configuration file
+func helperDirs(configPath string) []string {
+	var dirs []string
+	home, _ := os.UserHomeDir()
+	if dir := cliConfigDir(home); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "plugins"))
+	}
+	return append(dirs, filepath.Dir(configPath))
+}
+
+// normalizeHost lowercases a hostname for lookups
+func normalizeHost(hostname string) string {
+	return strings.ToLower(strings.TrimSpace(hostname))
+}
+
+// warn reports a problem with a credentials source
+func warn(msg string, err error, attrs ...any) {
+	log := logging.Default()
+	if log.IsNormal() {
+		log.Print("Warning: %s: %v\n", msg, err)
+	} else {
+		log.Warn(msg, append(attrs, "error", err)...)
+	}
+}
+
+// HostAuth is a header sent with every request to one host, such as an
+// authenticated archive host that registry tokens do not cover
+type HostAuth struct {
+	Header string
+	Value  string
+}
+
+// BearerAuth sends a token as Authorization: Bearer
+func BearerAuth(token string) HostAuth {
+	return HostAuth{Header: "Authorization", Value: "Bearer " + token}
+}
+
+// BasicAuth sends a user name and password as Authorization: Basic
+func BasicAuth(username, password string) HostAuth {
+	return HostAuth{
+		Header: "Authorization",
+		Value:  "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)),
+	}
+}
+
+// HeaderAuth sends a token in a custom header, such as GitLab's PRIVATE-TOKEN
+func HeaderAuth(header, token string) HostAuth {
+	return HostAuth{Header: header, Value: token}
+}
+
+// hostAuthTransport adds the configured header to requests for exactly its
+// host. The header is added per request rather than to the caller's request,
+// so redirects to another host never carry it.
+type hostAuthTransport struct {
+	base http.RoundTripper
+	auth map[string]HostAuth // by lowercase host or host:port
+}
+
+func (t *hostAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	auth, ok := t.auth[strings.ToLower(req.URL.Host)]
+	if !ok {
+		auth, ok = t.auth[strings.ToLower(req.URL.Hostname())]
+	}
+	if !ok || (req.URL.Scheme != "https" && !isLoopback(req.URL.Hostname())) {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set(auth.Header, auth.Value)
+	return t.base.RoundTrip(req)
+}
+
+// isLoopback returns true for local hosts, where plain HTTP is acceptable
+func isLoopback(hostname string) bool {
+	if hostname == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkRedirect drops the registry Authorization header when a redirect
+// leaves the original host, including for subdomains
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
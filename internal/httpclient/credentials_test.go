@@ -0,0 +1,310 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeFile writes a test file and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// --- CLI configuration tests ---
+
+func TestReadCLIConfig_HCL(t *testing.T) {
+	path := writeFile(t, t.TempDir(), ".terraformrc", `
+plugin_cache_dir = "$HOME/.terraform.d/plugin-cache"
+
+provider_installation {
+  filesystem_mirror {
+    path = "/opt/mirror"
+  }
+}
+
+credentials "app.terraform.io" {
+  token = "tfc-token"
+}
+
+credentials_helper "vault" {
+  args = ["--role", "ci"]
+}
+`)
+
+	cfg, err := readCLIConfig(path)
+	if err != nil {
+		t.Fatalf("readCLIConfig() error = %v", err)
+	}
+	if len(cfg.Credentials) != 1 || cfg.Credentials[0].Host != "app.terraform.io" || cfg.Credentials[0].Token != "tfc-token" {
+		t.Errorf("unexpected credentials: %+v", cfg.Credentials)
+	}
+	if len(cfg.CredentialsHelpers) != 1 || cfg.CredentialsHelpers[0].Name != "vault" || len(cfg.CredentialsHelpers[0].Args) != 2 {
+		t.Errorf("unexpected credentials helpers: %+v", cfg.CredentialsHelpers)
+	}
+}
+
+func TestReadCLIConfig_CredentialsJSON(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "credentials.tfrc.json", `{
+  "credentials": {
+    "registry.corp.example": {"token": "login-token"}
+  }
+}`)
+
+	cfg, err := readCLIConfig(path)
+	if err != nil {
+		t.Fatalf("readCLIConfig() error = %v", err)
+	}
+	if len(cfg.Credentials) != 1 || cfg.Credentials[0].Token != "login-token" {
+		t.Errorf("unexpected credentials: %+v", cfg.Credentials)
+	}
+}
+
+func TestReadCLIConfig_Invalid(t *testing.T) {
+	path := writeFile(t, t.TempDir(), ".terraformrc", `credentials "x" {`)
+	if _, err := readCLIConfig(path); err == nil {
+		t.Error("expected error for invalid configuration")
+	}
+}
+
+func TestCLIConfigPaths_ExplicitFile(t *testing.T) {
+	t.Setenv("TOFU_CLI_CONFIG_FILE", "")
+	t.Setenv("TF_CLI_CONFIG_FILE", "/etc/terraform/cli.tfrc")
+
+	paths := CLIConfigPaths()
+	if len(paths) == 0 || paths[0] != "/etc/terraform/cli.tfrc" {
+		t.Fatalf("expected the explicit file first, got %v", paths)
+	}
+	for _, path := range paths[1:] {
+		if filepath.Base(path) != "credentials.tfrc.json" {
+			t.Errorf("expected default config files to be replaced, got %v", paths)
+		}
+	}
+}
+
+// --- Precedence tests ---
+
+func TestCredentials_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFile(t, dir, ".tofurc", `
+credentials "registry.corp.example" {
+  token = "tofurc-token"
+}
+credentials "env.example" {
+  token = "config-token"
+}
+`)
+	second := writeFile(t, dir, "credentials.tfrc.json", `{
+  "credentials": {
+    "registry.corp.example": {"token": "login-token"},
+    "login.example": {"token": "login-only"}
+  }
+}`)
+	t.Setenv("TF_TOKEN_env_example", "env-token")
+
+	c := newCredentials([]string{first, filepath.Join(dir, "missing"), second})
+
+	tests := []struct {
+		hostname string
+		want     string
+	}{
+		{"env.example", "env-token"},
+		{"registry.corp.example", "tofurc-token"},
+		{"Registry.Corp.Example", "tofurc-token"},
+		{"login.example", "login-only"},
+		{"unknown.example", ""},
+	}
+
+	for _, tt := range tests {
+		if got := c.token(context.Background(), tt.hostname); got != tt.want {
+			t.Errorf("token(%q) = %q, want %q", tt.hostname, got, tt.want)
+		}
+	}
+}
+
+// --- Credentials helper tests ---
+
+func TestCredentials_Helper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	script := writeFile(t, dir, "terraform-credentials-test", `#!/bin/sh
+echo "$@" >> "`+calls+`"
+if [ "$3" = "registry.corp.example" ]; then
+  echo '{"token": "helper-token"}'
+else
+  echo '{}'
+fi
+`)
+	if err := os.Chmod(script, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	config := writeFile(t, dir, ".terraformrc", `
+credentials "app.terraform.io" {
+  token = "config-token"
+}
+credentials_helper "test" {
+  args = ["--profile"]
+}
+`)
+
+	c := newCredentials([]string{config})
+	ctx := context.Background()
+
+	if got := c.token(ctx, "registry.corp.example"); got != "helper-token" {
+		t.Errorf("expected helper token, got %q", got)
+	}
+	if got := c.token(ctx, "registry.corp.example"); got != "helper-token" {
+		t.Errorf("expected cached helper token, got %q", got)
+	}
+	if got := c.token(ctx, "other.example"); got != "" {
+		t.Errorf("expected no token for unknown host, got %q", got)
+	}
+	if got := c.token(ctx, "app.terraform.io"); got != "config-token" {
+		t.Errorf("expected credentials block to take precedence, got %q", got)
+	}
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--profile get registry.corp.example\n--profile get other.example\n"
+	if string(data) != want {
+		t.Errorf("helper calls = %q, want %q", data, want)
+	}
+}
+
+func TestCredentialsHelper_NotFound(t *testing.T) {
+	h := &credentialsHelper{name: "does-not-exist", dirs: []string{t.TempDir()}}
+	if _, err := h.get(context.Background(), "registry.corp.example"); err == nil {
+		t.Error("expected error for missing helper")
+	}
+}
+
+// --- Host auth tests ---
+
+// recordingServer returns a server that records the headers of each request
+func recordingServer(t *testing.T, headers *[]http.Header) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*headers = append(*headers, r.Header.Clone())
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHostAuth_Headers(t *testing.T) {
+	tests := []struct {
+		name   string
+		auth   HostAuth
+		header string
+		want   string
+	}{
+		{"bearer", BearerAuth("secret"), "Authorization", "Bearer secret"},
+		{"basic", BasicAuth("ci", "hunter2"), "Authorization", "Basic Y2k6aHVudGVyMg=="},
+		{"header", HeaderAuth("PRIVATE-TOKEN", "secret"), "Private-Token", "secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []http.Header
+			srv := recordingServer(t, &got)
+			host := srv.Listener.Addr().String()
+
+			c := New(Config{HostAuth: map[string]HostAuth{host: tt.auth}, CLIConfigPaths: []string{}})
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/archive.zip", nil)
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if len(got) != 1 || got[0].Get(tt.header) != tt.want {
+				t.Errorf("expected %s: %s, got %v", tt.header, tt.want, got)
+			}
+			if req.Header.Get(tt.header) != "" {
+				t.Error("expected the caller's request to be left unchanged")
+			}
+		})
+	}
+}
+
+func TestHostAuth_NotSentToOtherHosts(t *testing.T) {
+	var targetHeaders, originHeaders []http.Header
+	target := recordingServer(t, &targetHeaders)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originHeaders = append(originHeaders, r.Header.Clone())
+		http.Redirect(w, r, target.URL+"/archive.zip", http.StatusFound)
+	}))
+	t.Cleanup(origin.Close)
+
+	c := New(Config{
+		HostAuth:       map[string]HostAuth{origin.Listener.Addr().String(): HeaderAuth("X-Archive-Token", "secret")},
+		CLIConfigPaths: []string{},
+	})
+	req, _ := http.NewRequest(http.MethodGet, origin.URL+"/download", nil)
+	req.Header.Set("Authorization", "Bearer registry-token")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if len(originHeaders) != 1 || originHeaders[0].Get("X-Archive-Token") != "secret" {
+		t.Errorf("expected credentials on the configured host, got %v", originHeaders)
+	}
+	if len(targetHeaders) != 1 {
+		t.Fatalf("expected the redirect to be followed, got %d requests", len(targetHeaders))
+	}
+	if v := targetHeaders[0].Get("X-Archive-Token"); v != "" {
+		t.Errorf("host credentials leaked on redirect: %q", v)
+	}
+	if v := targetHeaders[0].Get("Authorization"); v != "" {
+		t.Errorf("registry token leaked on redirect: %q", v)
+	}
+}
+
+func TestHostAuth_RequiresHTTPS(t *testing.T) {
+	var sent []http.Header
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent = append(sent, req.Header.Clone())
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	transport := &hostAuthTransport{
+		base: base,
+		auth: map[string]HostAuth{"artifacts.corp.example": BearerAuth("secret")},
+	}
+
+	for _, raw := range []string{"http://artifacts.corp.example/a.zip", "https://ARTIFACTS.corp.example/a.zip"} {
+		u, _ := url.Parse(raw)
+		if _, err := transport.RoundTrip(&http.Request{URL: u, Header: http.Header{}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if sent[0].Get("Authorization") != "" {
+		t.Error("expected no credentials over plain HTTP")
+	}
+	if sent[1].Get("Authorization") != "Bearer secret" {
+		t.Error("expected credentials over HTTPS regardless of host case")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,155 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+)
+
+// DefaultHostConcurrency is the number of concurrent requests allowed to
+// one host before it throttles
+const DefaultHostConcurrency = 16
+
+// sharedLimiter is used by every client that does not configure its own, so
+// the registry client and the downloader back off together
+var sharedLimiter = NewLimiter(DefaultHostConcurrency)
+
+// Limiter schedules requests per host. When a host answers 429 or 503, all
+// requests to it are paused for its Retry-After (or an exponential cooldown),
+// capped by the backoff limit of the client that saw the response, and its
+// concurrency is halved. Concurrency grows back by one for every
+// window of successful requests.
+type Limiter struct {
+	maxConcurrency int
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState tracks the schedule of one host
+type hostState struct {
+	limit       int       // current concurrency limit
+	inFlight    int       // requests waiting for response headers
+	successes   int       // successful responses since the limit last grew
+	throttles   int       // consecutive throttled responses
+	pausedUntil time.Time // no requests are sent before this time
+	wake        chan struct{}
+}
+
+// NewLimiter creates a limiter allowing up to maxConcurrency concurrent
+// requests per host
+func NewLimiter(maxConcurrency int) *Limiter {
+	if maxConcurrency <= 0 {
+		maxConcurrency = DefaultHostConcurrency
+	}
+	return &Limiter{
+		maxConcurrency: maxConcurrency,
+		hosts:          make(map[string]*hostState),
+	}
+}
+
+// state returns the schedule of a host; the caller holds l.mu
+func (l *Limiter) state(host string) *hostState {
+	host = strings.ToLower(host)
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostState{limit: l.maxConcurrency, wake: make(chan struct{})}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// acquire waits until the host is not paused and has a free slot
+func (l *Limiter) acquire(ctx context.Context, host string) error {
+	for {
+		l.mu.Lock()
+		h := l.state(host)
+		wait := time.Until(h.pausedUntil)
+		if wait <= 0 && h.inFlight < h.limit {
+			h.inFlight++
+			l.mu.Unlock()
+			return nil
+		}
+		wake := h.wake
+		l.mu.Unlock()
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			err := ctx.Err()
+			if timer != nil {
+				timer.Stop()
+			}
+			return err
+		case <-wake:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// release frees the slot taken by acquire
+func (l *Limiter) release(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h := l.state(host)
+	h.inFlight--
+	h.notify()
+}
+
+// observe adapts the schedule of a host to a response status. A throttled
+// host is paused for at most maxPause.
+func (l *Limiter) observe(host string, status int, retryAfter, maxPause time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h := l.state(host)
+	if status != http.StatusTooManyRequests && status != http.StatusServiceUnavailable {
+		h.throttles = 0
+		h.successes++
+		if h.successes >= h.limit && h.limit < l.maxConcurrency {
+			h.limit++
+			h.successes = 0
+			h.notify()
+		}
+		return
+	}
+
+	h.throttles++
+	h.successes = 0
+	h.limit = max(1, h.limit/2)
+
+	pause := retryAfter
+	if pause <= 0 {
+		pause = time.Second << min(h.throttles-1, 6)
+	}
+	pause = min(pause, maxPause)
+
+	if until := time.Now().Add(pause); until.After(h.pausedUntil) {
+		h.pausedUntil = until
+		logging.Default().Verbose("host throttled",
+			"host", host,
+			"status", status,
+			"pause", pause,
+			"concurrency", h.limit,
+		)
+	}
+}
+
+// notify wakes requests waiting for the host; the caller holds l.mu
+func (h *hostState) notify() {
+	close(h.wake)
+	h.wake = make(chan struct{})
+}
@@ -0,0 +1,164 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// hostLimit returns the current concurrency limit of a host
+func hostLimit(l *Limiter, host string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state(host).limit
+}
+
+// --- Concurrency tests ---
+
+func TestLimiter_AdaptsConcurrency(t *testing.T) {
+	l := NewLimiter(8)
+	host := "registry.example.com"
+
+	l.observe(host, http.StatusTooManyRequests, time.Millisecond, time.Second)
+	if got := hostLimit(l, host); got != 4 {
+		t.Errorf("expected limit halved to 4, got %d", got)
+	}
+	l.observe(host, http.StatusServiceUnavailable, time.Millisecond, time.Second)
+	l.observe(host, http.StatusServiceUnavailable, time.Millisecond, time.Second)
+	l.observe(host, http.StatusServiceUnavailable, time.Millisecond, time.Second)
+	if got := hostLimit(l, host); got != 1 {
+		t.Errorf("expected limit of at least 1, got %d", got)
+	}
+
+	l.observe(host, http.StatusInternalServerError, 0, time.Second)
+	if got := hostLimit(l, host); got != 2 {
+		t.Errorf("expected limit to grow after a window of responses, got %d", got)
+	}
+	for range 2 {
+		l.observe(host, http.StatusOK, 0, time.Second)
+	}
+	if got := hostLimit(l, host); got != 3 {
+		t.Errorf("expected limit 3, got %d", got)
+	}
+
+	if got := hostLimit(l, "other.example.com"); got != 8 {
+		t.Errorf("expected other hosts to be unaffected, got %d", got)
+	}
+}
+
+func TestLimiter_WaitsForFreeSlot(t *testing.T) {
+	l := NewLimiter(1)
+	ctx := context.Background()
+	host := "registry.example.com"
+
+	if err := l.acquire(ctx, host); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if err := l.acquire(ctx, host); err == nil {
+			close(acquired)
+		}
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("expected the second request to wait for a free slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	l.release(host)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("expected the second request to proceed after release")
+	}
+}
+
+// --- Pause tests ---
+
+func TestLimiter_PausesHost(t *testing.T) {
+	l := NewLimiter(4)
+	host := "registry.example.com"
+
+	l.observe(host, http.StatusTooManyRequests, 50*time.Millisecond, time.Second)
+
+	start := time.Now()
+	if err := l.acquire(context.Background(), host); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected to wait for the pause, waited %v", elapsed)
+	}
+
+	start = time.Now()
+	if err := l.acquire(context.Background(), "other.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("expected other hosts not to wait, waited %v", elapsed)
+	}
+}
+
+func TestLimiter_PauseCappedByMaxPause(t *testing.T) {
+	l := NewLimiter(4)
+	host := "registry.example.com"
+
+	l.observe(host, http.StatusTooManyRequests, time.Hour, 30*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := l.acquire(ctx, host); err != nil {
+		t.Errorf("expected the pause to be capped, got %v", err)
+	}
+}
+
+func TestLimiter_AcquireCanceled(t *testing.T) {
+	l := NewLimiter(4)
+	host := "registry.example.com"
+	l.observe(host, http.StatusServiceUnavailable, time.Minute, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, host); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestLimiter_SharedAcrossClients(t *testing.T) {
+	throttled := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !throttled {
+			throttled = true
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	limiter := NewLimiter(4)
+	cfg := Config{MaxBackoff: 100 * time.Millisecond, Limiter: limiter, CLIConfigPaths: []string{}}
+	registryClient, downloadClient := New(cfg), New(cfg)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := registryClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	start := time.Now()
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err = downloadClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected the other client to wait for the throttled host, waited %v", elapsed)
+	}
+}
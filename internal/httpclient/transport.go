@@ -0,0 +1,164 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// TLSConfig configures server verification and the client certificate used
+// for mutual TLS
+type TLSConfig struct {
+	CAFile   string // PEM bundle trusted in addition to the system roots
+	CertFile string // PEM client certificate
+	KeyFile  string // PEM private key of the client certificate
+}
+
+// TransportConfig configures how connections are made
+type TransportConfig struct {
+	// Proxy is the proxy URL for all requests. Empty uses HTTPS_PROXY,
+	// HTTP_PROXY and NO_PROXY from the environment.
+	Proxy string
+
+	// TLS applies to every host
+	TLS TLSConfig
+
+	// HostTLS holds settings by hostname. Fields set for a host replace the
+	// corresponding fields of TLS.
+	HostTLS map[string]TLSConfig
+}
+
+// Transport holds loaded connection settings, ready to be shared by clients
+type Transport struct {
+	proxy func(*http.Request) (*url.URL, error)
+	tls   *tls.Config
+	hosts map[string]*tls.Config // by lowercase hostname
+}
+
+// Load reads the proxy and TLS settings, including certificate files
+func (c TransportConfig) Load() (*Transport, error) {
+	t := &Transport{proxy: http.ProxyFromEnvironment}
+
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", c.Proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("invalid proxy URL %q: unsupported scheme %q", c.Proxy, u.Scheme)
+		}
+		t.proxy = http.ProxyURL(u)
+	}
+
+	var err error
+	if t.tls, err = c.TLS.load(); err != nil {
+		return nil, err
+	}
+
+	for host, hostTLS := range c.HostTLS {
+		merged := c.TLS
+		if hostTLS.CAFile != "" {
+			merged.CAFile = hostTLS.CAFile
+		}
+		if hostTLS.CertFile != "" || hostTLS.KeyFile != "" {
+			merged.CertFile, merged.KeyFile = hostTLS.CertFile, hostTLS.KeyFile
+		}
+
+		cfg, err := merged.load()
+		if err != nil {
+			return nil, fmt.Errorf("TLS settings for %s: %w", host, err)
+		}
+		if t.hosts == nil {
+			t.hosts = make(map[string]*tls.Config)
+		}
+		t.hosts[normalizeHost(host)] = cfg
+	}
+
+	return t, nil
+}
+
+// load builds a tls.Config, or returns nil when nothing is configured
+func (c TLSConfig) load() (*tls.Config, error) {
+	if c == (TLSConfig{}) {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	switch {
+	case c.CertFile != "" && c.KeyFile != "":
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	case c.CertFile != "":
+		return nil, errors.New("client certificate requires a key")
+	case c.KeyFile != "":
+		return nil, errors.New("client key requires a certificate")
+	}
+
+	return cfg, nil
+}
+
+// roundTripper builds the transport for a client, with a separate connection
+// pool for each host that has its own TLS settings
+func (t *Transport) roundTripper(cfg Config) http.RoundTripper {
+	if t == nil {
+		t = &Transport{proxy: http.ProxyFromEnvironment}
+	}
+
+	newTransport := func(tlsConfig *tls.Config) *http.Transport {
+		return &http.Transport{
+			Proxy:               t.proxy,
+			TLSClientConfig:     tlsConfig.Clone(),
+			ForceAttemptHTTP2:   true,
+			MaxConnsPerHost:     cfg.MaxConnsPerHost,
+			MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+			MaxIdleConns:        cfg.MaxConnsPerHost * 2, // Allow more total idle connections
+		}
+	}
+
+	if len(t.hosts) == 0 {
+		return newTransport(t.tls)
+	}
+	r := &hostTransport{base: newTransport(t.tls), hosts: make(map[string]http.RoundTripper, len(t.hosts))}
+	for host, tlsConfig := range t.hosts {
+		r.hosts[host] = newTransport(tlsConfig)
+	}
+	return r
+}
+
+// hostTransport routes requests to the transport configured for their host
+type hostTransport struct {
+	base  http.RoundTripper
+	hosts map[string]http.RoundTripper
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt, ok := t.hosts[strings.ToLower(req.URL.Hostname())]; ok {
+		return rt.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,174 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCertPEM writes a certificate as PEM and returns its path
+func writeCertPEM(t *testing.T, dir, name string, der []byte) string {
+	t.Helper()
+	return writeFile(t, dir, name, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+}
+
+// clientCertificate generates a self-signed client certificate and writes the
+// certificate and key as PEM files
+func clientCertificate(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "provider-mirror"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := writeCertPEM(t, dir, "client.pem", der)
+	keyFile := writeFile(t, dir, "client-key.pem", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	return cert, certFile, keyFile
+}
+
+// get performs a GET request with a client using the given transport settings
+func get(t *testing.T, cfg TransportConfig, url string) error {
+	t.Helper()
+	transport, err := cfg.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	c := New(Config{Transport: transport, CLIConfigPaths: []string{}})
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// --- TLS tests ---
+
+func TestTransport_CAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	caFile := writeCertPEM(t, t.TempDir(), "ca.pem", srv.Certificate().Raw)
+
+	if err := get(t, TransportConfig{}, srv.URL); err == nil {
+		t.Error("expected verification to fail without the CA bundle")
+	}
+	if err := get(t, TransportConfig{TLS: TLSConfig{CAFile: caFile}}, srv.URL); err != nil {
+		t.Errorf("expected success with the CA bundle, got %v", err)
+	}
+
+	hostTLS := map[string]TLSConfig{"127.0.0.1": {CAFile: caFile}}
+	if err := get(t, TransportConfig{HostTLS: hostTLS}, srv.URL); err != nil {
+		t.Errorf("expected success with a per-host CA bundle, got %v", err)
+	}
+	hostTLS = map[string]TLSConfig{"other.example": {CAFile: caFile}}
+	if err := get(t, TransportConfig{HostTLS: hostTLS}, srv.URL); err == nil {
+		t.Error("expected another host's CA bundle not to apply")
+	}
+}
+
+func TestTransport_ClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	cert, certFile, keyFile := clientCertificate(t, dir)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	caFile := writeCertPEM(t, dir, "ca.pem", srv.Certificate().Raw)
+
+	if err := get(t, TransportConfig{TLS: TLSConfig{CAFile: caFile}}, srv.URL); err == nil {
+		t.Error("expected the server to reject a client without a certificate")
+	}
+
+	cfg := TransportConfig{
+		TLS:     TLSConfig{CAFile: caFile},
+		HostTLS: map[string]TLSConfig{"127.0.0.1": {CertFile: certFile, KeyFile: keyFile}},
+	}
+	if err := get(t, cfg, srv.URL); err != nil {
+		t.Errorf("expected success with the client certificate, got %v", err)
+	}
+}
+
+// --- Proxy tests ---
+
+func TestTransport_Proxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	if err := get(t, TransportConfig{Proxy: proxy.URL}, "http://registry.corp.example/v1/providers"); err != nil {
+		t.Fatalf("request through proxy failed: %v", err)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://registry.corp.example/v1/providers" {
+		t.Errorf("expected the request to go through the proxy, got %v", proxied)
+	}
+}
+
+// --- Load error tests ---
+
+func TestTransportConfig_LoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := writeFile(t, dir, "ca.pem", "not a certificate")
+
+	tests := []struct {
+		name string
+		cfg  TransportConfig
+		want string
+	}{
+		{"proxy without host", TransportConfig{Proxy: "proxy.corp.example"}, "invalid proxy URL"},
+		{"proxy scheme", TransportConfig{Proxy: "ftp://proxy.corp.example"}, "unsupported scheme"},
+		{"missing CA bundle", TransportConfig{TLS: TLSConfig{CAFile: dir + "/missing.pem"}}, "reading CA bundle"},
+		{"empty CA bundle", TransportConfig{TLS: TLSConfig{CAFile: notPEM}}, "no certificates found"},
+		{"certificate without key", TransportConfig{TLS: TLSConfig{CertFile: notPEM}}, "requires a key"},
+		{"key without certificate", TransportConfig{TLS: TLSConfig{KeyFile: notPEM}}, "requires a certificate"},
+		{
+			"host settings",
+			TransportConfig{HostTLS: map[string]TLSConfig{"registry.corp.example": {CertFile: notPEM, KeyFile: notPEM}}},
+			"TLS settings for registry.corp.example: loading client certificate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cfg.Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package lockdiff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+)
+
+// Status describes how an entry differs between two lock files
+type Status string
+
+const (
+	StatusAdded   Status = "added"
+	StatusRemoved Status = "removed"
+	StatusChanged Status = "changed"
+)
+
+// Diff lists the differences between two lock files
+type Diff struct {
+	Providers []ProviderDiff `json:"providers"`
+}
+
+// ProviderDiff lists the differences of one provider
+type ProviderDiff struct {
+	Source   string        `json:"source"` // hostname/namespace/name
+	Status   Status        `json:"status"`
+	Versions []VersionDiff `json:"versions,omitempty"`
+}
+
+// VersionDiff lists the differences of one provider version
+type VersionDiff struct {
+	Version string `json:"version"`
+	Status  Status `json:"status"`
+
+	Platforms        []string `json:"platforms,omitempty"`         // every platform of an added or removed version
+	AddedPlatforms   []string `json:"added_platforms,omitempty"`   // platforms added to a version present in both
+	RemovedPlatforms []string `json:"removed_platforms,omitempty"` // platforms removed from a version present in both
+
+	Checksums []ChecksumChange `json:"checksums,omitempty"`
+}
+
+// ChecksumChange records a hash that differs for the same version and
+// platform, which means the release was published again upstream
+type ChecksumChange struct {
+	Platform string `json:"platform"`
+	Field    string `json:"field"` // "sha256" or "h1"
+	Old      string `json:"old"`
+	New      string `json:"new"`
+}
+
+// Empty returns true if the lock files are equivalent
+func (d *Diff) Empty() bool {
+	return len(d.Providers) == 0
+}
+
+// ChecksumChanges returns every checksum change in the diff
+func (d *Diff) ChecksumChanges() []ChecksumChange {
+	var changes []ChecksumChange
+	for _, p := range d.Providers {
+		for _, v := range p.Versions {
+			changes = append(changes, v.Checksums...)
+		}
+	}
+	return changes
+}
+
+// Compare returns the differences from old to new. The generation time and
+// manifest sources are ignored. Checksums are only compared when both lock
+// files record them, so a plan without hashes can be compared with a mirror.
+func Compare(old, new *mirror.LockFile) *Diff {
+	oldProviders := indexProviders(old)
+	newProviders := indexProviders(new)
+
+	d := &Diff{}
+	for _, source := range unionKeys(oldProviders, newProviders) {
+		op, inOld := oldProviders[source]
+		np, inNew := newProviders[source]
+
+		pd := ProviderDiff{Source: source, Status: StatusChanged}
+		switch {
+		case !inOld:
+			pd.Status = StatusAdded
+		case !inNew:
+			pd.Status = StatusRemoved
+		}
+
+		pd.Versions = compareVersions(op, np)
+		if len(pd.Versions) > 0 || pd.Status != StatusChanged {
+			d.Providers = append(d.Providers, pd)
+		}
+	}
+	return d
+}
+
+// compareVersions returns the differences between the versions of a provider
+func compareVersions(old, new map[string]mirror.LockFileVersion) []VersionDiff {
+	var diffs []VersionDiff
+
+	for _, v := range sortVersions(unionKeys(old, new)) {
+		ov, inOld := old[v]
+		nv, inNew := new[v]
+
+		switch {
+		case !inOld:
+			diffs = append(diffs, VersionDiff{Version: v, Status: StatusAdded, Platforms: platformNames(nv)})
+		case !inNew:
+			diffs = append(diffs, VersionDiff{Version: v, Status: StatusRemoved, Platforms: platformNames(ov)})
+		default:
+			vd := comparePlatforms(ov, nv)
+			if len(vd.AddedPlatforms) > 0 || len(vd.RemovedPlatforms) > 0 || len(vd.Checksums) > 0 {
+				diffs = append(diffs, vd)
+			}
+		}
+	}
+
+	return diffs
+}
+
+// comparePlatforms compares the platforms and checksums of a version
+func comparePlatforms(old, new mirror.LockFileVersion) VersionDiff {
+	vd := VersionDiff{Version: new.Version, Status: StatusChanged}
+
+	oldPlatforms := indexPlatforms(old)
+	newPlatforms := indexPlatforms(new)
+
+	for _, platform := range unionKeys(oldPlatforms, newPlatforms) {
+		op, inOld := oldPlatforms[platform]
+		np, inNew := newPlatforms[platform]
+
+		switch {
+		case !inOld:
+			vd.AddedPlatforms = append(vd.AddedPlatforms, platform)
+		case !inNew:
+			vd.RemovedPlatforms = append(vd.RemovedPlatforms, platform)
+		default:
+			if op.SHA256 != "" && np.SHA256 != "" && op.SHA256 != np.SHA256 {
+				vd.Checksums = append(vd.Checksums, ChecksumChange{platform, "sha256", op.SHA256, np.SHA256})
+			}
+			if op.H1 != "" && np.H1 != "" && op.H1 != np.H1 {
+				vd.Checksums = append(vd.Checksums, ChecksumChange{platform, "h1", op.H1, np.H1})
+			}
+		}
+	}
+
+	return vd
+}
+
+// indexProviders maps provider addresses to their versions
+func indexProviders(l *mirror.LockFile) map[string]map[string]mirror.LockFileVersion {
+	index := make(map[string]map[string]mirror.LockFileVersion)
+	if l == nil {
+		return index
+	}
+
+	for _, p := range l.Providers {
+		source := strings.ToLower(p.Hostname + "/" + p.Namespace + "/" + p.Name)
+		if index[source] == nil {
+			index[source] = make(map[string]mirror.LockFileVersion)
+		}
+		for _, v := range p.Versions {
+			index[source][v.Version] = v
+		}
+	}
+	return index
+}
+
+// indexPlatforms maps os_arch names to the platforms of a version
+func indexPlatforms(v mirror.LockFileVersion) map[string]mirror.LockFilePlatform {
+	index := make(map[string]mirror.LockFilePlatform)
+	for _, p := range v.Platforms {
+		index[p.OS+"_"+p.Arch] = p
+	}
+	return index
+}
+
+// platformNames returns the sorted os_arch names of a version's platforms
+func platformNames(v mirror.LockFileVersion) []string {
+	var names []string
+	for _, p := range v.Platforms {
+		names = append(names, p.OS+"_"+p.Arch)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unionKeys returns the sorted keys present in either map
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortVersions sorts versions newest first. Unparsable versions sort last.
+func sortVersions(versions []string) []string {
+	sort.SliceStable(
+		versions, func(i, j int) bool {
+			vi, errI := version.NewVersion(versions[i])
+			vj, errJ := version.NewVersion(versions[j])
+			switch {
+			case errI != nil || errJ != nil:
+				return errI == nil && errJ != nil
+			default:
+				return vi.GreaterThan(vj)
+			}
+		},
+	)
+	return versions
+}
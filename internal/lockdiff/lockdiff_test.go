@@ -0,0 +1,183 @@
+package lockdiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+)
+
+func lockVersion(version string, platforms ...mirror.LockFilePlatform) mirror.LockFileVersion {
+	return mirror.LockFileVersion{Version: version, Platforms: platforms}
+}
+
+func lockPlatform(os, arch, sha256, h1 string) mirror.LockFilePlatform {
+	return mirror.LockFilePlatform{OS: os, Arch: arch, SHA256: sha256, H1: h1}
+}
+
+func lockFile(providers ...mirror.LockFileProvider) *mirror.LockFile {
+	return &mirror.LockFile{Version: 1, Providers: providers}
+}
+
+func lockProvider(name string, versions ...mirror.LockFileVersion) mirror.LockFileProvider {
+	return mirror.LockFileProvider{
+		Hostname:  "registry.terraform.io",
+		Namespace: "hashicorp",
+		Name:      name,
+		Versions:  versions,
+	}
+}
+
+// --- Compare tests ---
+
+func TestCompare_Identical(t *testing.T) {
+	old := lockFile(lockProvider("aws", lockVersion("5.9.0", lockPlatform("linux", "amd64", "aaa", "h1:a"))))
+	old.GeneratedAt = "2025-01-01T00:00:00Z"
+	new := lockFile(lockProvider("aws", lockVersion("5.9.0", lockPlatform("linux", "amd64", "aaa", "h1:a"))))
+	new.GeneratedAt = "2025-02-01T00:00:00Z"
+	new.Providers[0].Versions[0].ManifestSources = []string{"hashicorp/aws"}
+
+	if d := Compare(old, new); !d.Empty() {
+		t.Errorf("expected no differences, got %+v", d.Providers)
+	}
+}
+
+func TestCompare_ProvidersAndVersions(t *testing.T) {
+	old := lockFile(
+		lockProvider(
+			"aws",
+			lockVersion("5.9.0", lockPlatform("linux", "amd64", "", "")),
+			lockVersion("5.8.0", lockPlatform("linux", "amd64", "", "")),
+		),
+		lockProvider("null", lockVersion("3.2.4", lockPlatform("linux", "amd64", "", ""))),
+	)
+	new := lockFile(
+		lockProvider(
+			"aws",
+			lockVersion("5.10.0", lockPlatform("linux", "amd64", "", ""), lockPlatform("darwin", "arm64", "", "")),
+			lockVersion("5.9.0", lockPlatform("linux", "amd64", "", "")),
+		),
+		lockProvider("google", lockVersion("6.2.0", lockPlatform("linux", "amd64", "", ""))),
+	)
+
+	want := []ProviderDiff{
+		{
+			Source: "registry.terraform.io/hashicorp/aws",
+			Status: StatusChanged,
+			Versions: []VersionDiff{
+				{Version: "5.10.0", Status: StatusAdded, Platforms: []string{"darwin_arm64", "linux_amd64"}},
+				{Version: "5.8.0", Status: StatusRemoved, Platforms: []string{"linux_amd64"}},
+			},
+		},
+		{
+			Source:   "registry.terraform.io/hashicorp/google",
+			Status:   StatusAdded,
+			Versions: []VersionDiff{{Version: "6.2.0", Status: StatusAdded, Platforms: []string{"linux_amd64"}}},
+		},
+		{
+			Source:   "registry.terraform.io/hashicorp/null",
+			Status:   StatusRemoved,
+			Versions: []VersionDiff{{Version: "3.2.4", Status: StatusRemoved, Platforms: []string{"linux_amd64"}}},
+		},
+	}
+
+	if got := Compare(old, new).Providers; !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestCompare_PlatformsAndChecksums(t *testing.T) {
+	old := lockFile(
+		lockProvider(
+			"aws",
+			lockVersion(
+				"5.9.0",
+				lockPlatform("linux", "amd64", "aaa", "h1:a"),
+				lockPlatform("darwin", "arm64", "bbb", "h1:b"),
+			),
+		),
+	)
+	new := lockFile(
+		lockProvider(
+			"aws",
+			lockVersion(
+				"5.9.0",
+				lockPlatform("linux", "amd64", "ccc", "h1:c"),
+				lockPlatform("windows", "amd64", "ddd", "h1:d"),
+			),
+		),
+	)
+
+	d := Compare(old, new)
+	if len(d.Providers) != 1 || len(d.Providers[0].Versions) != 1 {
+		t.Fatalf("expected one changed version, got %+v", d.Providers)
+	}
+
+	v := d.Providers[0].Versions[0]
+	if v.Status != StatusChanged {
+		t.Errorf("expected changed status, got %s", v.Status)
+	}
+	if !reflect.DeepEqual(v.AddedPlatforms, []string{"windows_amd64"}) ||
+		!reflect.DeepEqual(v.RemovedPlatforms, []string{"darwin_arm64"}) {
+		t.Errorf("unexpected platform changes: +%v -%v", v.AddedPlatforms, v.RemovedPlatforms)
+	}
+
+	want := []ChecksumChange{
+		{Platform: "linux_amd64", Field: "sha256", Old: "aaa", New: "ccc"},
+		{Platform: "linux_amd64", Field: "h1", Old: "h1:a", New: "h1:c"},
+	}
+	if got := d.ChecksumChanges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ChecksumChanges() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCompare_MissingChecksumsAreNotCompared(t *testing.T) {
+	// A plan has no checksums yet
+	mirrored := lockFile(lockProvider("aws", lockVersion("5.9.0", lockPlatform("linux", "amd64", "aaa", "h1:a"))))
+	planned := lockFile(lockProvider("aws", lockVersion("5.9.0", lockPlatform("linux", "amd64", "", ""))))
+
+	if d := Compare(mirrored, planned); !d.Empty() {
+		t.Errorf("expected no differences, got %+v", d.Providers)
+	}
+}
+
+func TestCompare_NilLockFile(t *testing.T) {
+	new := lockFile(lockProvider("aws", lockVersion("5.9.0", lockPlatform("linux", "amd64", "", ""))))
+
+	d := Compare(nil, new)
+	if len(d.Providers) != 1 || d.Providers[0].Status != StatusAdded {
+		t.Errorf("expected added provider, got %+v", d.Providers)
+	}
+}
+
+func TestDiff_JSON(t *testing.T) {
+	old := lockFile(lockProvider("aws", lockVersion("5.9.0", lockPlatform("linux", "amd64", "", ""))))
+	new := lockFile(
+		lockProvider(
+			"aws",
+			lockVersion("5.9.0", lockPlatform("linux", "amd64", "", "")),
+			lockVersion("5.10.0", lockPlatform("linux", "amd64", "", "")),
+		),
+	)
+
+	data, err := json.Marshal(Compare(old, new))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"providers":[{"source":"registry.terraform.io/hashicorp/aws","status":"changed",` +
+		`"versions":[{"version":"5.10.0","status":"added","platforms":["linux_amd64"]}]}]}`
+	if string(data) != want {
+		t.Errorf("unexpected JSON:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+// --- sortVersions tests ---
+
+func TestSortVersions(t *testing.T) {
+	got := sortVersions([]string{"5.9.0", "bad", "5.10.0", "4.0.0"})
+	want := []string{"5.10.0", "5.9.0", "4.0.0", "bad"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortVersions() = %v, want %v", got, want)
+	}
+}
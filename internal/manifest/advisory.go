@@ -0,0 +1,77 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v3"
+)
+
+// Advisory marks a range of provider versions that must never be mirrored
+type Advisory struct {
+	Source   string `yaml:"source"`   // namespace/name or hostname/namespace/name
+	Versions string `yaml:"versions"` // affected version constraint
+	Reason   string `yaml:"reason"`
+}
+
+// advisoryFile represents the on-disk advisory file format (YAML or JSON)
+type advisoryFile struct {
+	Advisories []Advisory `yaml:"advisories"`
+}
+
+// Matches returns true if the advisory applies to the given provider address.
+// Advisories without a hostname apply to the provider on every registry.
+func (a Advisory) Matches(source ProviderSource) bool {
+	parsed, err := ParseProviderSource(a.Source)
+	if err != nil {
+		return false
+	}
+	if parsed.Hostname != "" && parsed.Hostname != source.Hostname {
+		return false
+	}
+	return parsed.Namespace == source.Namespace && parsed.Name == source.Name
+}
+
+// LoadAdvisories reads an advisory file
+func LoadAdvisories(path string) ([]Advisory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading advisory file: %w", err)
+	}
+
+	var f advisoryFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing advisory file %s: %w", path, err)
+	}
+
+	for i, a := range f.Advisories {
+		if _, err := ParseProviderSource(a.Source); err != nil {
+			return nil, fmt.Errorf("advisory %d in %s: %w", i, path, err)
+		}
+		if _, err := version.NewConstraint(a.Versions); err != nil {
+			return nil, fmt.Errorf(
+				"advisory %d in %s: invalid versions %q: %w", i, path, a.Versions, err,
+			)
+		}
+	}
+
+	return f.Advisories, nil
+}
+
+// loadAdvisories loads all advisory files referenced by the manifest.
+// Relative paths are resolved against baseDir.
+func (m *Manifest) loadAdvisories(baseDir string) error {
+	for _, path := range m.AdvisoryFiles {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		advisories, err := LoadAdvisories(path)
+		if err != nil {
+			return err
+		}
+		m.Advisories = append(m.Advisories, advisories...)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// --- Advisory tests ---
+
+func TestAdvisory_Matches(t *testing.T) {
+	tf := ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+	tofu := ProviderSource{Hostname: "registry.opentofu.org", Namespace: "hashicorp", Name: "aws"}
+
+	tests := []struct {
+		name   string
+		source string
+		target ProviderSource
+		want   bool
+	}{
+		{"short source matches terraform", "hashicorp/aws", tf, true},
+		{"short source matches opentofu", "hashicorp/aws", tofu, true},
+		{"full source matches same host", "registry.terraform.io/hashicorp/aws", tf, true},
+		{"full source ignores other host", "registry.terraform.io/hashicorp/aws", tofu, false},
+		{"different name", "hashicorp/google", tf, false},
+		{"invalid source", "aws", tf, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Advisory{Source: tt.source, Versions: "5.31.0"}
+			if got := a.Matches(tt.target); got != tt.want {
+				t.Errorf("Advisory{%q}.Matches(%s) = %v, want %v", tt.source, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+// --- LoadAdvisories tests ---
+
+func TestLoadAdvisories_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "advisories.yaml")
+	content := `
+advisories:
+  - source: hashicorp/aws
+    versions: ">= 5.31.0, < 5.31.2"
+    reason: broken S3 backend
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write advisory file: %v", err)
+	}
+
+	advisories, err := LoadAdvisories(path)
+	if err != nil {
+		t.Fatalf("LoadAdvisories() error = %v", err)
+	}
+
+	if len(advisories) != 1 {
+		t.Fatalf("expected 1 advisory, got %d", len(advisories))
+	}
+
+	if advisories[0].Reason != "broken S3 backend" {
+		t.Errorf("unexpected reason: %s", advisories[0].Reason)
+	}
+}
+
+func TestLoadAdvisories_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "advisories.json")
+	content := `{"advisories": [{"source": "hashicorp/null", "versions": "3.2.0", "reason": "regression"}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write advisory file: %v", err)
+	}
+
+	advisories, err := LoadAdvisories(path)
+	if err != nil {
+		t.Fatalf("LoadAdvisories() error = %v", err)
+	}
+
+	if len(advisories) != 1 || advisories[0].Source != "hashicorp/null" {
+		t.Errorf("unexpected advisories: %+v", advisories)
+	}
+}
+
+func TestLoadAdvisories_InvalidConstraint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "advisories.yaml")
+	content := `
+advisories:
+  - source: hashicorp/aws
+    versions: "not a version"
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write advisory file: %v", err)
+	}
+
+	if _, err := LoadAdvisories(path); err == nil {
+		t.Error("expected error for invalid version constraint")
+	}
+}
+
+func TestLoadAdvisories_InvalidSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "advisories.yaml")
+	content := `
+advisories:
+  - source: aws
+    versions: "1.0.0"
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write advisory file: %v", err)
+	}
+
+	if _, err := LoadAdvisories(path); err == nil {
+		t.Error("expected error for invalid source")
+	}
+}
+
+func TestLoad_ResolvesAdvisoriesRelativeToManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	advisories := `
+advisories:
+  - source: hashicorp/null
+    versions: "3.2.0"
+`
+	if err := os.MkdirAll(filepath.Join(dir, "security"), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "security", "advisories.yaml"), []byte(advisories), 0644); err != nil {
+		t.Fatalf("failed to write advisory file: %v", err)
+	}
+
+	manifestContent := `
+defaults:
+  engines:
+    - terraform
+advisories:
+  - security/advisories.yaml
+providers:
+  - source: hashicorp/null
+    versions: ["~> 3.2"]
+    exclude: ["3.2.1"]
+`
+	path := filepath.Join(dir, "mirror.yaml")
+	if err := os.WriteFile(path, []byte(manifestContent), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(m.Advisories) != 1 {
+		t.Fatalf("expected 1 advisory, got %d", len(m.Advisories))
+	}
+
+	if len(m.Providers[0].Exclude) != 1 || m.Providers[0].Exclude[0] != "3.2.1" {
+		t.Errorf("expected exclude [3.2.1], got %v", m.Providers[0].Exclude)
+	}
+}
+
+func TestLoad_MissingAdvisoryFile(t *testing.T) {
+	dir := t.TempDir()
+	manifestContent := `
+defaults:
+  engines:
+    - terraform
+advisories:
+  - missing.yaml
+providers:
+  - source: hashicorp/null
+    versions: ["~> 3.2"]
+`
+	path := filepath.Join(dir, "mirror.yaml")
+	if err := os.WriteFile(path, []byte(manifestContent), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for missing advisory file")
+	}
+}
@@ -0,0 +1,125 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/httpclient"
+)
+
+// AuthType is the kind of credentials sent to an archive host
+type AuthType string
+
+const (
+	AuthBearer AuthType = "bearer" // Authorization: Bearer <token>
+	AuthBasic  AuthType = "basic"  // Authorization: Basic <username:password>
+	AuthHeader AuthType = "header" // <header>: <token>
+)
+
+// IsValid returns true if the type is a supported value
+func (t AuthType) IsValid() bool {
+	switch t {
+	case AuthBearer, AuthBasic, AuthHeader:
+		return true
+	default:
+		return false
+	}
+}
+
+// HostAuth configures the credentials sent to one archive download host.
+// Secrets are read from environment variables, never from the manifest.
+type HostAuth struct {
+	Type        AuthType `yaml:"type,omitempty"`         // defaults to bearer
+	TokenEnv    string   `yaml:"token_env,omitempty"`    // bearer and header
+	Header      string   `yaml:"header,omitempty"`       // header name, for header
+	Username    string   `yaml:"username,omitempty"`     // basic
+	PasswordEnv string   `yaml:"password_env,omitempty"` // basic
+}
+
+// AuthType returns the configured type, defaulting to bearer
+func (a HostAuth) AuthType() AuthType {
+	if a.Type == "" {
+		return AuthBearer
+	}
+	return a.Type
+}
+
+// Secret returns the token or password from the environment
+func (a HostAuth) Secret() (string, error) {
+	name := a.TokenEnv
+	if a.AuthType() == AuthBasic {
+		name = a.PasswordEnv
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// problems returns what is wrong with the configuration, if anything
+func (a HostAuth) problems() []string {
+	if !a.AuthType().IsValid() {
+		return []string{fmt.Sprintf("unsupported auth type: %s", a.Type)}
+	}
+
+	var problems []string
+	requireEnv := func(field, name string) {
+		switch {
+		case name == "":
+			problems = append(problems, fmt.Sprintf("%s is required for %s auth", field, a.AuthType()))
+		case !varNamePattern.MatchString(name):
+			problems = append(problems, fmt.Sprintf("invalid environment variable name %q in %s", name, field))
+		}
+	}
+
+	switch a.AuthType() {
+	case AuthBearer:
+		requireEnv("token_env", a.TokenEnv)
+	case AuthHeader:
+		requireEnv("token_env", a.TokenEnv)
+		if strings.TrimSpace(a.Header) == "" {
+			problems = append(problems, "header is required for header auth")
+		}
+	case AuthBasic:
+		if a.Username == "" {
+			problems = append(problems, "username is required for basic auth")
+		}
+		requireEnv("password_env", a.PasswordEnv)
+	}
+	return problems
+}
+
+// HostAuth returns the headers to send to each configured archive host,
+// reading secrets from the environment
+func (m *Manifest) HostAuth() (map[string]httpclient.HostAuth, error) {
+	result := make(map[string]httpclient.HostAuth, len(m.Auth))
+	for _, host := range authHosts(m.Auth) {
+		a := m.Auth[host]
+		secret, err := a.Secret()
+		if err != nil {
+			return nil, fmt.Errorf("auth for %s: %w", host, err)
+		}
+		switch a.AuthType() {
+		case AuthBasic:
+			result[host] = httpclient.BasicAuth(a.Username, secret)
+		case AuthHeader:
+			result[host] = httpclient.HeaderAuth(a.Header, secret)
+		default:
+			result[host] = httpclient.BearerAuth(secret)
+		}
+	}
+	return result, nil
+}
+
+// authHosts returns the configured hosts in sorted order
+func authHosts(auth map[string]HostAuth) []string {
+	hosts := make([]string, 0, len(auth))
+	for host := range auth {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// --- Auth validation tests ---
+
+func TestValidate_Auth(t *testing.T) {
+	tests := []struct {
+		name string
+		auth string
+		want string
+	}{
+		{"unsupported type", "type: digest\n    token_env: TOKEN", "unsupported auth type: digest"},
+		{"missing token", "type: bearer", "token_env is required for bearer auth"},
+		{"invalid variable", "token_env: my-token", `invalid environment variable name "my-token" in token_env`},
+		{"missing header", "type: header\n    token_env: TOKEN", "header is required for header auth"},
+		{"missing username", "type: basic\n    password_env: PASSWORD", "username is required for basic auth"},
+		{"missing password", "type: basic\n    username: ci", "password_env is required for basic auth"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verrs := parseValidationErrors(t, `
+defaults:
+  engines: [terraform]
+auth:
+  artifacts.corp.example:
+    `+tt.auth+`
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+`)
+
+			if len(verrs) != 1 || !strings.Contains(verrs[0].Message, "auth for artifacts.corp.example: "+tt.want) {
+				t.Errorf("expected %q, got %v", tt.want, verrs)
+			}
+		})
+	}
+}
+
+func TestValidate_AuthInvalidHostname(t *testing.T) {
+	verrs := parseValidationErrors(t, `
+defaults:
+  engines: [terraform]
+auth:
+  "https://artifacts.corp.example":
+    token_env: TOKEN
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+`)
+
+	if len(verrs) != 1 || verrs[0].Line != 6 || !strings.Contains(verrs[0].Message, "invalid hostname") {
+		t.Errorf("unexpected problems: %v", verrs)
+	}
+}
+
+// --- Host auth tests ---
+
+func TestHostAuth(t *testing.T) {
+	t.Setenv("ARTIFACTS_TOKEN", "secret")
+	t.Setenv("NEXUS_PASSWORD", "hunter2")
+
+	m, err := Parse([]byte(`
+defaults:
+  engines: [terraform]
+auth:
+  artifacts.corp.example:
+    token_env: ARTIFACTS_TOKEN
+  gitlab.corp.example:
+    type: header
+    header: PRIVATE-TOKEN
+    token_env: ARTIFACTS_TOKEN
+  nexus.corp.example:8443:
+    type: basic
+    username: ci
+    password_env: NEXUS_PASSWORD
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	auth, err := m.HostAuth()
+	if err != nil {
+		t.Fatalf("HostAuth() error = %v", err)
+	}
+
+	tests := []struct {
+		host   string
+		header string
+		value  string
+	}{
+		{"artifacts.corp.example", "Authorization", "Bearer secret"},
+		{"gitlab.corp.example", "PRIVATE-TOKEN", "secret"},
+		{"nexus.corp.example:8443", "Authorization", "Basic Y2k6aHVudGVyMg=="},
+	}
+
+	for _, tt := range tests {
+		got := auth[tt.host]
+		if got.Header != tt.header || got.Value != tt.value {
+			t.Errorf("HostAuth()[%q] = %+v, want %s: %s", tt.host, got, tt.header, tt.value)
+		}
+	}
+}
+
+func TestHostAuth_MissingVariable(t *testing.T) {
+	m := &Manifest{Auth: map[string]HostAuth{
+		"artifacts.corp.example": {TokenEnv: "PM_TEST_UNSET_TOKEN"},
+	}}
+
+	_, err := m.HostAuth()
+	if err == nil || !strings.Contains(err.Error(), "PM_TEST_UNSET_TOKEN is not set") {
+		t.Errorf("expected missing variable error, got %v", err)
+	}
+}
+
+func TestLoad_IncludeAuthConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir, map[string]string{
+			"mirror.yaml": `
+defaults:
+  engines: [terraform]
+include: [team.yaml]
+auth:
+  artifacts.corp.example:
+    token_env: ARTIFACTS_TOKEN
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+`,
+			"team.yaml": `
+auth:
+  Artifacts.Corp.Example:
+    token_env: TEAM_TOKEN
+providers:
+  - source: hashicorp/google
+    versions: ["~> 6.0"]
+`,
+		},
+	)
+
+	_, err := Load(filepath.Join(dir, "mirror.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "configured differently by another manifest") {
+		t.Errorf("expected auth conflict, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package manifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration that additionally accepts a day suffix ("7d")
+type Duration time.Duration
+
+// ParseDuration parses a duration string such as "36h", "7d" or "1d12h"
+func ParseDuration(s string) (Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	var days float64
+	if i := strings.Index(s, "d"); i >= 0 {
+		n, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		days = n
+		s = s[i+1:]
+	}
+
+	var rest time.Duration
+	if s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		rest = d
+	}
+
+	return Duration(time.Duration(days*float64(24*time.Hour)) + rest), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		// Reported as a type error so decoding continues and the line is kept
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: %v", value.Line, err)}}
+	}
+	*d = parsed
+	return nil
+}
+
+// String returns the duration in Go notation
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
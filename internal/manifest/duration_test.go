@@ -0,0 +1,66 @@
+package manifest
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      time.Duration
+		wantError bool
+	}{
+		{input: "36h", want: 36 * time.Hour},
+		{input: "90m", want: 90 * time.Minute},
+		{input: "7d", want: 7 * 24 * time.Hour},
+		{input: "1d12h", want: 36 * time.Hour},
+		{input: "0.5d", want: 12 * time.Hour},
+		{input: "0", want: 0},
+		{input: "", wantError: true},
+		{input: "soon", wantError: true},
+		{input: "-3d", wantError: true},
+		{input: "-1h", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDuration(tt.input)
+
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("ParseDuration(%q) expected error, got nil", tt.input)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseDuration(%q) unexpected error: %v", tt.input, err)
+			}
+
+			if time.Duration(got) != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, time.Duration(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestDuration_UnmarshalYAML(t *testing.T) {
+	var out struct {
+		MinAge Duration `yaml:"min_age"`
+	}
+
+	if err := yaml.Unmarshal([]byte("min_age: 3d"), &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if time.Duration(out.MinAge) != 72*time.Hour {
+		t.Errorf("expected 72h, got %v", out.MinAge)
+	}
+
+	if err := yaml.Unmarshal([]byte("min_age: forever"), &out); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
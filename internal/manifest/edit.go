@@ -0,0 +1,551 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v3"
+)
+
+// Editor edits a YAML manifest in place. Changes are applied to the source
+// text at the positions of the YAML nodes, so comments, ordering, quoting and
+// blank lines outside the edited entries are preserved.
+type Editor struct {
+	path  string
+	lines []string
+	root  yaml.Node
+}
+
+// VersionChange describes a version constraint rewritten by RewriteVersions
+type VersionChange struct {
+	Source string
+	From   string
+	To     string
+}
+
+// OpenEditor reads a YAML manifest file for editing
+func OpenEditor(path string) (*Editor, error) {
+	if f := FormatOf(path); f != FormatYAML {
+		return nil, fmt.Errorf("editing %s manifests is not supported: %s", f, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	e, err := NewEditor(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	e.path = path
+	return e, nil
+}
+
+// NewEditor creates an editor for manifest YAML data
+func NewEditor(data []byte) (*Editor, error) {
+	e := &Editor{lines: strings.Split(string(data), "\n")}
+	if err := e.reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Bytes returns the edited manifest
+func (e *Editor) Bytes() []byte {
+	return []byte(strings.Join(e.lines, "\n"))
+}
+
+// Save writes the edited manifest back to the file it was opened from
+func (e *Editor) Save() error {
+	if e.path == "" {
+		return errors.New("editor has no file")
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(e.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	// Write to a temporary file first, so that a failed write leaves the manifest intact
+	tmp, err := os.CreateTemp(filepath.Dir(e.path), ".manifest-*")
+	if err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(e.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), e.path); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	return nil
+}
+
+// reload parses the current text, refreshing node positions after an edit
+func (e *Editor) reload() error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(e.Bytes(), &root); err != nil {
+		return fmt.Errorf("parsing manifest: %w", err)
+	}
+	e.root = root
+	return nil
+}
+
+// providers returns the providers sequence node and its key, or nil if absent
+func (e *Editor) providers() (key, seq *yaml.Node, err error) {
+	doc := nodeAt(&e.root)
+	if doc.Kind != yaml.MappingNode {
+		if doc.Kind == 0 || doc.Kind == yaml.DocumentNode {
+			return nil, nil, nil
+		}
+		return nil, nil, errors.New("manifest is not a mapping")
+	}
+
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value != "providers" {
+			continue
+		}
+		key, seq = doc.Content[i], doc.Content[i+1]
+		switch {
+		case seq.Kind == yaml.ScalarNode && seq.Tag == "!!null":
+			return key, nil, nil
+		case seq.Kind != yaml.SequenceNode:
+			return nil, nil, errors.New("providers is not a list")
+		case seq.Style&yaml.FlowStyle != 0 && len(seq.Content) > 0:
+			return nil, nil, errors.New("providers written in flow style cannot be edited")
+		}
+		return key, seq, nil
+	}
+	return nil, nil, nil
+}
+
+// findProviders returns the indices of the provider blocks with the given source
+func (e *Editor) findProviders(seq *yaml.Node, source string) []int {
+	if seq == nil {
+		return nil
+	}
+
+	var result []int
+	for i, item := range seq.Content {
+		if n := childNode(item, "source"); n != nil && sameSource(n.Value, source) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// sameSource compares provider sources, ignoring case
+func sameSource(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// AddProvider adds a provider block. If a block with the same source already
+// exists and p sets nothing but versions, missing constraints are added to it.
+func (e *Editor) AddProvider(p Provider) error {
+	if _, err := ParseProviderSource(p.Source); err != nil {
+		return err
+	}
+	if len(p.Versions) == 0 {
+		return fmt.Errorf("provider %s: at least one version constraint is required", p.Source)
+	}
+	for _, c := range p.Versions {
+		if _, err := version.NewConstraint(c); err != nil {
+			return fmt.Errorf("provider %s: invalid version constraint %q", p.Source, c)
+		}
+	}
+	for _, engine := range p.Engines {
+		if !engine.IsValid() {
+			return fmt.Errorf("provider %s: unsupported engine: %s", p.Source, engine)
+		}
+	}
+
+	key, seq, err := e.providers()
+	if err != nil {
+		return err
+	}
+
+	if existing := e.findProviders(seq, p.Source); len(existing) > 0 {
+		if len(p.Platforms) > 0 || len(p.Engines) > 0 {
+			return fmt.Errorf(
+				"provider %s is already in the manifest; remove it first to change its settings", p.Source,
+			)
+		}
+		return e.addVersions(seq.Content[existing[0]], p)
+	}
+
+	return e.appendProvider(key, seq, p)
+}
+
+// appendProvider adds a new block after the last provider block
+func (e *Editor) appendProvider(key, seq *yaml.Node, p Provider) error {
+	// Indentation of "- " and of the keys, taken from the existing blocks
+	dash, separate := 2, true
+	if seq != nil && len(seq.Content) > 0 {
+		dash = seq.Content[0].Column - 3
+		separate = e.blockSeparated(seq)
+	}
+
+	block := providerBlock(p, dash)
+
+	switch {
+	case key == nil:
+		// No providers key yet: add one at the end of the document
+		at := e.lastContentLine() + 1
+		block = append([]string{"", "providers:"}, block...)
+		e.insertLines(at, block)
+
+	case seq == nil || len(seq.Content) == 0:
+		// "providers:" or "providers: []"
+		line := key.Line - 1
+		if idx := strings.Index(e.lines[line], "[]"); idx >= 0 {
+			e.lines[line] = strings.TrimRight(e.lines[line][:idx], " ")
+		}
+		e.insertLines(line+1, block)
+
+	default:
+		_, end := e.blockSpan(seq, len(seq.Content)-1)
+		if separate {
+			block = append([]string{""}, block...)
+		}
+		e.insertLines(end+1, block)
+	}
+
+	return e.reload()
+}
+
+// addVersions appends constraints missing from an existing provider block
+func (e *Editor) addVersions(item *yaml.Node, p Provider) error {
+	versions := childNode(item, "versions")
+	if versions == nil || versions.Kind != yaml.SequenceNode {
+		return fmt.Errorf("provider %s: versions is not a list", p.Source)
+	}
+
+	present := make(map[string]bool)
+	for _, n := range versions.Content {
+		present[n.Value] = true
+	}
+
+	var added []string
+	for _, c := range p.Versions {
+		if !present[c] {
+			added = append(added, c)
+			present[c] = true
+		}
+	}
+	if len(added) == 0 {
+		return fmt.Errorf("provider %s already has versions %s", p.Source, strings.Join(p.Versions, ", "))
+	}
+
+	switch {
+	case versions.Style&yaml.FlowStyle != 0:
+		line := versions.Line - 1
+		closing := strings.LastIndex(e.lines[line], "]")
+		if closing < 0 {
+			return fmt.Errorf("provider %s: versions list spanning several lines cannot be edited", p.Source)
+		}
+		prefix := strings.TrimRight(e.lines[line][:closing], " ")
+		for _, c := range added {
+			if !strings.HasSuffix(prefix, "[") {
+				prefix += ", "
+			}
+			prefix += quoteValue(c, true)
+		}
+		e.lines[line] = prefix + e.lines[line][closing:]
+
+	default:
+		last := versions.Content[len(versions.Content)-1]
+		indent := strings.Repeat(" ", last.Column-3)
+		var lines []string
+		for _, c := range added {
+			lines = append(lines, indent+"- "+quoteValue(c, true))
+		}
+		e.insertLines(last.Line, lines)
+	}
+
+	return e.reload()
+}
+
+// RemoveProvider removes every provider block with the given source and
+// returns the number of blocks removed
+func (e *Editor) RemoveProvider(source string) (int, error) {
+	key, seq, err := e.providers()
+	if err != nil {
+		return 0, err
+	}
+
+	indices := e.findProviders(seq, source)
+	if len(indices) == 0 {
+		return 0, fmt.Errorf("provider %s is not in the manifest", source)
+	}
+
+	// Remove from the last block, so that earlier positions stay valid
+	for k := len(indices) - 1; k >= 0; k-- {
+		i := indices[k]
+		start, end := e.blockSpan(seq, i)
+
+		if i == len(seq.Content)-1 {
+			// Last block: take the blank lines separating it from the previous one
+			for start > 0 && strings.TrimSpace(e.lines[start-1]) == "" {
+				start--
+			}
+		} else {
+			// Take the blank lines separating it from the next block
+			next, _ := e.blockSpan(seq, i+1)
+			end = next - 1
+		}
+
+		e.lines = append(e.lines[:start], e.lines[end+1:]...)
+		if err := e.reload(); err != nil {
+			return 0, err
+		}
+		if _, seq, err = e.providers(); err != nil {
+			return 0, err
+		}
+	}
+
+	if seq == nil || len(seq.Content) == 0 {
+		line := key.Line - 1
+		e.lines[line] = strings.TrimRight(e.lines[line], " ") + " []"
+		if err := e.reload(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(indices), nil
+}
+
+// RewriteVersions replaces version constraints. rewrite returns the new value
+// for a constraint of a provider source; constraints it returns an error for
+// are left unchanged and reported together.
+// If sources is not empty, only blocks with those sources are rewritten.
+func (e *Editor) RewriteVersions(
+	rewrite func(source, constraint string) (string, error),
+	sources ...string,
+) ([]VersionChange, error) {
+	_, seq, err := e.providers()
+	if err != nil {
+		return nil, err
+	}
+	if seq == nil {
+		return nil, nil
+	}
+
+	for _, s := range sources {
+		if len(e.findProviders(seq, s)) == 0 {
+			return nil, fmt.Errorf("provider %s is not in the manifest", s)
+		}
+	}
+
+	var changes []VersionChange
+	var errs []error
+
+	for i := range seq.Content {
+		// Positions change with every edit, so look the block up again
+		_, seq, _ = e.providers()
+		item := seq.Content[i]
+
+		sourceNode := childNode(item, "source")
+		versions := childNode(item, "versions")
+		if sourceNode == nil || versions == nil || versions.Kind != yaml.SequenceNode {
+			continue
+		}
+		source := sourceNode.Value
+		if len(sources) > 0 && !containsSource(sources, source) {
+			continue
+		}
+
+		for j := range versions.Content {
+			_, seq, _ = e.providers()
+			n := childNode(seq.Content[i], "versions").Content[j]
+
+			value, err := rewrite(source, n.Value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("provider %s: %w", source, err))
+				continue
+			}
+			if value == n.Value {
+				continue
+			}
+
+			if err := e.replaceScalar(n, value); err != nil {
+				return changes, err
+			}
+			changes = append(changes, VersionChange{Source: source, From: n.Value, To: value})
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+func containsSource(sources []string, source string) bool {
+	for _, s := range sources {
+		if sameSource(s, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceScalar replaces the text of a single-line scalar, keeping its quoting style
+func (e *Editor) replaceScalar(n *yaml.Node, value string) error {
+	line := []rune(e.lines[n.Line-1])
+	start := n.Column - 1
+	if start < 0 || start >= len(line) {
+		return fmt.Errorf("line %d: value not found", n.Line)
+	}
+
+	var end int // exclusive
+	var replacement string
+	switch n.Style {
+	case yaml.DoubleQuotedStyle:
+		end = closingQuote(line, start, '"')
+		replacement = quoteValue(value, true)
+	case yaml.SingleQuotedStyle:
+		end = closingQuote(line, start, '\'')
+		replacement = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	default:
+		end = start + len([]rune(n.Value))
+		replacement = quoteValue(value, false)
+	}
+	if end < 0 || end > len(line) {
+		return fmt.Errorf("line %d: values spanning several lines cannot be edited", n.Line)
+	}
+
+	e.lines[n.Line-1] = string(line[:start]) + replacement + string(line[end:])
+	return e.reload()
+}
+
+// closingQuote returns the index after the quote closing the string at start, or -1
+func closingQuote(line []rune, start int, quote rune) int {
+	for i := start + 1; i < len(line); i++ {
+		switch {
+		case quote == '"' && line[i] == '\\':
+			i++
+		case quote == '\'' && line[i] == '\'' && i+1 < len(line) && line[i+1] == '\'':
+			i++
+		case line[i] == quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// blockSpan returns the first and last line index (0-based) of a provider
+// block, including the comments directly above it but not trailing blank lines
+func (e *Editor) blockSpan(seq *yaml.Node, i int) (start, end int) {
+	item := seq.Content[i]
+	dash := item.Column - 3
+
+	start = item.Line - 1
+	for start > 0 {
+		prev := e.lines[start-1]
+		if !strings.HasPrefix(strings.TrimSpace(prev), "#") || indentOf(prev) < dash {
+			break
+		}
+		start--
+	}
+
+	if i+1 < len(seq.Content) {
+		next, _ := e.blockSpan(seq, i+1)
+		end = next - 1
+	} else {
+		end = item.Line - 1
+		for end+1 < len(e.lines) {
+			line := e.lines[end+1]
+			if strings.TrimSpace(line) != "" && indentOf(line) <= dash {
+				break
+			}
+			end++
+		}
+	}
+
+	for end > start && strings.TrimSpace(e.lines[end]) == "" {
+		end--
+	}
+	return start, end
+}
+
+// blockSeparated reports whether provider blocks are separated by blank lines
+func (e *Editor) blockSeparated(seq *yaml.Node) bool {
+	if len(seq.Content) < 2 {
+		return true
+	}
+	_, end := e.blockSpan(seq, 0)
+	next, _ := e.blockSpan(seq, 1)
+	return next-end > 1
+}
+
+// lastContentLine returns the index of the last non-blank line
+func (e *Editor) lastContentLine() int {
+	for i := len(e.lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(e.lines[i]) != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e *Editor) insertLines(at int, lines []string) {
+	result := make([]string, 0, len(e.lines)+len(lines))
+	result = append(result, e.lines[:at]...)
+	result = append(result, lines...)
+	result = append(result, e.lines[at:]...)
+	e.lines = result
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// providerBlock renders a provider block with "- " at the given indentation
+func providerBlock(p Provider, dash int) []string {
+	indent := strings.Repeat(" ", dash+2)
+	lines := []string{strings.Repeat(" ", dash) + "- source: " + quoteValue(p.Source, false)}
+
+	list := func(key string, values []string, quote bool) {
+		if len(values) == 0 {
+			return
+		}
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			quoted[i] = quoteValue(v, quote)
+		}
+		lines = append(lines, fmt.Sprintf("%s%s: [%s]", indent, key, strings.Join(quoted, ", ")))
+	}
+
+	list("versions", p.Versions, true)
+
+	engines := make([]string, len(p.Engines))
+	for i, engine := range p.Engines {
+		engines[i] = string(engine)
+	}
+	list("engines", engines, false)
+	list("platforms", p.Platforms, false)
+
+	return lines
+}
+
+var plainValuePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// quoteValue renders a string for YAML, double-quoted if required or asked for
+func quoteValue(s string, always bool) string {
+	if !always && plainValuePattern.MatchString(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
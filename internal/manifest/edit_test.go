@@ -0,0 +1,349 @@
+package manifest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const editManifest = `# Mirror manifest
+defaults:
+  engines:
+    - terraform   # the default
+  platforms: [linux_amd64]
+
+providers:
+  # AWS
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+
+  # Kept for legacy modules
+  - source: hashicorp/null
+    versions:
+      - '~> 3.0'
+      - 2.1.2
+
+  - source: hashicorp/random
+    versions: [3.6.0]
+
+# trailing comment
+`
+
+func newTestEditor(t *testing.T, data string) *Editor {
+	t.Helper()
+	e, err := NewEditor([]byte(data))
+	if err != nil {
+		t.Fatalf("NewEditor() error = %v", err)
+	}
+	return e
+}
+
+// --- AddProvider tests ---
+
+func TestEditor_AddProvider(t *testing.T) {
+	e := newTestEditor(t, editManifest)
+
+	err := e.AddProvider(
+		Provider{
+			Source:    "hashicorp/google",
+			Versions:  []string{"~> 6.0"},
+			Platforms: []string{"linux_amd64", "darwin_arm64"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("AddProvider() error = %v", err)
+	}
+
+	want := strings.Replace(
+		editManifest,
+		"    versions: [3.6.0]\n",
+		"    versions: [3.6.0]\n\n  - source: hashicorp/google\n    versions: [\"~> 6.0\"]\n    platforms: [linux_amd64, darwin_arm64]\n",
+		1,
+	)
+	if got := string(e.Bytes()); got != want {
+		t.Errorf("unexpected manifest:\n%s\nwant:\n%s", got, want)
+	}
+
+	m, err := Parse(e.Bytes())
+	if err != nil {
+		t.Fatalf("edited manifest does not parse: %v", err)
+	}
+	if len(m.Providers) != 4 || m.Providers[3].Source != "hashicorp/google" {
+		t.Errorf("unexpected providers: %+v", m.Providers)
+	}
+}
+
+func TestEditor_AddProvider_Versions(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		versions []string
+		old      string
+		new      string
+	}{
+		{
+			name:     "flow list",
+			source:   "hashicorp/aws",
+			versions: []string{"~> 5.0", "~> 4.0"},
+			old:      `versions: ["~> 5.0"]`,
+			new:      `versions: ["~> 5.0", "~> 4.0"]`,
+		},
+		{
+			name:     "block list",
+			source:   "hashicorp/null",
+			versions: []string{"1.0.0"},
+			old:      "      - 2.1.2\n",
+			new:      "      - 2.1.2\n      - \"1.0.0\"\n",
+		},
+		{
+			name:     "case insensitive source",
+			source:   "HashiCorp/Random",
+			versions: []string{"3.5.0"},
+			old:      "versions: [3.6.0]",
+			new:      `versions: [3.6.0, "3.5.0"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				e := newTestEditor(t, editManifest)
+				if err := e.AddProvider(Provider{Source: tt.source, Versions: tt.versions}); err != nil {
+					t.Fatalf("AddProvider() error = %v", err)
+				}
+
+				want := strings.Replace(editManifest, tt.old, tt.new, 1)
+				if got := string(e.Bytes()); got != want {
+					t.Errorf("unexpected manifest:\n%s\nwant:\n%s", got, want)
+				}
+			},
+		)
+	}
+}
+
+func TestEditor_AddProvider_NoProviders(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "missing key",
+			data: "defaults:\n  engines: [terraform]\n",
+			want: "defaults:\n  engines: [terraform]\n\nproviders:\n  - source: hashicorp/null\n    versions: [\"3.2.4\"]\n",
+		},
+		{
+			name: "empty list",
+			data: "defaults:\n  engines: [terraform]\nproviders: []\n",
+			want: "defaults:\n  engines: [terraform]\nproviders:\n  - source: hashicorp/null\n    versions: [\"3.2.4\"]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				e := newTestEditor(t, tt.data)
+				if err := e.AddProvider(Provider{Source: "hashicorp/null", Versions: []string{"3.2.4"}}); err != nil {
+					t.Fatalf("AddProvider() error = %v", err)
+				}
+				if got := string(e.Bytes()); got != tt.want {
+					t.Errorf("unexpected manifest:\n%q\nwant:\n%q", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestEditor_AddProvider_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       Provider
+		wantErr string
+	}{
+		{"invalid source", Provider{Source: "aws", Versions: []string{"1.0.0"}}, "invalid provider source"},
+		{"no versions", Provider{Source: "hashicorp/dns"}, "at least one version constraint"},
+		{"invalid constraint", Provider{Source: "hashicorp/dns", Versions: []string{"latest"}}, "invalid version constraint"},
+		{"invalid engine", Provider{Source: "hashicorp/dns", Versions: []string{"1.0.0"}, Engines: []Engine{"pulumi"}}, "unsupported engine"},
+		{
+			"existing with settings",
+			Provider{Source: "hashicorp/aws", Versions: []string{"1.0.0"}, Platforms: []string{"linux_arm64"}},
+			"already in the manifest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				e := newTestEditor(t, editManifest)
+				err := e.AddProvider(tt.p)
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("AddProvider() error = %v, want %q", err, tt.wantErr)
+				}
+				if string(e.Bytes()) != editManifest {
+					t.Error("manifest changed despite the error")
+				}
+			},
+		)
+	}
+}
+
+// --- RemoveProvider tests ---
+
+func TestEditor_RemoveProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		want    string
+	}{
+		{
+			name:    "middle block with comment",
+			sources: []string{"hashicorp/null"},
+			want: strings.Replace(
+				editManifest,
+				"  # Kept for legacy modules\n  - source: hashicorp/null\n    versions:\n      - '~> 3.0'\n      - 2.1.2\n\n",
+				"",
+				1,
+			),
+		},
+		{
+			name:    "last block",
+			sources: []string{"hashicorp/random"},
+			want:    strings.Replace(editManifest, "\n  - source: hashicorp/random\n    versions: [3.6.0]\n", "", 1),
+		},
+		{
+			name:    "all blocks",
+			sources: []string{"hashicorp/aws", "hashicorp/null", "hashicorp/random"},
+			want: `# Mirror manifest
+defaults:
+  engines:
+    - terraform   # the default
+  platforms: [linux_amd64]
+
+providers: []
+
+# trailing comment
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				e := newTestEditor(t, editManifest)
+				for _, source := range tt.sources {
+					if n, err := e.RemoveProvider(source); err != nil || n != 1 {
+						t.Fatalf("RemoveProvider(%q) = %d, %v", source, n, err)
+					}
+				}
+				if got := string(e.Bytes()); got != tt.want {
+					t.Errorf("unexpected manifest:\n%s\nwant:\n%s", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestEditor_RemoveProvider_NotFound(t *testing.T) {
+	e := newTestEditor(t, editManifest)
+	if _, err := e.RemoveProvider("hashicorp/dns"); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+// --- RewriteVersions tests ---
+
+func TestEditor_RewriteVersions(t *testing.T) {
+	locked := map[string]string{
+		"hashicorp/aws ~> 5.0":   "5.9.0",
+		"hashicorp/null ~> 3.0":  "3.2.4",
+		"hashicorp/null 2.1.2":   "2.1.2",
+		"hashicorp/random 3.6.0": "3.6.0",
+	}
+	lookup := func(source, constraint string) (string, error) {
+		if v, ok := locked[source+" "+constraint]; ok {
+			return v, nil
+		}
+		return "", errors.New("not locked")
+	}
+
+	e := newTestEditor(t, editManifest)
+	changes, err := e.RewriteVersions(lookup)
+	if err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	wantChanges := []VersionChange{
+		{Source: "hashicorp/aws", From: "~> 5.0", To: "5.9.0"},
+		{Source: "hashicorp/null", From: "~> 3.0", To: "3.2.4"},
+	}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("changes = %+v, want %+v", changes, wantChanges)
+	}
+
+	want := strings.NewReplacer(`["~> 5.0"]`, `["5.9.0"]`, `'~> 3.0'`, `'3.2.4'`).Replace(editManifest)
+	if got := string(e.Bytes()); got != want {
+		t.Errorf("unexpected manifest:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEditor_RewriteVersions_Errors(t *testing.T) {
+	lookup := func(source, constraint string) (string, error) {
+		if source == "hashicorp/aws" {
+			return "5.9.0", nil
+		}
+		return "", errors.New("not locked")
+	}
+
+	e := newTestEditor(t, editManifest)
+	changes, err := e.RewriteVersions(lookup, "hashicorp/aws", "hashicorp/null")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if strings.Count(err.Error(), "provider hashicorp/null: not locked") != 2 {
+		t.Errorf("expected both null constraints reported, got: %v", err)
+	}
+	if len(changes) != 1 || !strings.Contains(string(e.Bytes()), `versions: ["5.9.0"]`) {
+		t.Errorf("expected aws to be pinned despite errors, got %+v", changes)
+	}
+
+	if _, err := e.RewriteVersions(lookup, "hashicorp/dns"); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+// --- OpenEditor tests ---
+
+func TestOpenEditor_Save(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mirror.yaml")
+	if err := os.WriteFile(path, []byte(editManifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := OpenEditor(path)
+	if err != nil {
+		t.Fatalf("OpenEditor() error = %v", err)
+	}
+	if _, err := e.RemoveProvider("hashicorp/random"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "hashicorp/random") {
+		t.Error("saved manifest still contains removed provider")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("expected file mode to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+func TestOpenEditor_UnsupportedFormat(t *testing.T) {
+	if _, err := OpenEditor("mirror.hcl"); err == nil {
+		t.Error("expected error for HCL manifest")
+	}
+}
@@ -0,0 +1,380 @@
+package manifest
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a manifest file format
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json" // decoded by the YAML parser
+	FormatHCL  Format = "hcl"
+)
+
+// FormatOf returns the manifest format for a file name, based on its extension
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".hcl":
+		return FormatHCL
+	default:
+		return FormatYAML
+	}
+}
+
+// hclDocument parses an HCL manifest into a YAML node tree with the same shape
+// as a YAML manifest:
+//
+//	allow_env = ["REGISTRY"]
+//	defaults {
+//	  engines = ["terraform"]
+//	}
+//	provider "hashicorp/aws" {
+//	  versions = ["~> 5.0"]
+//	}
+//
+// Values are evaluated with var.NAME for variables, env.NAME for allowed
+// environment variables, and the try and can functions.
+func hclDocument(data []byte, opts Options, allowEnv []string) (*yaml.Node, ValidationErrors, ValidationErrors, error) {
+	file, diags := hclsyntax.ParseConfig(data, "manifest.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, nil, nil, diagnosticErrors(diags)
+	}
+
+	h := &hclConverter{}
+	content, diags := file.Body.Content(h.schema(reflect.TypeOf(Manifest{}), "defaults", "providers"))
+	h.errs = append(h.errs, diagnosticErrors(diags)...)
+
+	// allow_env is read before any other value, without variables
+	if attr, ok := content.Attributes["allow_env"]; ok {
+		val, diags := attr.Expr.Value(nil)
+		h.errs = append(h.errs, diagnosticErrors(diags)...)
+		if !diags.HasErrors() && val.CanIterateElements() {
+			for it := val.ElementIterator(); it.Next(); {
+				if _, v := it.Element(); v.Type() == cty.String && v.IsKnown() && !v.IsNull() {
+					allowEnv = append(allowEnv, v.AsString())
+				}
+			}
+		}
+	}
+	h.ctx = evalContext(opts, allowEnv)
+
+	doc := mappingNode(file.Body.(*hclsyntax.Body).SrcRange)
+	h.attributes(doc, content.Attributes)
+
+	var providers *yaml.Node
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "defaults":
+			if childNode(doc, "defaults") != nil {
+				h.add(block.DefRange, "duplicate defaults block")
+				continue
+			}
+			defaults := mappingNode(block.DefRange)
+			h.body(defaults, block.Body, reflect.TypeOf(Defaults{}))
+			addMapping(doc, keyNode("defaults", block.TypeRange), defaults)
+
+		case "provider":
+			if providers == nil {
+				providers = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: block.DefRange.Start.Line, Column: block.DefRange.Start.Column}
+				addMapping(doc, keyNode("providers", block.TypeRange), providers)
+			}
+			p := mappingNode(block.DefRange)
+			addMapping(p, keyNode("source", block.LabelRanges[0]), scalarNode(block.Labels[0], block.LabelRanges[0]))
+			h.body(p, block.Body, reflect.TypeOf(Provider{}), "source")
+			providers.Content = append(providers.Content, p)
+		}
+	}
+
+	root := &yaml.Node{Kind: yaml.DocumentNode, Line: 1, Column: 1, Content: []*yaml.Node{doc}}
+	return root, h.errs, h.unresolved, nil
+}
+
+// hclConverter accumulates problems while converting an HCL body
+type hclConverter struct {
+	ctx        *hcl.EvalContext
+	errs       ValidationErrors
+	unresolved ValidationErrors
+}
+
+// schema returns the HCL schema for the yaml fields of t. Fields in blocks
+// are HCL blocks (providers become "provider" blocks labeled with the source);
+// fields in skip are omitted.
+func (h *hclConverter) schema(t reflect.Type, blocks ...string) *hcl.BodySchema {
+	schema := &hcl.BodySchema{}
+	for _, name := range yamlFieldNames(t) {
+		switch {
+		case name == "providers" && contains(blocks, name):
+			schema.Blocks = append(schema.Blocks, hcl.BlockHeaderSchema{Type: "provider", LabelNames: []string{"source"}})
+		case contains(blocks, name):
+			schema.Blocks = append(schema.Blocks, hcl.BlockHeaderSchema{Type: name})
+		default:
+			schema.Attributes = append(schema.Attributes, hcl.AttributeSchema{Name: name})
+		}
+	}
+	return schema
+}
+
+// body converts the attributes of a block body for the yaml fields of t
+func (h *hclConverter) body(node *yaml.Node, body hcl.Body, t reflect.Type, skip ...string) {
+	schema := h.schema(t)
+	attrs := schema.Attributes[:0]
+	for _, a := range schema.Attributes {
+		if !contains(skip, a.Name) {
+			attrs = append(attrs, a)
+		}
+	}
+	schema.Attributes = attrs
+
+	content, diags := body.Content(schema)
+	h.errs = append(h.errs, diagnosticErrors(diags)...)
+	h.attributes(node, content.Attributes)
+}
+
+// attributes evaluates attributes in source order and adds them to a mapping node
+func (h *hclConverter) attributes(node *yaml.Node, attrs hcl.Attributes) {
+	sorted := make([]*hcl.Attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		sorted = append(sorted, attr)
+	}
+	sort.Slice(
+		sorted, func(i, j int) bool {
+			return sorted[i].Range.Start.Byte < sorted[j].Range.Start.Byte
+		},
+	)
+
+	for _, attr := range sorted {
+		if value := h.expression(attr.Expr); value != nil {
+			addMapping(node, keyNode(attr.Name, attr.NameRange), value)
+		}
+	}
+}
+
+// expression evaluates an expression into a node. Elements of a list that
+// fail to evaluate are kept as empty strings marked unresolved, so that the
+// problem is reported once; other expressions that fail are dropped.
+func (h *hclConverter) expression(expr hcl.Expression) *yaml.Node {
+	val, diags := expr.Value(h.ctx)
+	if !diags.HasErrors() {
+		if val.IsNull() {
+			return nil
+		}
+		return valueNode(expr, val)
+	}
+
+	tuple, ok := expr.(*hclsyntax.TupleConsExpr)
+	if !ok {
+		h.unresolved = append(h.unresolved, diagnosticErrors(diags)...)
+		return nil
+	}
+
+	rng := expr.Range()
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: rng.Start.Line, Column: rng.Start.Column}
+	for _, e := range tuple.Exprs {
+		val, diags := e.Value(h.ctx)
+		if !diags.HasErrors() {
+			seq.Content = append(seq.Content, valueNode(e, val))
+			continue
+		}
+
+		// Report at the element, which validation then skips
+		placeholder := scalarNode("", e.Range())
+		for _, ve := range diagnosticErrors(diags) {
+			ve.Line, ve.Column = placeholder.Line, placeholder.Column
+			h.unresolved = append(h.unresolved, ve)
+		}
+		seq.Content = append(seq.Content, placeholder)
+	}
+	return seq
+}
+
+func (h *hclConverter) add(rng hcl.Range, format string, args ...any) {
+	h.errs = append(
+		h.errs, ValidationError{
+			Line:    rng.Start.Line,
+			Column:  rng.Start.Column,
+			Message: fmt.Sprintf(format, args...),
+		},
+	)
+}
+
+// evalContext exposes variables as var.NAME and allowed environment variables as env.NAME
+func evalContext(opts Options, allowEnv []string) *hcl.EvalContext {
+	vars := make(map[string]cty.Value, len(opts.Vars))
+	for k, v := range opts.Vars {
+		vars[k] = cty.StringVal(v)
+	}
+
+	env := make(map[string]cty.Value)
+	lookup := opts.lookupEnv()
+	for _, name := range allowEnv {
+		if v, ok := lookup(name); ok {
+			env[name] = cty.StringVal(v)
+		}
+	}
+
+	return &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(vars),
+			"env": cty.ObjectVal(env),
+		},
+		Functions: map[string]function.Function{
+			"try": tryfunc.TryFunc,
+			"can": tryfunc.CanFunc,
+		},
+	}
+}
+
+// valueNode converts an evaluated value into a node, taking positions from
+// the expression where it is a literal list or object
+func valueNode(expr hcl.Expression, val cty.Value) *yaml.Node {
+	rng := expr.Range()
+	ty := val.Type()
+
+	switch {
+	case val.IsNull():
+		n := scalarNode("", rng)
+		n.Tag = "!!null"
+		return n
+
+	case ty == cty.String:
+		return scalarNode(val.AsString(), rng)
+
+	case ty == cty.Number:
+		bf := val.AsBigFloat()
+		n := scalarNode(bf.Text('f', -1), rng)
+		n.Tag = "!!float"
+		if bf.IsInt() {
+			n.Tag = "!!int"
+		}
+		return n
+
+	case ty == cty.Bool:
+		n := scalarNode(fmt.Sprint(val.True()), rng)
+		n.Tag = "!!bool"
+		return n
+
+	case ty.IsListType() || ty.IsTupleType() || ty.IsSetType():
+		var exprs []hclsyntax.Expression
+		if tuple, ok := expr.(*hclsyntax.TupleConsExpr); ok && len(tuple.Exprs) == val.LengthInt() {
+			exprs = tuple.Exprs
+		}
+
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: rng.Start.Line, Column: rng.Start.Column}
+		i := 0
+		for it := val.ElementIterator(); it.Next(); i++ {
+			_, v := it.Element()
+			var e hcl.Expression = expr
+			if exprs != nil {
+				e = exprs[i]
+			}
+			seq.Content = append(seq.Content, valueNode(e, v))
+		}
+		return seq
+
+	case ty.IsObjectType() || ty.IsMapType():
+		items := make(map[string]hclsyntax.ObjectConsItem)
+		if obj, ok := expr.(*hclsyntax.ObjectConsExpr); ok {
+			for _, item := range obj.Items {
+				if k, diags := item.KeyExpr.Value(nil); !diags.HasErrors() && k.Type() == cty.String {
+					items[k.AsString()] = item
+				}
+			}
+		}
+
+		m := mappingNode(rng)
+		var keys []string
+		for it := val.ElementIterator(); it.Next(); {
+			k, _ := it.Element()
+			keys = append(keys, k.AsString())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			keyRange, valueExpr := rng, expr
+			if item, ok := items[k]; ok {
+				keyRange, valueExpr = item.KeyExpr.Range(), item.ValueExpr
+			}
+			addMapping(m, keyNode(k, keyRange), valueNode(valueExpr, val.GetAttr(k)))
+		}
+		return m
+
+	default:
+		return scalarNode(fmt.Sprint(val.GoString()), rng)
+	}
+}
+
+func scalarNode(value string, rng hcl.Range) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Line: rng.Start.Line, Column: rng.Start.Column}
+}
+
+func keyNode(name string, rng hcl.Range) *yaml.Node {
+	return scalarNode(name, rng)
+}
+
+func mappingNode(rng hcl.Range) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: rng.Start.Line, Column: rng.Start.Column}
+}
+
+func addMapping(m, key, value *yaml.Node) {
+	m.Content = append(m.Content, key, value)
+}
+
+// diagnosticErrors converts HCL error diagnostics into validation errors
+func diagnosticErrors(diags hcl.Diagnostics) ValidationErrors {
+	var errs ValidationErrors
+	for _, d := range diags {
+		if d.Severity != hcl.DiagError {
+			continue
+		}
+		ve := ValidationError{Message: d.Summary}
+		if d.Detail != "" {
+			ve.Message += ": " + d.Detail
+		}
+		if d.Subject != nil {
+			ve.Line = d.Subject.Start.Line
+			ve.Column = d.Subject.Start.Column
+		}
+		errs = append(errs, ve)
+	}
+	return errs
+}
+
+// yamlFieldNames returns the yaml keys of the fields of a struct type
+func yamlFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" || name == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package manifest
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// --- Format tests ---
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want Format
+	}{
+		{"mirror.yaml", FormatYAML},
+		{"mirror.yml", FormatYAML},
+		{"mirror.json", FormatJSON},
+		{"teams/net.HCL", FormatHCL},
+		{"mirror", FormatYAML},
+	}
+
+	for _, tt := range tests {
+		if got := FormatOf(tt.path); got != tt.want {
+			t.Errorf("FormatOf(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseWithOptions_HCL(t *testing.T) {
+	data := `
+allow_env = ["WIDGET_VERSION"]
+
+defaults {
+  engines   = ["terraform", "opentofu"]
+  platforms = [try(var.PLATFORM, "linux_amd64")]
+  platform_sets = {
+    mac = ["darwin_amd64", "darwin_arm64"]
+  }
+  min_age = "3d"
+}
+
+provider "hashicorp/aws" {
+  versions  = ["~> 5.0", var.AWS_VERSION]
+  platforms = ["mac"]
+  exclude   = ["5.1.0"]
+}
+
+provider "registry.example.com/acme/widget" {
+  versions          = [env.WIDGET_VERSION]
+  missing_platforms = "skip"
+}
+`
+	m, err := ParseWithOptions(
+		[]byte(data), Options{
+			Format:    FormatHCL,
+			Vars:      map[string]string{"AWS_VERSION": "~> 4.0"},
+			LookupEnv: fakeEnv(map[string]string{"WIDGET_VERSION": "1.0.0"}),
+		},
+	)
+	if err != nil {
+		t.Fatalf("ParseWithOptions() error = %v", err)
+	}
+
+	if len(m.Defaults.Engines) != 2 || m.Defaults.MinAge == nil || m.Defaults.MinAge.String() != "72h0m0s" {
+		t.Errorf("unexpected defaults: %+v", m.Defaults)
+	}
+	if len(m.Providers) != 2 {
+		t.Fatalf("expected 2 providers, got %d", len(m.Providers))
+	}
+
+	aws := m.Providers[0]
+	if aws.Source != "hashicorp/aws" || !reflect.DeepEqual(aws.Versions, []string{"~> 5.0", "~> 4.0"}) {
+		t.Errorf("unexpected aws provider: %+v", aws)
+	}
+	if !reflect.DeepEqual(aws.Platforms, []string{"darwin_amd64", "darwin_arm64"}) {
+		t.Errorf("expected platform set expansion, got %v", aws.Platforms)
+	}
+
+	widget := m.Providers[1]
+	if widget.Versions[0] != "1.0.0" || widget.MissingPlatforms != PlatformPolicySkip {
+		t.Errorf("unexpected widget provider: %+v", widget)
+	}
+	if !reflect.DeepEqual(widget.Platforms, []string{"linux_amd64"}) {
+		t.Errorf("expected default platform from try(), got %v", widget.Platforms)
+	}
+}
+
+func TestParseWithOptions_HCLProblems(t *testing.T) {
+	data := `defaults {
+  engines = ["terraform"]
+}
+
+provider "hashicorp/aws" {
+  versions = ["~> 5.0", var.MISSING]
+  platfrom = ["linux_amd64"]
+}
+
+provider "hashicorp/null" {
+  versions = ["not a constraint"]
+}
+`
+	_, err := ParseWithOptions([]byte(data), Options{Format: FormatHCL})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		`line 7, column 3: Unsupported argument`,
+		`line 6, column 25: Unsupported attribute`,
+		`line 11, column 15: provider hashicorp/null: invalid version constraint "not a constraint"`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in error, got:\n%s", want, msg)
+		}
+	}
+	if strings.Count(msg, "\n") != 3 {
+		t.Errorf("expected exactly 3 problems, got:\n%s", msg)
+	}
+}
+
+func TestParseWithOptions_HCLSyntaxError(t *testing.T) {
+	_, err := ParseWithOptions([]byte("provider \"hashicorp/aws\" {\n  versions = [\n"), Options{Format: FormatHCL})
+	if err == nil {
+		t.Fatal("expected syntax error, got nil")
+	}
+}
+
+func TestLoad_MixedFormats(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir, map[string]string{
+			"mirror.json": `{
+  "defaults": {"engines": ["terraform"], "platforms": ["linux_amd64"]},
+  "include": ["teams/*.hcl"],
+  "providers": [{"source": "hashicorp/null", "versions": ["3.2.4"]}]
+}`,
+			"teams/net.hcl": `
+provider "hashicorp/dns" {
+  versions = ["~> 3.0"]
+}
+`,
+		},
+	)
+
+	m, err := Load(filepath.Join(dir, "mirror.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(m.Providers) != 2 {
+		t.Fatalf("expected 2 providers, got %d", len(m.Providers))
+	}
+	if m.Providers[1].Source != "hashicorp/dns" || m.Providers[1].Origin != "teams/net.hcl" {
+		t.Errorf("unexpected included provider: %+v", m.Providers[1])
+	}
+	if m.Providers[1].Engines[0] != EngineTerraform {
+		t.Errorf("expected engines inherited from JSON manifest, got %v", m.Providers[1].Engines)
+	}
+}
+
+func TestLoad_JSONUnknownKey(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir, map[string]string{
+			"mirror.json": `{
+  "defaults": {"engines": ["terraform"]},
+  "providers": [{"source": "hashicorp/null", "versions": ["3.2.4"], "platfrom": ["x"]}]
+}`,
+		},
+	)
+
+	_, err := Load(filepath.Join(dir, "mirror.json"))
+	if err == nil || !strings.Contains(err.Error(), `mirror.json:3:69: unknown field "platfrom"`) {
+		t.Errorf("expected unknown field with position, got: %v", err)
+	}
+}
+
+// --- Schema tests ---
+
+func TestJSONSchema_MatchesManifestFields(t *testing.T) {
+	var s struct {
+		Properties map[string]any `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(JSONSchema(), &s); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		properties map[string]any
+		typ        reflect.Type
+	}{
+		{"manifest", s.Properties, reflect.TypeOf(Manifest{})},
+		{"defaults", s.Defs["defaults"].Properties, reflect.TypeOf(Defaults{})},
+		{"provider", s.Defs["provider"].Properties, reflect.TypeOf(Provider{})},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for name := range tt.properties {
+			got = append(got, name)
+		}
+		want := yamlFieldNames(tt.typ)
+		sort.Strings(got)
+		sort.Strings(want)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: schema properties %v do not match manifest fields %v", tt.name, got, want)
+		}
+	}
+}
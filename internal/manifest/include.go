@@ -0,0 +1,228 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Load reads and parses one or more manifest files.
+// Files referenced by include are loaded recursively, relative to the file
+// that includes them, and their defaults are layered over those of the
+// including file. Providers from all files are merged, and blocks that repeat
+// a source and constraint with different settings are reported as conflicts.
+func Load(paths ...string) (*Manifest, error) {
+	return LoadWithOptions(Options{}, paths...)
+}
+
+// LoadWithOptions is like Load, expanding ${VAR} references as ParseWithOptions does.
+// Included files may also read the environment variables allowed by the files including them.
+func LoadWithOptions(opts Options, paths ...string) (*Manifest, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no manifest files given")
+	}
+
+	l := &loader{
+		opts:   opts,
+		root:   filepath.Dir(paths[0]),
+		loaded: make(map[string]bool),
+		active: make(map[string]bool),
+	}
+
+	for _, path := range paths {
+		if _, err := l.load(path, Defaults{}, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	m := l.result
+	if len(m.Providers) == 0 && len(l.errs) == 0 {
+		// Every file was valid on its own, but the includes matched no providers
+		l.errs = append(
+			l.errs, ValidationError{File: paths[0], Message: "manifest must specify at least one provider"},
+		)
+	}
+	l.errs = append(l.errs, checkConflicts(m.Providers)...)
+
+	if len(l.errs) > 0 {
+		return nil, l.errs
+	}
+
+	// Record provenance only when the manifest spans several files
+	if len(l.loaded) > 1 {
+		for i := range m.Providers {
+			m.Providers[i].Origin = l.origin(m.Providers[i].file)
+		}
+	}
+
+	return m, nil
+}
+
+// loader accumulates the providers and problems of a set of manifest files
+type loader struct {
+	opts   Options
+	root   string          // directory origins are relative to
+	loaded map[string]bool // absolute paths of files already loaded
+	active map[string]bool // absolute paths of files on the current include chain
+	result *Manifest
+	errs   ValidationErrors
+}
+
+// load reads a single manifest file and everything it includes. A file
+// reached again, such as a fragment shared by two teams, is skipped. It
+// returns false if the file includes itself through the include chain.
+func (l *loader) load(path string, parent Defaults, allowEnv []string) (bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false, fmt.Errorf("resolving manifest path: %w", err)
+	}
+	if l.active[abs] {
+		return false, nil
+	}
+	if l.loaded[abs] {
+		return true, nil
+	}
+	l.loaded[abs] = true
+	l.active[abs] = true
+	defer delete(l.active, abs)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	opts := l.opts
+	opts.Format = FormatOf(path)
+
+	m, errs, err := decode(data, opts, allowEnv)
+	if err != nil {
+		var verrs ValidationErrors
+		if errors.As(err, &verrs) {
+			return false, verrs.withFile(path)
+		}
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+
+	errs = append(errs, m.validate(parent)...)
+	l.errs = append(l.errs, errs.withFile(path)...)
+
+	for i := range m.Providers {
+		m.Providers[i].file = path
+	}
+
+	defaults := parent.layer(m.Defaults)
+	allowEnv = append(append([]string(nil), allowEnv...), m.AllowEnv...)
+
+	if l.result == nil {
+		// The first file provides the top-level settings of the result
+		root := *m
+		root.Providers = nil
+		root.Auth = nil
+		l.result = &root
+	}
+	l.result.Providers = append(l.result.Providers, defaults.apply(m.Providers)...)
+	l.mergeAuth(m, path)
+	l.result.Files = append(l.result.Files, path)
+
+	dir := filepath.Dir(path)
+	if err := m.loadAdvisories(dir); err != nil {
+		return false, err
+	}
+	l.result.Advisories = append(l.result.Advisories, m.Advisories...)
+
+	for i, pattern := range m.Includes {
+		matches, err := resolveInclude(dir, pattern)
+		if err != nil {
+			l.errs = append(l.errs, l.includeError(m, path, i, err.Error()))
+			continue
+		}
+		for _, match := range matches {
+			ok, err := l.load(match, defaults, allowEnv)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				l.errs = append(
+					l.errs, l.includeError(m, path, i, fmt.Sprintf("include cycle through %s", match)),
+				)
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// mergeAuth adds the host credentials of an included manifest to the result.
+// A host configured differently by two files is reported.
+func (l *loader) mergeAuth(m *Manifest, path string) {
+	if l.result.Auth == nil {
+		l.result.Auth = make(map[string]HostAuth)
+	}
+	for _, host := range authHosts(m.Auth) {
+		auth := m.Auth[host]
+		prev, ok := l.result.Auth[strings.ToLower(host)]
+		if ok && prev != auth {
+			n := nodeAt(m.node, "auth", host)
+			l.errs = append(
+				l.errs, ValidationError{
+					File:    path,
+					Line:    n.Line,
+					Column:  n.Column,
+					Message: fmt.Sprintf("auth for %s is configured differently by another manifest", host),
+				},
+			)
+			continue
+		}
+		l.result.Auth[strings.ToLower(host)] = auth
+	}
+}
+
+// includeError reports a problem with the include entry at index i
+func (l *loader) includeError(m *Manifest, path string, i int, msg string) ValidationError {
+	n := nodeAt(m.node, "include", i)
+	return ValidationError{
+		File:    path,
+		Line:    n.Line,
+		Column:  n.Column,
+		Message: fmt.Sprintf("include %q: %s", m.Includes[i], msg),
+	}
+}
+
+// origin returns the path of a manifest file relative to the first manifest
+func (l *loader) origin(path string) string {
+	rel, err := filepath.Rel(l.root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// resolveInclude expands an include entry relative to dir.
+// Literal paths must exist; globs may match nothing.
+func resolveInclude(dir, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, errors.New("file not found")
+		}
+		return []string{pattern}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	var files []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && !info.IsDir() {
+			files = append(files, match)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,320 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files under dir from a map of relative path to content
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// --- Include tests ---
+
+func TestLoad_IncludeLayersDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir, map[string]string{
+			"mirror.yaml": `
+defaults:
+  engines: [terraform]
+  platforms: [linux_amd64]
+include:
+  - teams/*.yaml
+providers:
+  - source: hashicorp/null
+    versions: ["3.2.4"]
+`,
+			"teams/net.yaml": `
+defaults:
+  platforms: [linux_amd64, darwin_arm64]
+providers:
+  - source: hashicorp/dns
+    versions: ["~> 3.0"]
+`,
+			"teams/data.yaml": `
+providers:
+  - source: hashicorp/random
+    versions: ["~> 3.6"]
+`,
+		},
+	)
+
+	m, err := Load(filepath.Join(dir, "mirror.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := []struct {
+		source    string
+		origin    string
+		platforms int
+	}{
+		{"hashicorp/null", "mirror.yaml", 1},
+		{"hashicorp/random", "teams/data.yaml", 1},
+		{"hashicorp/dns", "teams/net.yaml", 2},
+	}
+
+	if len(m.Providers) != len(want) {
+		t.Fatalf("expected %d providers, got %d", len(want), len(m.Providers))
+	}
+	for i, w := range want {
+		p := m.Providers[i]
+		if p.Source != w.source || p.Origin != w.origin {
+			t.Errorf("provider %d: got %s from %s, want %s from %s", i, p.Source, p.Origin, w.source, w.origin)
+		}
+		if len(p.Platforms) != w.platforms {
+			t.Errorf("provider %s: expected %d platforms, got %v", p.Source, w.platforms, p.Platforms)
+		}
+		if len(p.Engines) != 1 || p.Engines[0] != EngineTerraform {
+			t.Errorf("provider %s: expected engines inherited from root, got %v", p.Source, p.Engines)
+		}
+	}
+
+	expanded, err := m.GetExpandedProviders()
+	if err != nil {
+		t.Fatalf("GetExpandedProviders() error = %v", err)
+	}
+	if expanded[2].Origin != "teams/net.yaml" {
+		t.Errorf("expected origin on expanded provider, got %q", expanded[2].Origin)
+	}
+}
+
+func TestLoad_SingleFileHasNoOrigin(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir, map[string]string{
+			"mirror.yaml": `
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/null
+    versions: ["3.2.4"]
+`,
+		},
+	)
+
+	m, err := Load(filepath.Join(dir, "mirror.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if m.Providers[0].Origin != "" {
+		t.Errorf("expected no origin, got %q", m.Providers[0].Origin)
+	}
+}
+
+func TestLoad_MultipleManifests(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir, map[string]string{
+			"a.yaml": `
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/null
+    versions: ["3.2.4"]
+`,
+			"b.yaml": `
+defaults:
+  engines: [opentofu]
+providers:
+  - source: hashicorp/random
+    versions: ["3.6.0"]
+`,
+		},
+	)
+
+	m, err := Load(filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(m.Providers) != 2 {
+		t.Fatalf("expected 2 providers, got %d", len(m.Providers))
+	}
+	if m.Providers[1].Engines[0] != EngineOpenTofu || m.Providers[1].Origin != "b.yaml" {
+		t.Errorf("unexpected second provider: %+v", m.Providers[1])
+	}
+
+	wantFiles := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}
+	if !reflect.DeepEqual(m.Files, wantFiles) {
+		t.Errorf("Files = %v, want %v", m.Files, wantFiles)
+	}
+}
+
+func TestLoad_IncludeSharedFragment(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir, map[string]string{
+			// The glob also matches net.yaml, which is included explicitly first
+			"mirror.yaml": `
+defaults:
+  engines: [terraform]
+include: [teams/net.yaml, teams/*.yaml]
+`,
+			"teams/net.yaml": `
+include: [../shared/common.yaml]
+providers:
+  - source: hashicorp/dns
+    versions: ["3.4.0"]
+`,
+			"teams/app.yaml": `
+include: [../shared/common.yaml]
+providers:
+  - source: hashicorp/random
+    versions: ["3.6.0"]
+`,
+			"shared/common.yaml": `
+providers:
+  - source: hashicorp/null
+    versions: ["3.2.4"]
+`,
+		},
+	)
+
+	m, err := Load(filepath.Join(dir, "mirror.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var sources []string
+	for _, p := range m.Providers {
+		sources = append(sources, p.Source)
+	}
+	want := []string{"hashicorp/dns", "hashicorp/null", "hashicorp/random"}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("expected each file once, got providers %v", sources)
+	}
+}
+
+func TestLoad_IncludeConflictAcrossFragments(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir, map[string]string{
+			"mirror.yaml": `
+defaults:
+  engines: [terraform]
+  platforms: [linux_amd64]
+include: [team.yaml]
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+`,
+			"team.yaml": `
+defaults:
+  platforms: [darwin_arm64]
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+`,
+		},
+	)
+
+	_, err := Load(filepath.Join(dir, "mirror.yaml"))
+	if err == nil {
+		t.Fatal("expected conflict error, got nil")
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, "team.yaml:5:5") || !strings.Contains(msg, "mirror.yaml:7") {
+		t.Errorf("expected positions of both blocks, got: %s", msg)
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "missing file",
+			files: map[string]string{
+				"mirror.yaml": "defaults:\n  engines: [terraform]\ninclude: [nope.yaml]\n",
+			},
+			want: `mirror.yaml:3:11: include "nope.yaml": file not found`,
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"mirror.yaml": "defaults:\n  engines: [terraform]\ninclude: [a.yaml]\n",
+				"a.yaml":      "include: [mirror.yaml]\nproviders:\n  - source: hashicorp/null\n    versions: [\"1.0.0\"]\n",
+			},
+			want: "include cycle through",
+		},
+		{
+			name: "glob matches nothing",
+			files: map[string]string{
+				"mirror.yaml": "defaults:\n  engines: [terraform]\ninclude: [teams/*.yaml]\n",
+			},
+			want: "manifest must specify at least one provider",
+		},
+		{
+			name: "fragment problems are reported with its file",
+			files: map[string]string{
+				"mirror.yaml": "defaults:\n  engines: [terraform]\ninclude: [a.yaml]\n",
+				"a.yaml":      "providers:\n  - source: hashicorp/null\n    versions: [\"1.0.0\"]\n    platform: [linux_amd64]\n",
+			},
+			want: `a.yaml:4:5: unknown field "platform"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				dir := t.TempDir()
+				writeFiles(t, dir, tt.files)
+
+				_, err := Load(filepath.Join(dir, "mirror.yaml"))
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("expected error containing %q, got: %v", tt.want, err)
+				}
+			},
+		)
+	}
+}
+
+func TestLoad_IncludeInheritsPlatformSets(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir, map[string]string{
+			"mirror.yaml": `
+defaults:
+  engines: [terraform]
+  platform_sets:
+    ci: [linux_amd64, linux_arm64]
+include: [team.yaml]
+`,
+			"team.yaml": `
+providers:
+  - source: hashicorp/null
+    versions: ["3.2.4"]
+    platforms: [ci]
+`,
+		},
+	)
+
+	m, err := Load(filepath.Join(dir, "mirror.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := m.Providers[0].Platforms; len(got) != 2 || got[0] != "linux_amd64" {
+		t.Errorf("expected platform set from including manifest, got %v", got)
+	}
+}
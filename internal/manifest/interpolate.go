@@ -0,0 +1,207 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Options controls how manifests are parsed and loaded
+type Options struct {
+	// Format of the data given to ParseWithOptions; Load detects it from the file extension
+	Format Format
+
+	// Vars are available to ${VAR} references and take precedence over the environment
+	Vars map[string]string
+
+	// LookupEnv reads environment variables listed in allow_env; os.LookupEnv if nil
+	LookupEnv func(string) (string, bool)
+}
+
+// lookupEnv returns the environment lookup function to use
+func (o Options) lookupEnv() func(string) (string, bool) {
+	if o.LookupEnv != nil {
+		return o.LookupEnv
+	}
+	return os.LookupEnv
+}
+
+var varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidVarName reports whether name can be referenced as ${name}
+func ValidVarName(name string) bool {
+	return varNamePattern.MatchString(name)
+}
+
+// LoadVars reads a variable file: a YAML or JSON mapping of names to values
+func LoadVars(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading variable file: %w", err)
+	}
+
+	var vars map[string]string
+	if err := yaml.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("parsing variable file %s: %w", path, err)
+	}
+
+	for name := range vars {
+		if !varNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("variable file %s: invalid variable name %q", path, name)
+		}
+	}
+
+	return vars, nil
+}
+
+// interpolator expands ${VAR} and ${VAR:-default} references in YAML values
+type interpolator struct {
+	vars      map[string]string
+	allowEnv  map[string]bool
+	lookupEnv func(string) (string, bool)
+	errs      ValidationErrors
+}
+
+func newInterpolator(opts Options, allowEnv []string) *interpolator {
+	in := &interpolator{
+		vars:      opts.Vars,
+		allowEnv:  make(map[string]bool, len(allowEnv)),
+		lookupEnv: opts.lookupEnv(),
+	}
+	for _, name := range allowEnv {
+		in.allowEnv[name] = true
+	}
+	return in
+}
+
+// allowedEnv returns the allow_env entries of a manifest document.
+// They are read before interpolation, so they cannot contain references.
+func allowedEnv(root *yaml.Node) []string {
+	doc := nodeAt(root)
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	list := childNode(doc, "allow_env")
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	var names []string
+	for _, n := range list.Content {
+		if n.Kind == yaml.ScalarNode {
+			names = append(names, n.Value)
+		}
+	}
+	return names
+}
+
+// document expands references in every value of a manifest document except allow_env
+func (in *interpolator) document(root *yaml.Node) {
+	doc := nodeAt(root)
+	if doc.Kind != yaml.MappingNode {
+		in.walk(doc)
+		return
+	}
+
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value == "allow_env" {
+			continue
+		}
+		in.walk(doc.Content[i+1])
+	}
+}
+
+// walk expands references in all scalar values below n; mapping keys are left as is
+func (in *interpolator) walk(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if strings.Contains(n.Value, "$") {
+			n.Value = in.expand(n, n.Value)
+			// Let yaml resolve the substituted plain scalar again, so that
+			// "align: ${ALIGN}" decodes as a bool; explicit tags are kept
+			if n.Style&yaml.TaggedStyle == 0 {
+				n.Tag = ""
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			in.walk(n.Content[i+1])
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, c := range n.Content {
+			in.walk(c)
+		}
+	}
+}
+
+// expand replaces the references in s. "$${" produces a literal "${".
+// Problems are recorded at the position of n.
+func (in *interpolator) expand(n *yaml.Node, s string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+
+		b.WriteString(s[:i])
+		end := strings.Index(s[i:], "}")
+		if end < 0 {
+			in.add(n, "unterminated variable reference in %q", n.Value)
+			return b.String() + s[i:]
+		}
+
+		b.WriteString(in.resolve(n, s[i+2:i+end]))
+		s = s[i+end+1:]
+	}
+}
+
+// resolve returns the value of a reference body such as "VAR" or "VAR:-default"
+func (in *interpolator) resolve(n *yaml.Node, ref string) string {
+	name, def, hasDefault := strings.Cut(ref, ":-")
+	if !varNamePattern.MatchString(name) {
+		in.add(n, "invalid variable reference ${%s}", ref)
+		return ""
+	}
+
+	if v, ok := in.vars[name]; ok {
+		return v
+	}
+	if in.allowEnv[name] {
+		if v, ok := in.lookupEnv(name); ok {
+			return v
+		}
+	}
+	if hasDefault {
+		return def
+	}
+
+	if _, set := in.lookupEnv(name); set && !in.allowEnv[name] {
+		in.add(n, "unresolved variable %q (environment variable is not listed in allow_env)", name)
+	} else {
+		in.add(n, "unresolved variable %q", name)
+	}
+	return ""
+}
+
+func (in *interpolator) add(n *yaml.Node, format string, args ...any) {
+	in.errs = append(
+		in.errs, ValidationError{
+			Line:    n.Line,
+			Column:  n.Column,
+			Message: fmt.Sprintf(format, args...),
+		},
+	)
+}
@@ -0,0 +1,218 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeEnv returns a LookupEnv function backed by a map
+func fakeEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+// --- Interpolation tests ---
+
+func TestParseWithOptions_Interpolation(t *testing.T) {
+	data := `
+allow_env: [REGISTRY]
+defaults:
+  engines: [terraform]
+  platforms: ["${PLATFORM:-linux_amd64}"]
+  min_age: ${MIN_AGE:-3d}
+providers:
+  - source: ${REGISTRY}/acme/widget
+    versions: ["${WIDGET_VERSION}"]
+  - source: hashicorp/null
+    versions: ["~> 3.0"]
+    exclude: ["$${NOT_A_VAR}"]
+`
+	opts := Options{
+		Vars:      map[string]string{"WIDGET_VERSION": "1.2.3"},
+		LookupEnv: fakeEnv(map[string]string{"REGISTRY": "registry.example.com"}),
+	}
+
+	// The literal exclude entry is not a valid constraint; check it survives expansion
+	_, err := ParseWithOptions([]byte(data), opts)
+	if err == nil || !strings.Contains(err.Error(), `invalid exclude constraint "${NOT_A_VAR}"`) {
+		t.Fatalf("expected escaped reference to be kept literally, got: %v", err)
+	}
+
+	data = strings.Replace(data, `exclude: ["$${NOT_A_VAR}"]`, `exclude: ["3.0.0"]`, 1)
+	m, err := ParseWithOptions([]byte(data), opts)
+	if err != nil {
+		t.Fatalf("ParseWithOptions() error = %v", err)
+	}
+
+	p := m.Providers[0]
+	if p.Source != "registry.example.com/acme/widget" {
+		t.Errorf("unexpected source %q", p.Source)
+	}
+	if p.Versions[0] != "1.2.3" {
+		t.Errorf("unexpected version %q", p.Versions[0])
+	}
+	if len(p.Platforms) != 1 || p.Platforms[0] != "linux_amd64" {
+		t.Errorf("expected default platform, got %v", p.Platforms)
+	}
+	if p.MinAge == nil || p.MinAge.String() != "72h0m0s" {
+		t.Errorf("expected default min_age 72h, got %v", p.MinAge)
+	}
+}
+
+func TestParseWithOptions_VarsOverrideEnvironment(t *testing.T) {
+	data := `
+allow_env: [VERSION]
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/null
+    versions: ["${VERSION}"]
+`
+	m, err := ParseWithOptions(
+		[]byte(data), Options{
+			Vars:      map[string]string{"VERSION": "3.2.4"},
+			LookupEnv: fakeEnv(map[string]string{"VERSION": "1.0.0"}),
+		},
+	)
+	if err != nil {
+		t.Fatalf("ParseWithOptions() error = %v", err)
+	}
+	if m.Providers[0].Versions[0] != "3.2.4" {
+		t.Errorf("expected --var value to win, got %q", m.Providers[0].Versions[0])
+	}
+}
+
+func TestParseWithOptions_InterpolatedScalarTypes(t *testing.T) {
+	data := `
+defaults:
+  engines: [terraform, opentofu]
+  align: ${ALIGN}
+providers:
+  - source: hashicorp/null
+    versions: ["${VERSION}"]
+    align: ${NULL_ALIGN}
+`
+	m, err := ParseWithOptions(
+		[]byte(data), Options{
+			Vars: map[string]string{"ALIGN": "true", "NULL_ALIGN": "false", "VERSION": "3"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("ParseWithOptions() error = %v", err)
+	}
+
+	if m.Defaults.Align == nil || !*m.Defaults.Align {
+		t.Errorf("expected defaults align true, got %v", m.Defaults.Align)
+	}
+	p := m.Providers[0]
+	if p.Align == nil || *p.Align {
+		t.Errorf("expected provider align false, got %v", p.Align)
+	}
+	if p.Versions[0] != "3" {
+		t.Errorf("unexpected version %q", p.Versions[0])
+	}
+}
+
+func TestParseWithOptions_UnresolvedVariables(t *testing.T) {
+	data := `
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/null
+    versions: ["${VERSION}"]
+    platforms: ["${PLATFORM"]
+  - source: hashicorp/random
+    versions: ["${HOME_VERSION}"]
+`
+	_, err := ParseWithOptions(
+		[]byte(data), Options{LookupEnv: fakeEnv(map[string]string{"HOME_VERSION": "1.0.0"})},
+	)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		`line 6, column 16: unresolved variable "VERSION"`,
+		`line 7, column 17: unterminated variable reference`,
+		`unresolved variable "HOME_VERSION" (environment variable is not listed in allow_env)`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in error, got:\n%s", want, msg)
+		}
+	}
+}
+
+func TestParse_InvalidAllowEnvName(t *testing.T) {
+	_, err := Parse(
+		[]byte(`
+allow_env: ["not-a-name"]
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/null
+    versions: ["3.2.4"]
+`),
+	)
+	if err == nil || !strings.Contains(err.Error(), "invalid environment variable name") {
+		t.Errorf("expected invalid name error, got: %v", err)
+	}
+}
+
+func TestLoadWithOptions_IncludeInheritsAllowEnv(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir, map[string]string{
+			"mirror.yaml": `
+allow_env: [NULL_VERSION]
+defaults:
+  engines: [terraform]
+include: [team.yaml]
+`,
+			"team.yaml": `
+providers:
+  - source: hashicorp/null
+    versions: ["${NULL_VERSION}"]
+`,
+		},
+	)
+
+	m, err := LoadWithOptions(
+		Options{LookupEnv: fakeEnv(map[string]string{"NULL_VERSION": "3.2.4"})},
+		filepath.Join(dir, "mirror.yaml"),
+	)
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error = %v", err)
+	}
+	if m.Providers[0].Versions[0] != "3.2.4" {
+		t.Errorf("unexpected version %q", m.Providers[0].Versions[0])
+	}
+}
+
+func TestLoadVars(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prod.yaml")
+
+	if err := os.WriteFile(path, []byte("REGISTRY: registry.example.com\nAWS_VERSION: \"5.1.0\"\n"), 0644); err != nil {
+		t.Fatalf("failed to write var file: %v", err)
+	}
+
+	vars, err := LoadVars(path)
+	if err != nil {
+		t.Fatalf("LoadVars() error = %v", err)
+	}
+	if vars["REGISTRY"] != "registry.example.com" || vars["AWS_VERSION"] != "5.1.0" {
+		t.Errorf("unexpected vars: %v", vars)
+	}
+
+	if err := os.WriteFile(path, []byte("bad-name: x\n"), 0644); err != nil {
+		t.Fatalf("failed to write var file: %v", err)
+	}
+	if _, err := LoadVars(path); err == nil {
+		t.Error("expected error for invalid variable name")
+	}
+}
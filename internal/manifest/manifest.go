@@ -1,9 +1,15 @@
 package manifest
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -38,24 +44,97 @@ func (e Engine) DefaultRegistry() string {
 	}
 }
 
+// PlatformPolicy controls what happens when a selected version lacks a requested platform
+type PlatformPolicy string
+
+const (
+	PlatformPolicyFail     PlatformPolicy = "fail"     // abort resolution (default)
+	PlatformPolicySkip     PlatformPolicy = "skip"     // mirror the platforms that exist
+	PlatformPolicyFallback PlatformPolicy = "fallback" // pick an older version that has all platforms
+)
+
+// IsValid returns true if the policy is a supported value
+func (p PlatformPolicy) IsValid() bool {
+	switch p {
+	case PlatformPolicyFail, PlatformPolicySkip, PlatformPolicyFallback:
+		return true
+	default:
+		return false
+	}
+}
+
+// ProtocolMajor returns the major version of a plugin protocol such as "5" or "6.0"
+func ProtocolMajor(protocol string) (int, error) {
+	major, minor, hasMinor := strings.Cut(strings.TrimSpace(protocol), ".")
+	n, err := strconv.Atoi(major)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid plugin protocol %q", protocol)
+	}
+	if hasMinor {
+		if _, err := strconv.Atoi(minor); err != nil {
+			return 0, fmt.Errorf("invalid plugin protocol %q", protocol)
+		}
+	}
+	return n, nil
+}
+
 // Manifest represents the complete mirror manifest
 type Manifest struct {
-	Defaults  Defaults   `yaml:"defaults"`
-	Providers []Provider `yaml:"providers"`
+	Defaults      Defaults   `yaml:"defaults"`
+	Includes      []string   `yaml:"include,omitempty"`    // fragment files or globs, relative to the manifest
+	AllowEnv      []string   `yaml:"allow_env,omitempty"`  // environment variables ${VAR} references may read
+	AdvisoryFiles []string   `yaml:"advisories,omitempty"` // advisory files, relative to the manifest
+	Providers     []Provider `yaml:"providers"`
+
+	// Auth configures credentials for archive download hosts, by hostname
+	Auth map[string]HostAuth `yaml:"auth,omitempty"`
+
+	Advisories []Advisory `yaml:"-"` // loaded from AdvisoryFiles by Load
+	Files      []string   `yaml:"-"` // manifest files read by Load, in load order
+
+	node       *yaml.Node      // document the manifest was parsed from, for error positions
+	unresolved map[[2]int]bool // line and column of values with unresolved variables
 }
 
 // Defaults contains default settings applied to all providers
 type Defaults struct {
 	Engines   []Engine `yaml:"engines"`
 	Platforms []string `yaml:"platforms"`
+	// PlatformSets defines named platform lists that platform entries can reference
+	PlatformSets map[string][]string `yaml:"platform_sets,omitempty"`
+	MinAge       *Duration           `yaml:"min_age,omitempty"` // minimum release age before a version is mirrored
+
+	MissingPlatforms PlatformPolicy `yaml:"missing_platforms,omitempty"`
+	Align            *bool          `yaml:"align,omitempty"` // select the same version on every engine's registry
+
+	// Protocols lists the plugin protocol versions the consuming CLIs speak
+	Protocols []string `yaml:"protocols,omitempty"`
 }
 
 // Provider represents a single provider entry in the manifest
 type Provider struct {
-	Source    string   `yaml:"source"`
-	Versions  []string `yaml:"versions"`
-	Engines   []Engine `yaml:"engines,omitempty"`   // overrides defaults
-	Platforms []string `yaml:"platforms,omitempty"` // overrides defaults
+	Source    string    `yaml:"source"`
+	Versions  []string  `yaml:"versions"`
+	Engines   []Engine  `yaml:"engines,omitempty"`   // overrides defaults
+	Platforms []string  `yaml:"platforms,omitempty"` // overrides defaults
+	Exclude   []string  `yaml:"exclude,omitempty"`   // versions or constraints never to select
+	MinAge    *Duration `yaml:"min_age,omitempty"`   // overrides defaults
+
+	MissingPlatforms PlatformPolicy `yaml:"missing_platforms,omitempty"` // overrides defaults
+	Align            *bool          `yaml:"align,omitempty"`             // overrides defaults
+	Protocols        []string       `yaml:"protocols,omitempty"`         // overrides defaults
+
+	// PublishAs is the address the provider is published under in the mirror,
+	// as hostname/namespace/name or namespace/name to keep the registry hostname
+	PublishAs string `yaml:"publish_as,omitempty"`
+
+	// Origin is the manifest file the block was declared in, relative to the
+	// first manifest loaded. Empty unless the manifest spans several files.
+	Origin string `yaml:"-"`
+
+	file   string // file the block was read from, for error positions
+	line   int
+	column int
 }
 
 // ProviderSource represents a parsed provider address
@@ -70,105 +149,256 @@ func (p ProviderSource) String() string {
 	return fmt.Sprintf("%s/%s/%s", p.Hostname, p.Namespace, p.Name)
 }
 
-// Load reads and parses the manifest file
-func Load(path string) (*Manifest, error) {
-	data, err := os.ReadFile(path)
+// Parse parses manifest YAML data.
+// Unknown keys are rejected, and all problems found are returned together as
+// ValidationErrors with line and column numbers.
+// Includes and advisory files are not resolved; use Load to resolve them
+// relative to the manifest.
+func Parse(data []byte) (*Manifest, error) {
+	return ParseWithOptions(data, Options{})
+}
+
+// ParseWithOptions parses manifest YAML data, expanding ${VAR} references in
+// values from opts.Vars and the environment variables listed in allow_env
+func ParseWithOptions(data []byte, opts Options) (*Manifest, error) {
+	m, errs, err := decode(data, opts, nil)
 	if err != nil {
-		return nil, fmt.Errorf("reading manifest: %w", err)
+		return nil, err
 	}
 
-	return Parse(data)
+	errs = append(errs, m.validate(Defaults{})...)
+	m.Providers = m.Defaults.apply(m.Providers)
+	errs = append(errs, checkConflicts(m.Providers)...)
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return m, nil
 }
 
-// Parse parses manifest YAML data
-func Parse(data []byte) (*Manifest, error) {
-	var m Manifest
-	if err := yaml.Unmarshal(data, &m); err != nil {
-		return nil, fmt.Errorf("parsing manifest: %w", err)
+// decode strictly decodes a manifest document without validating it.
+// Unknown keys, unresolved variables and type errors are returned as
+// validation errors. allowEnv extends the document's own allow_env list.
+func decode(data []byte, opts Options, allowEnv []string) (*Manifest, ValidationErrors, error) {
+	var root *yaml.Node
+	var errs, unresolved ValidationErrors
+	var err error
+
+	// Every format is converted into a YAML node tree with source positions
+	if opts.Format == FormatHCL {
+		root, errs, unresolved, err = hclDocument(data, opts, allowEnv)
+	} else {
+		root, errs, unresolved, err = yamlDocument(data, opts, allowEnv)
+	}
+	if err != nil {
+		return nil, nil, err
 	}
+	errs = append(errs, unresolved...)
 
-	if err := m.Validate(); err != nil {
-		return nil, err
+	// Type errors are collected, decoding continues with the remaining fields
+	var m Manifest
+	if err := root.Decode(&m); err != nil {
+		var te *yaml.TypeError
+		if !errors.As(err, &te) {
+			return nil, nil, fmt.Errorf("parsing manifest: %w", err)
+		}
+		errs = append(errs, typeErrors(root, te)...)
 	}
 
-	m.applyDefaults()
+	m.node = root
+	m.unresolved = make(map[[2]int]bool, len(unresolved))
+	for _, ve := range unresolved {
+		m.unresolved[[2]int{ve.Line, ve.Column}] = true
+	}
+	for i := range m.Providers {
+		n := nodeAt(root, "providers", i)
+		m.Providers[i].line = n.Line
+		m.Providers[i].column = n.Column
+	}
 
-	return &m, nil
+	return &m, errs, nil
 }
 
-// Validate checks that the manifest is well-formed
-func (m *Manifest) Validate() error {
-	for _, e := range m.Defaults.Engines {
-		if !e.IsValid() {
-			return fmt.Errorf("unsupported engine: %s", e)
+// yamlDocument parses YAML (or JSON) data, reporting unknown keys and
+// expanding ${VAR} references in values
+func yamlDocument(data []byte, opts Options, allowEnv []string) (*yaml.Node, ValidationErrors, ValidationErrors, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+
+	var errs ValidationErrors
+
+	// Unknown keys are detected on the raw document; type errors are only
+	// reported after interpolation, when values are final
+	var raw Manifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+		var te *yaml.TypeError
+		if !errors.As(err, &te) {
+			return nil, nil, nil, fmt.Errorf("parsing manifest: %w", err)
+		}
+		for _, ve := range typeErrors(&root, te) {
+			if strings.HasPrefix(ve.Message, "unknown field") {
+				errs = append(errs, ve)
+			}
 		}
 	}
 
-	if len(m.Providers) == 0 {
-		return fmt.Errorf("manifest must specify at least one provider")
+	in := newInterpolator(opts, append(allowedEnv(&root), allowEnv...))
+	in.document(&root)
+
+	return &root, errs, in.errs, nil
+}
+
+// IsPlatformPattern returns true if the platform entry is a wildcard pattern
+func IsPlatformPattern(platform string) bool {
+	return strings.ContainsAny(platform, "*?[")
+}
+
+// MatchPlatform reports whether a concrete os_arch platform matches a platform
+// entry, which is either a literal platform or a wildcard pattern.
+func MatchPlatform(pattern, platform string) bool {
+	if !IsPlatformPattern(pattern) {
+		return pattern == platform
 	}
+	matched, err := path.Match(pattern, platform)
+	return err == nil && matched
+}
 
-	for i, p := range m.Providers {
-		if p.Source == "" {
-			return fmt.Errorf("provider %d: source is required", i)
-		}
-		if len(p.Versions) == 0 {
-			return fmt.Errorf("provider %s: at least one version constraint is required", p.Source)
+// expandPlatformSets replaces platform set references with their members
+func (d Defaults) expandPlatformSets(platforms []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
 		}
-		for _, e := range p.Engines {
-			if !e.IsValid() {
-				return fmt.Errorf("provider %s: unsupported engine: %s", p.Source, e)
+	}
+
+	for _, p := range platforms {
+		if members, ok := d.PlatformSets[p]; ok {
+			for _, member := range members {
+				add(member)
 			}
+			continue
 		}
-		if len(p.Engines) == 0 && len(m.Defaults.Engines) == 0 {
-			return fmt.Errorf(
-				"provider %s: no engines specified (set defaults.engines or provider-level engines)",
-				p.Source,
-			)
-		}
+		add(p)
 	}
 
-	return nil
+	return result
 }
 
-// applyDefaults fills in default values where not specified
-func (m *Manifest) applyDefaults() {
-	for i := range m.Providers {
-		if len(m.Providers[i].Engines) == 0 {
-			m.Providers[i].Engines = m.Defaults.Engines
+// layer returns the defaults with the values set in child taking precedence
+func (d Defaults) layer(child Defaults) Defaults {
+	result := d
+	if len(child.Engines) > 0 {
+		result.Engines = child.Engines
+	}
+	if len(child.Platforms) > 0 {
+		result.Platforms = child.Platforms
+	}
+	if len(child.PlatformSets) > 0 {
+		result.PlatformSets = make(map[string][]string, len(d.PlatformSets)+len(child.PlatformSets))
+		for name, members := range d.PlatformSets {
+			result.PlatformSets[name] = members
 		}
-		if len(m.Providers[i].Platforms) == 0 {
-			m.Providers[i].Platforms = m.Defaults.Platforms
+		for name, members := range child.PlatformSets {
+			result.PlatformSets[name] = members
 		}
 	}
+	if child.MinAge != nil {
+		result.MinAge = child.MinAge
+	}
+	if child.MissingPlatforms != "" {
+		result.MissingPlatforms = child.MissingPlatforms
+	}
+	if child.Align != nil {
+		result.Align = child.Align
+	}
+	if len(child.Protocols) > 0 {
+		result.Protocols = child.Protocols
+	}
+	return result
+}
+
+// apply returns a copy of the providers with default values filled in where not specified
+func (d Defaults) apply(providers []Provider) []Provider {
+	result := make([]Provider, len(providers))
+	for i, p := range providers {
+		if len(p.Engines) == 0 {
+			p.Engines = d.Engines
+		}
+		if len(p.Platforms) == 0 {
+			p.Platforms = d.Platforms
+		}
+		p.Platforms = d.expandPlatformSets(p.Platforms)
+		if p.MinAge == nil {
+			p.MinAge = d.MinAge
+		}
+		if p.MissingPlatforms == "" {
+			p.MissingPlatforms = d.MissingPlatforms
+		}
+		if p.MissingPlatforms == "" {
+			p.MissingPlatforms = PlatformPolicyFail
+		}
+		if p.Align == nil {
+			p.Align = d.Align
+		}
+		if len(p.Protocols) == 0 {
+			p.Protocols = d.Protocols
+		}
+		result[i] = p
+	}
+	return result
 }
 
 // ParseProviderSource parses a provider source string into its components
 func ParseProviderSource(source string) (ProviderSource, error) {
 	parts := strings.Split(source, "/")
 
+	var ps ProviderSource
 	switch len(parts) {
 	case 2:
 		// namespace/name
-		return ProviderSource{
+		ps = ProviderSource{
 			Namespace: parts[0],
 			Name:      parts[1],
-		}, nil
+		}
 	case 3:
 		// hostname/namespace/name
-		return ProviderSource{
+		ps = ProviderSource{
 			Hostname:  parts[0],
 			Namespace: parts[1],
 			Name:      parts[2],
-		}, nil
+		}
+		if !hostnamePattern.MatchString(ps.Hostname) {
+			return ProviderSource{}, fmt.Errorf("invalid hostname %q in provider source %s", ps.Hostname, source)
+		}
 	default:
 		return ProviderSource{}, fmt.Errorf(
 			"invalid provider source format: %s (expected namespace/name or hostname/namespace/name)",
 			source,
 		)
 	}
+
+	if !addressPartPattern.MatchString(ps.Namespace) {
+		return ProviderSource{}, fmt.Errorf("invalid namespace %q in provider source %s", ps.Namespace, source)
+	}
+	if !addressPartPattern.MatchString(ps.Name) {
+		return ProviderSource{}, fmt.Errorf("invalid name %q in provider source %s", ps.Name, source)
+	}
+
+	return ps, nil
 }
 
+var (
+	hostnamePattern    = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?(:[0-9]+)?$`)
+	addressPartPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_-]*[A-Za-z0-9])?$`)
+)
+
 // expandProvider expands a provider specification across configured engines
 func (m *Manifest) expandProvider(p Provider) ([]ExpandedProvider, error) {
 	parsed, err := ParseProviderSource(p.Source)
@@ -176,47 +406,77 @@ func (m *Manifest) expandProvider(p Provider) ([]ExpandedProvider, error) {
 		return nil, err
 	}
 
-	var result []ExpandedProvider
+	var minAge time.Duration
+	if p.MinAge != nil {
+		minAge = time.Duration(*p.MinAge)
+	}
+
+	var publishAs ProviderSource
+	if p.PublishAs != "" {
+		publishAs, err = ParseProviderSource(p.PublishAs)
+		if err != nil {
+			return nil, fmt.Errorf("publish_as: %w", err)
+		}
+	}
+
+	base := ExpandedProvider{
+		Source:           parsed,
+		Versions:         p.Versions,
+		Platforms:        p.Platforms,
+		Exclude:          p.Exclude,
+		MinAge:           minAge,
+		MissingPlatforms: p.MissingPlatforms,
+		Align:            p.Align != nil && *p.Align,
+		Protocols:        p.Protocols,
+		SourceSpec:       p.Source,
+		Origin:           p.Origin,
+	}
 
 	if parsed.Hostname != "" {
 		// Explicit hostname
-		result = append(
-			result, ExpandedProvider{
-				Source:     parsed,
-				Versions:   p.Versions,
-				Platforms:  p.Platforms,
-				SourceSpec: p.Source,
-			},
-		)
-	} else {
-		// No hostname - expand per engine
-		for _, engine := range p.Engines {
-			hostname := engine.DefaultRegistry()
-			expanded := parsed
-			expanded.Hostname = hostname
+		base.PublishAs = publishAddress(publishAs, parsed.Hostname)
+		return []ExpandedProvider{base}, nil
+	}
 
-			result = append(
-				result, ExpandedProvider{
-					Source:     expanded,
-					Versions:   p.Versions,
-					Platforms:  p.Platforms,
-					Engine:     engine,
-					SourceSpec: p.Source,
-				},
-			)
-		}
+	// No hostname - expand per engine
+	var result []ExpandedProvider
+	for _, engine := range p.Engines {
+		expanded := base
+		expanded.Source.Hostname = engine.DefaultRegistry()
+		expanded.Engine = engine
+		expanded.PublishAs = publishAddress(publishAs, expanded.Source.Hostname)
+		result = append(result, expanded)
 	}
 
 	return result, nil
 }
 
+// publishAddress completes a publish_as address with the registry hostname
+// if it has none. The zero address is returned unchanged.
+func publishAddress(publishAs ProviderSource, hostname string) ProviderSource {
+	if publishAs == (ProviderSource{}) {
+		return publishAs
+	}
+	if publishAs.Hostname == "" {
+		publishAs.Hostname = hostname
+	}
+	return publishAs
+}
+
 // ExpandedProvider represents a provider with a fully resolved source
 type ExpandedProvider struct {
-	Source     ProviderSource
-	Versions   []string // constraints
-	Platforms  []string
-	Engine     Engine // empty if explicit hostname
-	SourceSpec string // original source specification
+	Source           ProviderSource
+	Versions         []string       // constraints
+	Platforms        []string       // os_arch platforms or wildcard patterns
+	Exclude          []string       // constraints of versions never to select
+	MinAge           time.Duration  // versions released more recently are held back
+	MissingPlatforms PlatformPolicy // what to do when a version lacks a platform
+	Align            bool           // select a version available on every aligned registry
+	Protocols        []string       // plugin protocols the consuming CLIs speak; empty allows any
+	PublishAs        ProviderSource // mirror address, if different from Source; zero otherwise
+	Engine           Engine         // empty if explicit hostname
+	SourceSpec       string         // original source specification
+	Origin           string         // manifest file the provider was declared in, if several
 }
 
 // GetExpandedProviders returns all providers expanded across engines
@@ -3,7 +3,9 @@ package manifest
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 // --- Engine tests ---
@@ -596,3 +598,344 @@ providers:
 		t.Errorf("expected 1 provider, got %d", len(m.Providers))
 	}
 }
+
+func TestGetExpandedProviders_PreservesExclude(t *testing.T) {
+	yaml := `
+defaults:
+  engines:
+    - terraform
+    - opentofu
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    exclude: ["5.31.0"]
+`
+	m, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	expanded, err := m.GetExpandedProviders()
+	if err != nil {
+		t.Fatalf("GetExpandedProviders() error = %v", err)
+	}
+
+	for _, ep := range expanded {
+		if len(ep.Exclude) != 1 || ep.Exclude[0] != "5.31.0" {
+			t.Errorf("expected exclude [5.31.0] for %s, got %v", ep.Source, ep.Exclude)
+		}
+	}
+}
+
+func TestParse_MinAgeDefaultsAndOverride(t *testing.T) {
+	yaml := `
+defaults:
+  engines:
+    - terraform
+  min_age: 3d
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+  - source: hashicorp/null
+    versions: ["~> 3.0"]
+    min_age: "0"
+`
+	m, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	expanded, err := m.GetExpandedProviders()
+	if err != nil {
+		t.Fatalf("GetExpandedProviders() error = %v", err)
+	}
+
+	if expanded[0].MinAge != 72*time.Hour {
+		t.Errorf("expected aws min_age 72h from defaults, got %v", expanded[0].MinAge)
+	}
+
+	if expanded[1].MinAge != 0 {
+		t.Errorf("expected null min_age override 0, got %v", expanded[1].MinAge)
+	}
+}
+
+func TestParse_AlignDefaultsAndOverride(t *testing.T) {
+	yaml := `
+defaults:
+  engines: [terraform, opentofu]
+  align: true
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+  - source: hashicorp/null
+    versions: ["~> 3.0"]
+    align: false
+`
+	m, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	expanded, err := m.GetExpandedProviders()
+	if err != nil {
+		t.Fatalf("GetExpandedProviders() error = %v", err)
+	}
+
+	for _, ep := range expanded {
+		want := ep.Source.Name == "aws"
+		if ep.Align != want {
+			t.Errorf("%s on %s: align = %v, want %v", ep.SourceSpec, ep.Source.Hostname, ep.Align, want)
+		}
+	}
+}
+
+func TestParse_ProtocolsDefaultsAndOverride(t *testing.T) {
+	yaml := `
+defaults:
+  engines: [terraform]
+  protocols: [5]
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+  - source: hashicorp/null
+    versions: ["~> 3.0"]
+    protocols: ["5.0", "6.0"]
+`
+	m, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	expanded, err := m.GetExpandedProviders()
+	if err != nil {
+		t.Fatalf("GetExpandedProviders() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(expanded[0].Protocols, []string{"5"}) {
+		t.Errorf("expected aws protocols [5] from defaults, got %v", expanded[0].Protocols)
+	}
+	if !reflect.DeepEqual(expanded[1].Protocols, []string{"5.0", "6.0"}) {
+		t.Errorf("expected null protocols override, got %v", expanded[1].Protocols)
+	}
+}
+
+func TestProtocolMajor(t *testing.T) {
+	tests := []struct {
+		protocol string
+		want     int
+		wantErr  bool
+	}{
+		{"5", 5, false},
+		{"6.0", 6, false},
+		{" 5.1 ", 5, false},
+		{"0", 0, true},
+		{"v6", 0, true},
+		{"6.x", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ProtocolMajor(tt.protocol)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ProtocolMajor(%q) = %d, %v; want %d, error %v", tt.protocol, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParse_PublishAs(t *testing.T) {
+	yaml := `
+defaults:
+  engines: [terraform, opentofu]
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    publish_as: registry.corp.example/hashicorp/aws
+  - source: hashicorp/null
+    versions: ["~> 3.0"]
+    publish_as: corp/null
+`
+	m, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	expanded, err := m.GetExpandedProviders()
+	if err != nil {
+		t.Fatalf("GetExpandedProviders() error = %v", err)
+	}
+
+	var got []string
+	for _, ep := range expanded {
+		got = append(got, ep.Source.String()+" -> "+ep.PublishAs.String())
+	}
+	want := []string{
+		"registry.terraform.io/hashicorp/aws -> registry.corp.example/hashicorp/aws",
+		"registry.opentofu.org/hashicorp/aws -> registry.corp.example/hashicorp/aws",
+		"registry.terraform.io/hashicorp/null -> registry.terraform.io/corp/null",
+		"registry.opentofu.org/hashicorp/null -> registry.opentofu.org/corp/null",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("publish_as expansion = %v, want %v", got, want)
+	}
+}
+
+func TestParse_MissingPlatformsPolicy(t *testing.T) {
+	yaml := `
+defaults:
+  engines:
+    - terraform
+  missing_platforms: skip
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+  - source: hashicorp/null
+    versions: ["~> 3.0"]
+    missing_platforms: fallback
+`
+	m, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if m.Providers[0].MissingPlatforms != PlatformPolicySkip {
+		t.Errorf("expected skip from defaults, got %q", m.Providers[0].MissingPlatforms)
+	}
+
+	if m.Providers[1].MissingPlatforms != PlatformPolicyFallback {
+		t.Errorf("expected fallback override, got %q", m.Providers[1].MissingPlatforms)
+	}
+}
+
+func TestParse_MissingPlatformsDefaultsToFail(t *testing.T) {
+	yaml := `
+defaults:
+  engines:
+    - terraform
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+`
+	m, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if m.Providers[0].MissingPlatforms != PlatformPolicyFail {
+		t.Errorf("expected fail by default, got %q", m.Providers[0].MissingPlatforms)
+	}
+}
+
+func TestValidate_UnsupportedMissingPlatformsPolicy(t *testing.T) {
+	yaml := `
+defaults:
+  engines:
+    - terraform
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    missing_platforms: ignore
+`
+	if _, err := Parse([]byte(yaml)); err == nil {
+		t.Error("expected error for unsupported missing_platforms policy")
+	}
+}
+
+// --- Platform set and pattern tests ---
+
+func TestParse_PlatformSets(t *testing.T) {
+	yaml := `
+defaults:
+  engines:
+    - terraform
+  platform_sets:
+    ci: [linux_amd64, linux_arm64]
+    workstations: [darwin_arm64, windows_amd64]
+  platforms: [ci]
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+  - source: hashicorp/null
+    versions: ["~> 3.0"]
+    platforms: [ci, workstations, linux_amd64, "freebsd_*"]
+`
+	m, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []string{"linux_amd64", "linux_arm64"}
+	if !reflect.DeepEqual(m.Providers[0].Platforms, want) {
+		t.Errorf("expected default set expanded to %v, got %v", want, m.Providers[0].Platforms)
+	}
+
+	want = []string{"linux_amd64", "linux_arm64", "darwin_arm64", "windows_amd64", "freebsd_*"}
+	if !reflect.DeepEqual(m.Providers[1].Platforms, want) {
+		t.Errorf("expected %v, got %v", want, m.Providers[1].Platforms)
+	}
+}
+
+func TestValidate_UnknownPlatformSet(t *testing.T) {
+	yaml := `
+defaults:
+  engines:
+    - terraform
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    platforms: [workstations]
+`
+	if _, err := Parse([]byte(yaml)); err == nil {
+		t.Error("expected error for unknown platform set")
+	}
+}
+
+func TestValidate_PlatformSetWithInvalidMember(t *testing.T) {
+	yaml := `
+defaults:
+  engines:
+    - terraform
+  platform_sets:
+    nested: [ci]
+
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+`
+	if _, err := Parse([]byte(yaml)); err == nil {
+		t.Error("expected error for platform set referencing another set")
+	}
+}
+
+func TestMatchPlatform(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		platform string
+		want     bool
+	}{
+		{"*", "linux_amd64", true},
+		{"linux_*", "linux_arm64", true},
+		{"linux_*", "darwin_arm64", false},
+		{"*_arm64", "darwin_arm64", true},
+		{"linux_amd64", "linux_amd64", true},
+		{"linux_amd64", "linux_arm64", false},
+		{"[", "linux_amd64", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.platform, func(t *testing.T) {
+			if got := MatchPlatform(tt.pattern, tt.platform); got != tt.want {
+				t.Errorf("MatchPlatform(%q, %q) = %v, want %v", tt.pattern, tt.platform, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package manifest
+
+import _ "embed"
+
+//go:embed schema.json
+var schema []byte
+
+// JSONSchema returns the JSON Schema describing the manifest format.
+// It applies to YAML and JSON manifests alike.
+func JSONSchema() []byte {
+	return append([]byte(nil), schema...)
+}
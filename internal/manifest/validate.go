@@ -0,0 +1,469 @@
+package manifest
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError describes a single problem found in a manifest
+type ValidationError struct {
+	File    string // empty when parsed from memory
+	Line    int    // 1-based, 0 if unknown
+	Column  int    // 1-based, 0 if unknown
+	Message string
+}
+
+// Error returns the problem prefixed with its position, if known
+func (e ValidationError) Error() string {
+	var pos string
+	switch {
+	case e.File != "" && e.Line > 0:
+		pos = fmt.Sprintf("%s:%d:%d: ", e.File, e.Line, e.Column)
+	case e.File != "":
+		pos = e.File + ": "
+	case e.Line > 0:
+		pos = fmt.Sprintf("line %d, column %d: ", e.Line, e.Column)
+	}
+	return pos + e.Message
+}
+
+// ValidationErrors is the list of all problems found in a manifest
+type ValidationErrors []ValidationError
+
+// Error returns all problems, one per line
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "%d problems in manifest:", len(e))
+	for _, ve := range e {
+		b.WriteString("\n  - ")
+		b.WriteString(ve.Error())
+	}
+	return b.String()
+}
+
+// withFile returns a copy of the errors attributed to the given file
+func (e ValidationErrors) withFile(file string) ValidationErrors {
+	result := make(ValidationErrors, len(e))
+	for i, ve := range e {
+		if ve.File == "" {
+			ve.File = file
+		}
+		result[i] = ve
+	}
+	return result
+}
+
+// Validate checks that the manifest is well-formed without contacting any registry.
+// All problems are returned together as ValidationErrors.
+func (m *Manifest) Validate() error {
+	errs := m.validate(Defaults{})
+	errs = append(errs, checkConflicts(m.Defaults.apply(m.Providers))...)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate runs all static checks on a single manifest file.
+// The file's defaults are layered over parent, which holds the defaults of
+// the including manifests.
+func (m *Manifest) validate(parent Defaults) ValidationErrors {
+	v := &validator{m: m, defaults: parent.layer(m.Defaults)}
+
+	for i, e := range m.Defaults.Engines {
+		if !e.IsValid() {
+			v.add(v.at("defaults", "engines", i), "unsupported engine: %s", e)
+		}
+	}
+
+	for name, platforms := range m.Defaults.PlatformSets {
+		if isPlatformLike(name) {
+			v.add(v.at("defaults", "platform_sets", name), "platform set %q: name must not look like a platform", name)
+		}
+		for i, p := range platforms {
+			if err := validatePlatform(p); err != nil {
+				v.add(v.at("defaults", "platform_sets", name, i), "platform set %s: %v", name, err)
+			}
+		}
+	}
+
+	for i, p := range m.Defaults.Platforms {
+		if err := v.defaults.validatePlatformRef(p); err != nil {
+			v.add(v.at("defaults", "platforms", i), "defaults: %v", err)
+		}
+	}
+
+	if m.Defaults.MissingPlatforms != "" && !m.Defaults.MissingPlatforms.IsValid() {
+		v.add(
+			v.at("defaults", "missing_platforms"),
+			"unsupported missing_platforms policy: %s", m.Defaults.MissingPlatforms,
+		)
+	}
+
+	for i, protocol := range m.Defaults.Protocols {
+		if _, err := ProtocolMajor(protocol); err != nil {
+			v.add(v.at("defaults", "protocols", i), "defaults: %v", err)
+		}
+	}
+
+	for _, host := range authHosts(m.Auth) {
+		auth := m.Auth[host]
+		if !hostnamePattern.MatchString(host) {
+			v.add(v.at("auth", host), "auth: invalid hostname %q", host)
+		}
+		for _, problem := range auth.problems() {
+			v.add(v.at("auth", host), "auth for %s: %s", host, problem)
+		}
+	}
+
+	for i, name := range m.AllowEnv {
+		if !varNamePattern.MatchString(name) {
+			v.add(v.at("allow_env", i), "invalid environment variable name %q", name)
+		}
+	}
+
+	for i, inc := range m.Includes {
+		if strings.TrimSpace(inc) == "" {
+			v.add(v.at("include", i), "include entry must not be empty")
+		}
+	}
+
+	if len(m.Providers) == 0 && len(m.Includes) == 0 {
+		v.add(v.at("providers"), "manifest must specify at least one provider")
+	}
+
+	for i, p := range m.Providers {
+		v.validateProvider(i, p)
+	}
+
+	return v.errs
+}
+
+// validator accumulates problems with positions taken from the YAML node tree
+type validator struct {
+	m        *Manifest
+	defaults Defaults // effective defaults, including those of parent manifests
+	errs     ValidationErrors
+}
+
+// add records a problem at the position of the given node (which may be nil).
+// Values with unresolved variables are already reported and are skipped.
+func (v *validator) add(node *yaml.Node, format string, args ...any) {
+	ve := ValidationError{Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		if v.m.unresolved[[2]int{node.Line, node.Column}] {
+			return
+		}
+		ve.Line = node.Line
+		ve.Column = node.Column
+	}
+	v.errs = append(v.errs, ve)
+}
+
+// at returns the node at the given path of mapping keys and sequence indices.
+// If the path does not exist, the deepest node found along the way is returned.
+func (v *validator) at(path ...any) *yaml.Node {
+	if v.m.node == nil {
+		return nil
+	}
+	return nodeAt(v.m.node, path...)
+}
+
+// validateProvider checks a single provider block
+func (v *validator) validateProvider(i int, p Provider) {
+	name := p.Source
+	if name == "" {
+		name = strconv.Itoa(i)
+	}
+
+	if p.Source == "" {
+		v.add(v.at("providers", i), "provider %d: source is required", i)
+	} else if _, err := ParseProviderSource(p.Source); err != nil {
+		v.add(v.at("providers", i, "source"), "provider %s: %v", name, err)
+	}
+
+	if len(p.Versions) == 0 {
+		v.add(v.at("providers", i), "provider %s: at least one version constraint is required", name)
+	}
+	for j, c := range p.Versions {
+		if _, err := version.NewConstraint(c); err != nil {
+			v.add(v.at("providers", i, "versions", j), "provider %s: invalid version constraint %q", name, c)
+		}
+	}
+
+	for j, c := range p.Exclude {
+		if _, err := version.NewConstraint(c); err != nil {
+			v.add(v.at("providers", i, "exclude", j), "provider %s: invalid exclude constraint %q", name, c)
+		}
+	}
+
+	for j, e := range p.Engines {
+		if !e.IsValid() {
+			v.add(v.at("providers", i, "engines", j), "provider %s: unsupported engine: %s", name, e)
+		}
+	}
+	if len(p.Engines) == 0 && len(v.defaults.Engines) == 0 {
+		v.add(
+			v.at("providers", i),
+			"provider %s: no engines specified (set defaults.engines or provider-level engines)",
+			name,
+		)
+	}
+
+	for j, platform := range p.Platforms {
+		if err := v.defaults.validatePlatformRef(platform); err != nil {
+			v.add(v.at("providers", i, "platforms", j), "provider %s: %v", name, err)
+		}
+	}
+
+	if p.MissingPlatforms != "" && !p.MissingPlatforms.IsValid() {
+		v.add(
+			v.at("providers", i, "missing_platforms"),
+			"provider %s: unsupported missing_platforms policy: %s", name, p.MissingPlatforms,
+		)
+	}
+
+	if p.PublishAs != "" {
+		if _, err := ParseProviderSource(p.PublishAs); err != nil {
+			v.add(v.at("providers", i, "publish_as"), "provider %s: invalid publish_as: %v", name, err)
+		}
+	}
+
+	for j, protocol := range p.Protocols {
+		if _, err := ProtocolMajor(protocol); err != nil {
+			v.add(v.at("providers", i, "protocols", j), "provider %s: %v", name, err)
+		}
+	}
+}
+
+// checkConflicts reports provider blocks that declare the same source and
+// constraint with different settings. Sources are compared by the registry
+// addresses they resolve to, so spellings with and without the default
+// hostname conflict too. Providers must have defaults applied, so that blocks
+// from fragments with different defaults compare correctly.
+func checkConflicts(providers []Provider) ValidationErrors {
+	type key struct {
+		source     string
+		constraint string
+	}
+	first := make(map[key]int)
+
+	var errs ValidationErrors
+	for i, p := range providers {
+		for _, c := range p.Versions {
+			for _, source := range conflictSources(p) {
+				k := key{source: source, constraint: c}
+				prev, ok := first[k]
+				if !ok {
+					first[k] = i
+					continue
+				}
+				if prev == i || settingsKey(providers[prev]) == settingsKey(p) {
+					continue
+				}
+
+				errs = append(
+					errs, ValidationError{
+						File:   p.file,
+						Line:   p.line,
+						Column: p.column,
+						Message: fmt.Sprintf(
+							"provider %s: constraint %q is also declared at %s with different settings",
+							p.Source, c, providers[prev].location(),
+						),
+					},
+				)
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// conflictSources returns the lowercased registry addresses a provider block
+// resolves to. A source without a hostname resolves to the default registry
+// of each engine; an invalid source is compared as written.
+func conflictSources(p Provider) []string {
+	src, err := ParseProviderSource(p.Source)
+	if err != nil {
+		return []string{strings.ToLower(p.Source)}
+	}
+	if src.Hostname != "" {
+		return []string{strings.ToLower(src.String())}
+	}
+
+	var sources []string
+	for _, engine := range p.Engines {
+		src.Hostname = engine.DefaultRegistry()
+		sources = append(sources, strings.ToLower(src.String()))
+	}
+	if len(sources) == 0 {
+		return []string{strings.ToLower(p.Source)}
+	}
+	return sources
+}
+
+// location describes where a provider block was declared
+func (p Provider) location() string {
+	switch {
+	case p.file != "" && p.line > 0:
+		return fmt.Sprintf("%s:%d", p.file, p.line)
+	case p.file != "":
+		return p.file
+	case p.line > 0:
+		return fmt.Sprintf("line %d", p.line)
+	default:
+		return "another provider block"
+	}
+}
+
+// settingsKey summarizes the settings of a provider block that affect resolution.
+// Engines are left out, as they are covered by the addresses compared.
+func settingsKey(p Provider) string {
+	var minAge string
+	if p.MinAge != nil {
+		minAge = p.MinAge.String()
+	}
+	align := p.Align != nil && *p.Align
+	return fmt.Sprintf(
+		"%v|%v|%s|%s|%t|%v|%s",
+		p.Platforms, p.Exclude, minAge, p.MissingPlatforms, align, p.Protocols, p.PublishAs,
+	)
+}
+
+// validatePlatformRef checks a platform entry, which may refer to a platform set
+func (d Defaults) validatePlatformRef(platform string) error {
+	if _, ok := d.PlatformSets[platform]; ok {
+		return nil
+	}
+	if !isPlatformLike(platform) {
+		return fmt.Errorf("unknown platform or platform set %q", platform)
+	}
+	return validatePlatform(platform)
+}
+
+// validatePlatform checks a literal os_arch platform or a wildcard pattern
+func validatePlatform(platform string) error {
+	if IsPlatformPattern(platform) {
+		if !isPlatformLike(platform) {
+			return fmt.Errorf("invalid platform pattern %q (expected os_arch pattern such as linux_*)", platform)
+		}
+		if _, err := path.Match(platform, ""); err != nil {
+			return fmt.Errorf("invalid platform pattern %q: %v", platform, err)
+		}
+		return nil
+	}
+	if !platformPattern.MatchString(platform) {
+		return fmt.Errorf("invalid platform %q (expected os_arch)", platform)
+	}
+	return nil
+}
+
+var platformPattern = regexp.MustCompile(`^[a-z0-9]+_[a-z0-9]+$`)
+
+// isPlatformLike returns true for os_arch strings and patterns such as "*" or "linux_*"
+func isPlatformLike(s string) bool {
+	return s == "*" || strings.Contains(s, "_")
+}
+
+// nodeAt walks the YAML node tree along a path of mapping keys (string) and
+// sequence indices (int), returning the deepest node reached.
+func nodeAt(node *yaml.Node, path ...any) *yaml.Node {
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return node
+		}
+		node = node.Content[0]
+	}
+
+	for _, step := range path {
+		next := childNode(node, step)
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+
+	return node
+}
+
+// childNode returns the value of a mapping key or the element of a sequence
+func childNode(node *yaml.Node, step any) *yaml.Node {
+	switch s := step.(type) {
+	case string:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == s {
+				return node.Content[i+1]
+			}
+		}
+	case int:
+		if node.Kind != yaml.SequenceNode || s < 0 || s >= len(node.Content) {
+			return nil
+		}
+		return node.Content[s]
+	}
+	return nil
+}
+
+// typeErrors converts yaml decoding errors into validation errors, resolving
+// the column of unknown fields from the node tree
+func typeErrors(root *yaml.Node, te *yaml.TypeError) ValidationErrors {
+	var result ValidationErrors
+	for _, msg := range te.Errors {
+		ve := ValidationError{Message: msg}
+
+		if m := typeErrorPattern.FindStringSubmatch(msg); m != nil {
+			ve.Line, _ = strconv.Atoi(m[1])
+			ve.Message = m[2]
+		}
+
+		if m := unknownFieldPattern.FindStringSubmatch(ve.Message); m != nil {
+			ve.Message = fmt.Sprintf("unknown field %q", m[1])
+			if key := findKey(root, m[1], ve.Line); key != nil {
+				ve.Column = key.Column
+			}
+		}
+
+		if ve.Line > 0 && ve.Column == 0 {
+			ve.Column = 1
+		}
+
+		result = append(result, ve)
+	}
+	return result
+}
+
+var (
+	typeErrorPattern    = regexp.MustCompile(`^line (\d+): (.*)$`)
+	unknownFieldPattern = regexp.MustCompile(`^field (\S+) not found in type`)
+)
+
+// findKey searches the tree for a mapping key with the given name on the given line
+func findKey(node *yaml.Node, name string, line int) *yaml.Node {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if k := node.Content[i]; k.Value == name && k.Line == line {
+				return k
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if found := findKey(child, name, line); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package manifest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// --- Strict validation tests ---
+
+func parseValidationErrors(t *testing.T, data string) ValidationErrors {
+	t.Helper()
+
+	_, err := Parse([]byte(data))
+	if err == nil {
+		t.Fatal("expected validation error, got nil")
+	}
+
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+	return verrs
+}
+
+func TestValidate_UnknownKeyReportsPosition(t *testing.T) {
+	verrs := parseValidationErrors(t, `
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    platfroms: [linux_amd64]
+`)
+
+	if len(verrs) != 1 {
+		t.Fatalf("expected 1 problem, got %d: %v", len(verrs), verrs)
+	}
+	got := verrs[0]
+	if got.Line != 7 || got.Column != 5 {
+		t.Errorf("expected position 7:5, got %d:%d", got.Line, got.Column)
+	}
+	if !strings.Contains(got.Message, `unknown field "platfroms"`) {
+		t.Errorf("unexpected message: %s", got.Message)
+	}
+}
+
+func TestValidate_CollectsAllProblems(t *testing.T) {
+	verrs := parseValidationErrors(t, `
+defaults:
+  engines: [terraform]
+  platforms: [linux_amd64, linux-arm64]
+  min_age: forever
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0", "not a constraint"]
+  - source: "hashicorp/bad name"
+    versions: ["1.0.0"]
+    exclude: ["<<1"]
+`)
+
+	want := []struct {
+		line    int
+		message string
+	}{
+		{5, `invalid duration "forever"`},
+		{4, `unknown platform or platform set "linux-arm64"`},
+		{8, `invalid version constraint "not a constraint"`},
+		{9, `invalid name "bad name"`},
+		{11, `invalid exclude constraint "<<1"`},
+	}
+
+	if len(verrs) != len(want) {
+		t.Fatalf("expected %d problems, got %d: %v", len(want), len(verrs), verrs)
+	}
+	for i, w := range want {
+		if verrs[i].Line != w.line {
+			t.Errorf("problem %d: expected line %d, got %d (%s)", i, w.line, verrs[i].Line, verrs[i].Message)
+		}
+		if !strings.Contains(verrs[i].Message, w.message) {
+			t.Errorf("problem %d: expected message containing %q, got %q", i, w.message, verrs[i].Message)
+		}
+	}
+}
+
+func TestValidate_InvalidPlatformPattern(t *testing.T) {
+	verrs := parseValidationErrors(t, `
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    platforms: ["linux_[amd64"]
+`)
+
+	if len(verrs) != 1 || !strings.Contains(verrs[0].Message, "invalid platform pattern") {
+		t.Errorf("expected invalid platform pattern, got %v", verrs)
+	}
+}
+
+func TestValidate_InvalidHostname(t *testing.T) {
+	verrs := parseValidationErrors(t, `
+defaults:
+  engines: [terraform]
+providers:
+  - source: "registry..example_com/hashicorp/aws"
+    versions: ["~> 5.0"]
+`)
+
+	if len(verrs) != 1 || !strings.Contains(verrs[0].Message, "invalid hostname") {
+		t.Errorf("expected invalid hostname, got %v", verrs)
+	}
+}
+
+func TestValidate_InvalidProtocol(t *testing.T) {
+	verrs := parseValidationErrors(t, `
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    protocols: ["five"]
+`)
+
+	if len(verrs) != 1 || verrs[0].Line != 7 || !strings.Contains(verrs[0].Message, `invalid plugin protocol "five"`) {
+		t.Errorf("unexpected problems: %v", verrs)
+	}
+}
+
+func TestValidate_InvalidPublishAs(t *testing.T) {
+	verrs := parseValidationErrors(t, `
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    publish_as: aws
+`)
+
+	if len(verrs) != 1 || verrs[0].Line != 7 || !strings.Contains(verrs[0].Message, "invalid publish_as") {
+		t.Errorf("unexpected problems: %v", verrs)
+	}
+}
+
+func TestValidate_DuplicateBlocksWithConflictingSettings(t *testing.T) {
+	verrs := parseValidationErrors(t, `
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    platforms: [linux_amd64]
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    platforms: [darwin_arm64]
+`)
+
+	if len(verrs) != 1 {
+		t.Fatalf("expected 1 problem, got %d: %v", len(verrs), verrs)
+	}
+	if verrs[0].Line != 8 || !strings.Contains(verrs[0].Message, "also declared at line 5") {
+		t.Errorf("unexpected problem: %v", verrs[0])
+	}
+}
+
+func TestValidate_ConflictingSpellingsOfSameSource(t *testing.T) {
+	verrs := parseValidationErrors(t, `
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    platforms: [linux_amd64]
+  - source: Registry.Terraform.io/HashiCorp/AWS
+    versions: ["~> 5.0"]
+    platforms: [darwin_arm64]
+`)
+
+	if len(verrs) != 1 || verrs[0].Line != 8 || !strings.Contains(verrs[0].Message, "also declared at line 5") {
+		t.Errorf("unexpected problems: %v", verrs)
+	}
+}
+
+func TestValidate_SameSourceForDifferentEnginesAllowed(t *testing.T) {
+	_, err := Parse([]byte(`
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    engines: [terraform]
+    platforms: [linux_amd64]
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+    engines: [opentofu]
+    platforms: [darwin_arm64]
+`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate_DuplicateBlocksWithSameSettingsAllowed(t *testing.T) {
+	_, err := Parse([]byte(`
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/aws
+    versions: ["~> 5.0"]
+  - source: hashicorp/aws
+    versions: ["~> 5.0", "~> 4.0"]
+`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoad_ValidationErrorsIncludeFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mirror.yaml")
+
+	content := `
+defaults:
+  engines: [terraform]
+providers:
+  - source: hashicorp/aws
+    version: ["~> 5.0"]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, path+":6:5: unknown field \"version\"") {
+		t.Errorf("expected file position in error, got: %s", msg)
+	}
+	if !strings.Contains(msg, "at least one version constraint is required") {
+		t.Errorf("expected missing versions problem, got: %s", msg)
+	}
+}
+
+func TestValidationErrors_Error(t *testing.T) {
+	single := ValidationErrors{{Line: 3, Column: 7, Message: "bad"}}
+	if got := single.Error(); got != "line 3, column 7: bad" {
+		t.Errorf("single error = %q", got)
+	}
+
+	multi := ValidationErrors{
+		{File: "m.yaml", Line: 1, Column: 1, Message: "first"},
+		{File: "m.yaml", Message: "second"},
+	}
+	want := "2 problems in manifest:\n  - m.yaml:1:1: first\n  - m.yaml: second"
+	if got := multi.Error(); got != want {
+		t.Errorf("multi error = %q, want %q", got, want)
+	}
+}
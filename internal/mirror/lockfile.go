@@ -0,0 +1,117 @@
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+)
+
+// LockFileName is the name of the lock file in the mirror directory
+const LockFileName = "mirror.lock"
+
+// ReadLockFile reads and parses a mirror.lock file
+func ReadLockFile(path string) (*LockFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading lock file: %w", err)
+	}
+
+	var lockFile LockFile
+	if err := json.Unmarshal(data, &lockFile); err != nil {
+		return nil, fmt.Errorf("parsing lock file %s: %w", path, err)
+	}
+
+	return &lockFile, nil
+}
+
+// PinnedVersion returns the locked version a manifest constraint resolved to.
+// Sources without a hostname match the provider on every registry, which must
+// all have resolved the constraint to the same version.
+func (l *LockFile) PinnedVersion(source, constraint string) (string, error) {
+	src, err := manifest.ParseProviderSource(source)
+	if err != nil {
+		return "", err
+	}
+
+	c, err := version.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+
+	selected := l.lockedVersions(src, c)
+	if len(selected) == 0 {
+		return "", fmt.Errorf("no version matching %q in lock file", constraint)
+	}
+
+	var hosts []string
+	for host := range selected {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	pinned := selected[hosts[0]]
+	for _, host := range hosts[1:] {
+		if !selected[host].Equal(pinned) {
+			return "", fmt.Errorf(
+				"constraint %q resolved to %s on %s but %s on %s",
+				constraint, pinned.Original(), hosts[0], selected[host].Original(), host,
+			)
+		}
+	}
+
+	return pinned.Original(), nil
+}
+
+// LockedVersion returns the newest version of a provider on its registry
+// that is in the lock file and satisfies the constraint
+func (l *LockFile) LockedVersion(src manifest.ProviderSource, constraint version.Constraints) (string, bool) {
+	v := l.lockedVersions(src, constraint)[strings.ToLower(src.Hostname)]
+	if v == nil {
+		return "", false
+	}
+	return v.Original(), true
+}
+
+// lockedVersions returns the newest locked version matching the constraint,
+// keyed by lowercase registry hostname. An empty hostname matches every registry.
+// Versions published under another address are matched by their origin.
+func (l *LockFile) lockedVersions(src manifest.ProviderSource, c version.Constraints) map[string]*version.Version {
+	selected := make(map[string]*version.Version)
+	for _, p := range l.Providers {
+		for _, lv := range p.Versions {
+			upstream := lv.Upstream(p)
+			if !strings.EqualFold(upstream.Namespace, src.Namespace) || !strings.EqualFold(upstream.Name, src.Name) {
+				continue
+			}
+			if src.Hostname != "" && !strings.EqualFold(upstream.Hostname, src.Hostname) {
+				continue
+			}
+
+			v, err := version.NewVersion(lv.Version)
+			if err != nil || !c.Check(v) {
+				continue
+			}
+			host := strings.ToLower(upstream.Hostname)
+			if cur := selected[host]; cur == nil || v.GreaterThan(cur) {
+				selected[host] = v
+			}
+		}
+	}
+	return selected
+}
+
+// Upstream returns the address a version of the provider was downloaded from
+func (v LockFileVersion) Upstream(p LockFileProvider) manifest.ProviderSource {
+	if v.Origin != "" {
+		if src, err := manifest.ParseProviderSource(v.Origin); err == nil && src.Hostname != "" {
+			return src
+		}
+	}
+	return manifest.ProviderSource{Hostname: p.Hostname, Namespace: p.Namespace, Name: p.Name}
+}
@@ -0,0 +1,162 @@
+package mirror
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+)
+
+// --- ReadLockFile tests ---
+
+func TestReadLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+	data, _ := json.Marshal(testLockFile())
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lockFile, err := ReadLockFile(path)
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	if len(lockFile.Providers) != 3 {
+		t.Errorf("expected 3 providers, got %d", len(lockFile.Providers))
+	}
+}
+
+func TestReadLockFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadLockFile(path); err == nil {
+		t.Error("expected error for invalid lock file")
+	}
+}
+
+// --- PinnedVersion tests ---
+
+func testLockFile() *LockFile {
+	return &LockFile{
+		Version: 1,
+		Providers: []LockFileProvider{
+			{
+				Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws",
+				Versions: []LockFileVersion{{Version: "5.9.0"}, {Version: "5.10.0"}, {Version: "4.67.0"}},
+			},
+			{
+				Hostname: "registry.opentofu.org", Namespace: "hashicorp", Name: "aws",
+				Versions: []LockFileVersion{{Version: "5.10.0"}, {Version: "5.9.0"}},
+			},
+			{
+				Hostname: "registry.opentofu.org", Namespace: "hashicorp", Name: "google",
+				Versions: []LockFileVersion{{Version: "6.1.0"}},
+			},
+		},
+	}
+}
+
+func TestLockFile_PinnedVersion(t *testing.T) {
+	lockFile := testLockFile()
+	lockFile.Providers = append(
+		lockFile.Providers, LockFileProvider{
+			Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "google",
+			Versions: []LockFileVersion{{Version: "6.2.0"}},
+		},
+	)
+
+	tests := []struct {
+		source     string
+		constraint string
+		want       string
+		wantErr    string
+	}{
+		{"hashicorp/aws", "~> 5.0", "5.10.0", ""},
+		{"hashicorp/aws", "~> 4.0", "4.67.0", ""},
+		{"HashiCorp/AWS", ">= 5.9.0, < 5.10.0", "5.9.0", ""},
+		{"registry.opentofu.org/hashicorp/google", "~> 6.0", "6.1.0", ""},
+		{"hashicorp/google", "~> 6.0", "", "resolved to 6.1.0 on registry.opentofu.org but 6.2.0 on registry.terraform.io"},
+		{"hashicorp/aws", "~> 3.0", "", `no version matching "~> 3.0"`},
+		{"hashicorp/null", "3.2.4", "", "no version matching"},
+		{"hashicorp/aws", "not a constraint", "", "invalid version constraint"},
+	}
+
+	for _, tt := range tests {
+		got, err := lockFile.PinnedVersion(tt.source, tt.constraint)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("PinnedVersion(%q, %q) error = %v, want %q", tt.source, tt.constraint, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("PinnedVersion(%q, %q) error = %v", tt.source, tt.constraint, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("PinnedVersion(%q, %q) = %q, want %q", tt.source, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+// --- LockedVersion tests ---
+
+func TestLockFile_LockedVersion(t *testing.T) {
+	lockFile := testLockFile()
+	c, _ := version.NewConstraint("~> 5.0")
+
+	tests := []struct {
+		source manifest.ProviderSource
+		want   string
+		wantOK bool
+	}{
+		{manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}, "5.10.0", true},
+		{manifest.ProviderSource{Hostname: "Registry.OpenTofu.org", Namespace: "hashicorp", Name: "aws"}, "5.10.0", true},
+		{manifest.ProviderSource{Hostname: "registry.opentofu.org", Namespace: "hashicorp", Name: "google"}, "", false},
+		{manifest.ProviderSource{Hostname: "example.com", Namespace: "hashicorp", Name: "aws"}, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := lockFile.LockedVersion(tt.source, c)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("LockedVersion(%s) = %q, %v, want %q, %v", tt.source, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLockFile_LockedVersionMatchesOrigin(t *testing.T) {
+	lockFile := &LockFile{
+		Providers: []LockFileProvider{
+			{
+				Hostname: "registry.corp.example", Namespace: "hashicorp", Name: "aws",
+				Versions: []LockFileVersion{
+					{Version: "5.10.0", Origin: "registry.terraform.io/hashicorp/aws"},
+					{Version: "5.9.0", Origin: "registry.opentofu.org/hashicorp/aws"},
+				},
+			},
+		},
+	}
+	c, _ := version.NewConstraint("~> 5.0")
+
+	tf := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+	if got, ok := lockFile.LockedVersion(tf, c); !ok || got != "5.10.0" {
+		t.Errorf("LockedVersion(%s) = %q, %v, want 5.10.0", tf, got, ok)
+	}
+
+	tofu := manifest.ProviderSource{Hostname: "registry.opentofu.org", Namespace: "hashicorp", Name: "aws"}
+	if got, ok := lockFile.LockedVersion(tofu, c); !ok || got != "5.9.0" {
+		t.Errorf("LockedVersion(%s) = %q, %v, want 5.9.0", tofu, got, ok)
+	}
+
+	corp := manifest.ProviderSource{Hostname: "registry.corp.example", Namespace: "hashicorp", Name: "aws"}
+	if _, ok := lockFile.LockedVersion(corp, c); ok {
+		t.Errorf("expected the published address not to match as an upstream")
+	}
+}
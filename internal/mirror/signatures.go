@@ -0,0 +1,155 @@
+package mirror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/downloader"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+)
+
+// SigningKeyFileName is the public key of the organization signing key,
+// written to the mirror root
+const SigningKeyFileName = "signing-key.asc"
+
+// LockSignatureFileName is the detached signature of mirror.lock
+const LockSignatureFileName = LockFileName + SignatureSuffix
+
+// Suffixes of the files stored next to a version's SHA256SUMS file
+const (
+	SignatureSuffix = ".sig"      // detached signature
+	UpstreamSuffix  = ".upstream" // checksums as published upstream
+	PublicKeySuffix = ".asc"      // keys verifying the upstream signature
+)
+
+// SHA256SumsFilename returns the name of the SHA256SUMS file of a provider
+// version in its mirror directory
+func SHA256SumsFilename(name, version string) string {
+	return fmt.Sprintf("terraform-provider-%s_%s_SHA256SUMS", name, version)
+}
+
+// writeSignatures signs the checksums of every archive of a version with the
+// organization key, and keeps the upstream SHA256SUMS the downloaded archives
+// were verified against for audit
+func writeSignatures(
+	providerDir string,
+	pk providerKey,
+	lv LockFileVersion,
+	downloads []downloader.DownloadResult,
+	signer signing.Signer,
+) error {
+	version := lv.Version
+	sumsPath := filepath.Join(providerDir, SHA256SumsFilename(pk.name, version))
+
+	if upstream := upstreamChecksums(downloads); upstream != nil {
+		files := map[string][]byte{
+			sumsPath + UpstreamSuffix:                   upstream.SHA256Sums,
+			sumsPath + UpstreamSuffix + SignatureSuffix: upstream.Signature,
+			sumsPath + UpstreamSuffix + PublicKeySuffix: []byte(upstream.PublicKeys),
+		}
+		for path, data := range files {
+			if err := writeFile(path, data); err != nil {
+				return fmt.Errorf("writing upstream checksums: %w", err)
+			}
+		}
+	}
+
+	if signer == nil {
+		return nil
+	}
+
+	checksums := make(map[string]string, len(lv.Platforms))
+	for _, p := range lv.Platforms {
+		if p.Filename != "" && p.SHA256 != "" {
+			checksums[p.Filename] = p.SHA256
+		}
+	}
+	sums := signing.SHA256Sums(checksums)
+	sig, err := signer.Sign(sums)
+	if err != nil {
+		return fmt.Errorf("signing SHA256SUMS of %s: %w", version, err)
+	}
+
+	if err := writeFile(sumsPath, sums); err != nil {
+		return fmt.Errorf("writing SHA256SUMS: %w", err)
+	}
+	if err := writeFile(sumsPath+SignatureSuffix, sig); err != nil {
+		return fmt.Errorf("writing SHA256SUMS signature: %w", err)
+	}
+	return nil
+}
+
+// IsSigned reports whether the mirror in dir was written with a signing key
+func IsSigned(dir string) bool {
+	for _, name := range []string{SigningKeyFileName, LockSignatureFileName} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// writeLockFile writes mirror.lock to dir and, with a signer, its detached
+// signature. A signature left from an earlier signed write is removed.
+func writeLockFile(dir string, lockFile *LockFile, signer signing.Signer) error {
+	data, err := json.MarshalIndent(lockFile, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding lock file: %w", err)
+	}
+	data = append(data, '\n')
+
+	sigPath := filepath.Join(dir, LockSignatureFileName)
+	var sig []byte
+	if signer != nil {
+		if sig, err = signer.Sign(data); err != nil {
+			return fmt.Errorf("signing lock file: %w", err)
+		}
+	} else if err := os.Remove(sigPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing lock file signature: %w", err)
+	}
+
+	if err := writeFile(filepath.Join(dir, LockFileName), data); err != nil {
+		return fmt.Errorf("writing lock file: %w", err)
+	}
+	if sig != nil {
+		if err := writeFile(sigPath, sig); err != nil {
+			return fmt.Errorf("writing lock file signature: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeSigningKey writes the public key of the organization signing key to
+// the mirror root
+func writeSigningKey(dir string, signer signing.Signer) error {
+	if signer == nil {
+		return nil
+	}
+	if err := writeFile(filepath.Join(dir, SigningKeyFileName), []byte(signer.PublicKey())); err != nil {
+		return fmt.Errorf("writing signing key: %w", err)
+	}
+	return nil
+}
+
+// upstreamChecksums returns the upstream SHA256SUMS of a version's downloads
+func upstreamChecksums(downloads []downloader.DownloadResult) *downloader.UpstreamChecksums {
+	for _, dl := range downloads {
+		if dl.Upstream != nil {
+			return dl.Upstream
+		}
+	}
+	return nil
+}
+
+// writeFile writes a file, replacing it atomically
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,184 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/downloader"
+)
+
+// fakeSigner signs by prefixing data with its key ID
+type fakeSigner struct{}
+
+func (fakeSigner) KeyID() string     { return "0123456789ABCDEF" }
+func (fakeSigner) PublicKey() string { return "public key\n" }
+func (fakeSigner) Sign(data []byte) ([]byte, error) {
+	return append([]byte("signed by 0123456789ABCDEF: "), data...), nil
+}
+
+// --- Signature tests ---
+
+func TestWrite_SignsChecksums(t *testing.T) {
+	tmpDir := t.TempDir()
+	result := nullResult(t, tmpDir, "3.2.3")
+	result.Upstream = &downloader.UpstreamChecksums{
+		SHA256Sums: []byte("sha-3.2.3  " + result.Filename + "\n"),
+		Signature:  []byte("upstream signature"),
+		PublicKeys: "upstream key\n",
+		KeyID:      "FEDCBA9876543210",
+	}
+
+	outputDir := filepath.Join(tmpDir, "mirror")
+	if err := NewWriter(outputDir).WithSigner(fakeSigner{}).Write(context.Background(), []downloader.DownloadResult{result}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	sumsPath := filepath.Join(outputDir, "registry.terraform.io", "hashicorp", "null", SHA256SumsFilename("null", "3.2.3"))
+	wantSums := "sha-3.2.3  terraform-provider-null_3.2.3_linux_amd64.zip\n"
+	want := map[string]string{
+		sumsPath:                   wantSums,
+		sumsPath + SignatureSuffix: "signed by 0123456789ABCDEF: " + wantSums,
+		sumsPath + UpstreamSuffix:  wantSums,
+		sumsPath + UpstreamSuffix + SignatureSuffix:  "upstream signature",
+		sumsPath + UpstreamSuffix + PublicKeySuffix:  "upstream key\n",
+		filepath.Join(outputDir, SigningKeyFileName): "public key\n",
+	}
+	for path, content := range want {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("expected %s: %v", filepath.Base(path), err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", filepath.Base(path), data, content)
+		}
+	}
+
+	lockFile, err := ReadLockFile(filepath.Join(outputDir, LockFileName))
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	lv := lockFile.Providers[0].Versions[0]
+	if lv.SigningKeyID != "0123456789ABCDEF" || lv.UpstreamKeyID != "FEDCBA9876543210" {
+		t.Errorf("unexpected key IDs in lock file: %q, %q", lv.SigningKeyID, lv.UpstreamKeyID)
+	}
+
+	lockData, _ := os.ReadFile(filepath.Join(outputDir, LockFileName))
+	lockSig, err := os.ReadFile(filepath.Join(outputDir, LockSignatureFileName))
+	if err != nil {
+		t.Fatalf("expected a lock file signature: %v", err)
+	}
+	if string(lockSig) != "signed by 0123456789ABCDEF: "+string(lockData) {
+		t.Errorf("lock file signature does not cover mirror.lock: %q", lockSig)
+	}
+}
+
+func TestAdd_SignsLockFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputDir := filepath.Join(tmpDir, "mirror")
+	sigPath := filepath.Join(outputDir, LockSignatureFileName)
+
+	signed := NewWriter(outputDir).WithSigner(fakeSigner{})
+	if err := signed.Add(context.Background(), []downloader.DownloadResult{nullResult(t, tmpDir, "3.2.3")}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	lockData, _ := os.ReadFile(filepath.Join(outputDir, LockFileName))
+	lockSig, err := os.ReadFile(sigPath)
+	if err != nil || string(lockSig) != "signed by 0123456789ABCDEF: "+string(lockData) {
+		t.Fatalf("expected the merged lock file to be signed, got %q, %v", lockSig, err)
+	}
+
+	// An unsigned change must not leave a stale signature behind
+	if err := NewWriter(outputDir).Add(context.Background(), []downloader.DownloadResult{nullResult(t, tmpDir, "3.2.4")}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := os.Stat(sigPath); !os.IsNotExist(err) {
+		t.Errorf("expected the lock file signature to be removed, got %v", err)
+	}
+}
+
+func TestAdd_SignsEveryPlatformOfVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputDir := filepath.Join(tmpDir, "mirror")
+	w := NewWriter(outputDir).WithSigner(fakeSigner{})
+	ctx := context.Background()
+
+	linux := nullResult(t, tmpDir, "3.2.3")
+	darwin := nullResult(t, tmpDir, "3.2.3")
+	darwin.Task.Platform, darwin.Task.OS, darwin.Task.Arch = "darwin_arm64", "darwin", "arm64"
+	darwin.Filename = "terraform-provider-null_3.2.3_darwin_arm64.zip"
+	darwin.SHA256Sum = "sha-darwin"
+
+	if err := w.Add(ctx, []downloader.DownloadResult{linux}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := w.Add(ctx, []downloader.DownloadResult{darwin}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	providerDir := filepath.Join(outputDir, "registry.terraform.io", "hashicorp", "null")
+	sums, err := os.ReadFile(filepath.Join(providerDir, SHA256SumsFilename("null", "3.2.3")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSums := "sha-darwin  terraform-provider-null_3.2.3_darwin_arm64.zip\n" +
+		"sha-3.2.3  terraform-provider-null_3.2.3_linux_amd64.zip\n"
+	if string(sums) != wantSums {
+		t.Errorf("SHA256SUMS = %q, want %q", sums, wantSums)
+	}
+	sig, _ := os.ReadFile(filepath.Join(providerDir, SHA256SumsFilename("null", "3.2.3")+SignatureSuffix))
+	if string(sig) != "signed by 0123456789ABCDEF: "+wantSums {
+		t.Errorf("signature does not cover the merged checksums: %q", sig)
+	}
+
+	var versionMeta VersionJSON
+	data, err := os.ReadFile(filepath.Join(providerDir, "3.2.3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &versionMeta); err != nil {
+		t.Fatal(err)
+	}
+	if len(versionMeta.Archives) != 2 {
+		t.Errorf("expected both platforms in 3.2.3.json, got %v", versionMeta.Archives)
+	}
+
+	lockFile, err := ReadLockFile(filepath.Join(outputDir, LockFileName))
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	if got := lockFile.Providers[0].Versions[0].Platforms; len(got) != 2 {
+		t.Errorf("expected both platforms in the lock file, got %+v", got)
+	}
+}
+
+func TestWrite_UnsignedWithoutSigner(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputDir := filepath.Join(tmpDir, "mirror")
+	if err := NewWriter(outputDir).Write(context.Background(), []downloader.DownloadResult{nullResult(t, tmpDir, "3.2.3")}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	providerDir := filepath.Join(outputDir, "registry.terraform.io", "hashicorp", "null")
+	for _, path := range []string{
+		filepath.Join(providerDir, SHA256SumsFilename("null", "3.2.3")),
+		filepath.Join(providerDir, SHA256SumsFilename("null", "3.2.3")+UpstreamSuffix),
+		filepath.Join(outputDir, SigningKeyFileName),
+		filepath.Join(outputDir, LockSignatureFileName),
+	} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected no %s, got %v", filepath.Base(path), err)
+		}
+	}
+
+	lockFile, err := ReadLockFile(filepath.Join(outputDir, LockFileName))
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	if lv := lockFile.Providers[0].Versions[0]; lv.SigningKeyID != "" || lv.UpstreamKeyID != "" {
+		t.Errorf("expected no key IDs, got %q, %q", lv.SigningKeyID, lv.UpstreamKeyID)
+	}
+}
@@ -3,11 +3,14 @@ package mirror
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -15,12 +18,15 @@ import (
 	"golang.org/x/mod/sumdb/dirhash"
 
 	"github.com/petroprotsakh/go-provider-mirror/internal/downloader"
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
 )
 
 // Writer writes provider mirror filesystem layout
 type Writer struct {
 	outputDir  string
 	stagingDir string
+	signer     signing.Signer
 }
 
 // NewWriter creates a new mirror writer
@@ -32,6 +38,13 @@ func NewWriter(outputDir string) *Writer {
 	}
 }
 
+// WithSigner makes the writer sign each version's SHA256SUMS with an
+// organization key
+func (w *Writer) WithSigner(signer signing.Signer) *Writer {
+	w.signer = signer
+	return w
+}
+
 // IndexJSON represents the index.json file listing available versions.
 type IndexJSON struct {
 	Versions map[string]struct{} `json:"versions"`
@@ -76,55 +89,26 @@ func (w *Writer) Write(ctx context.Context, results []downloader.DownloadResult)
 		return err
 	}
 
-	// Group results by provider and version
-	type providerKey struct {
-		hostname  string
-		namespace string
-		name      string
-	}
-
-	providerVersions := make(map[providerKey]map[string][]downloader.DownloadResult)
-
-	for _, r := range results {
-		pk := providerKey{
-			hostname:  r.Task.Provider.Source.Hostname,
-			namespace: r.Task.Provider.Source.Namespace,
-			name:      r.Task.Provider.Source.Name,
-		}
-
-		if providerVersions[pk] == nil {
-			providerVersions[pk] = make(map[string][]downloader.DownloadResult)
-		}
-		providerVersions[pk][r.Task.Version.Version] = append(
-			providerVersions[pk][r.Task.Version.Version],
-			r,
-		)
-	}
+	lockFile := buildLockFile(results, h1Hashes, w.signingKeyID())
 
 	// Write each provider
-	for pk, versions := range providerVersions {
+	for pk, versions := range groupResults(results) {
 		// Check for cancellation between providers
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
 
-		if err := w.writeProvider(
-			pk.hostname,
-			pk.namespace,
-			pk.name,
-			versions,
-			h1Hashes,
-		); err != nil {
-			return fmt.Errorf(
-				"writing provider %s/%s/%s: %w",
-				pk.hostname, pk.namespace, pk.name, err,
-			)
+		if err := writeProvider(w.stagingDir, pk, versions, h1Hashes, lockFile, w.signer); err != nil {
+			return fmt.Errorf("writing provider %s: %w", pk, err)
 		}
 	}
+	if err := writeSigningKey(w.stagingDir, w.signer); err != nil {
+		return err
+	}
 
 	// Write lock file
-	if err := w.writeLockFile(results, h1Hashes); err != nil {
-		return fmt.Errorf("writing lock file: %w", err)
+	if err := writeLockFile(w.stagingDir, lockFile, w.signer); err != nil {
+		return err
 	}
 
 	// Atomic swap: remove old output, rename staging to output
@@ -139,13 +123,103 @@ func (w *Writer) Write(ctx context.Context, results []downloader.DownloadResult)
 	return nil
 }
 
-// writeProvider writes a single provider to the staging directory.
-func (w *Writer) writeProvider(
-	hostname, namespace, name string,
+// Add merges download results into the existing mirror in place, keeping the
+// versions already mirrored. Files are replaced atomically, so the mirror can
+// be served while it grows.
+func (w *Writer) Add(ctx context.Context, results []downloader.DownloadResult) error {
+	for _, r := range results {
+		if r.Error != nil {
+			return fmt.Errorf(
+				"cannot add to mirror: download failed for %s: %w",
+				r.Task.Provider.Source.String(), r.Error,
+			)
+		}
+	}
+
+	h1Hashes, err := computeHashesParallel(ctx, results)
+	if err != nil {
+		return err
+	}
+
+	// Merge first, so the checksums signed per version cover platforms
+	// mirrored earlier
+	lockPath := filepath.Join(w.outputDir, LockFileName)
+	lockFile := buildLockFile(results, h1Hashes, w.signingKeyID())
+	if existing, err := ReadLockFile(lockPath); err == nil {
+		lockFile = mergeLockFiles(existing, lockFile)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	for pk, versions := range groupResults(results) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := writeProvider(w.outputDir, pk, versions, h1Hashes, lockFile, w.signer); err != nil {
+			return fmt.Errorf("writing provider %s: %w", pk, err)
+		}
+	}
+	if err := writeSigningKey(w.outputDir, w.signer); err != nil {
+		return err
+	}
+
+	return writeLockFile(w.outputDir, lockFile, w.signer)
+}
+
+// signingKeyID returns the ID of the organization signing key, if any
+func (w *Writer) signingKeyID() string {
+	if w.signer == nil {
+		return ""
+	}
+	return w.signer.KeyID()
+}
+
+// providerKey identifies a provider by its mirror address
+type providerKey struct {
+	hostname  string
+	namespace string
+	name      string
+}
+
+func (k providerKey) String() string {
+	return k.hostname + "/" + k.namespace + "/" + k.name
+}
+
+// groupResults groups download results by mirror address and version
+func groupResults(results []downloader.DownloadResult) map[providerKey]map[string][]downloader.DownloadResult {
+	providerVersions := make(map[providerKey]map[string][]downloader.DownloadResult)
+
+	for _, r := range results {
+		address := r.Task.Provider.Address()
+		pk := providerKey{
+			hostname:  address.Hostname,
+			namespace: address.Namespace,
+			name:      address.Name,
+		}
+
+		if providerVersions[pk] == nil {
+			providerVersions[pk] = make(map[string][]downloader.DownloadResult)
+		}
+		providerVersions[pk][r.Task.Version.Version] = append(
+			providerVersions[pk][r.Task.Version.Version],
+			r,
+		)
+	}
+	return providerVersions
+}
+
+// writeProvider writes a single provider under dir. Versions already listed
+// in an existing index.json, and platforms already listed in an existing
+// <version>.json, are kept. lockFile lists every platform of the versions.
+func writeProvider(
+	dir string,
+	pk providerKey,
 	versions map[string][]downloader.DownloadResult,
 	h1Hashes map[string]string,
+	lockFile *LockFile,
+	signer signing.Signer,
 ) error {
-	providerDir := filepath.Join(w.stagingDir, hostname, namespace, name)
+	providerDir := filepath.Join(dir, pk.hostname, pk.namespace, pk.name)
 
 	if err := os.MkdirAll(providerDir, 0o755); err != nil {
 		return fmt.Errorf("creating provider directory: %w", err)
@@ -155,6 +229,15 @@ func (w *Writer) writeProvider(
 	index := IndexJSON{
 		Versions: make(map[string]struct{}),
 	}
+	indexPath := filepath.Join(providerDir, "index.json")
+	if data, err := os.ReadFile(indexPath); err == nil {
+		if err := json.Unmarshal(data, &index); err != nil {
+			return fmt.Errorf("reading index.json: %w", err)
+		}
+		if index.Versions == nil {
+			index.Versions = make(map[string]struct{})
+		}
+	}
 
 	for version, downloads := range versions {
 		// Add to index
@@ -164,6 +247,15 @@ func (w *Writer) writeProvider(
 		versionMeta := VersionJSON{
 			Archives: make(map[string]ArchiveInfo),
 		}
+		versionPath := filepath.Join(providerDir, version+".json")
+		if data, err := os.ReadFile(versionPath); err == nil {
+			if err := json.Unmarshal(data, &versionMeta); err != nil {
+				return fmt.Errorf("reading %s.json: %w", version, err)
+			}
+			if versionMeta.Archives == nil {
+				versionMeta.Archives = make(map[string]ArchiveInfo)
+			}
+		}
 
 		for _, dl := range downloads {
 			platform := fmt.Sprintf("%s_%s", dl.Task.OS, dl.Task.Arch)
@@ -181,32 +273,37 @@ func (w *Writer) writeProvider(
 			}
 		}
 
-		// Write <version>.json
-		versionPath := filepath.Join(providerDir, version+".json")
-		versionData, err := json.MarshalIndent(versionMeta, "", "  ")
-		if err != nil {
-			return fmt.Errorf("marshaling %s.json: %w", version, err)
+		lv := lockFile.version(pk, version)
+		if lv == nil {
+			return fmt.Errorf("version %s is missing from the lock file", version)
+		}
+		if err := writeSignatures(providerDir, pk, *lv, downloads, signer); err != nil {
+			return err
 		}
 
-		if err := os.WriteFile(versionPath, append(versionData, '\n'), 0o644); err != nil {
+		// Write <version>.json
+		if err := writeJSON(versionPath, versionMeta); err != nil {
 			return fmt.Errorf("writing %s.json: %w", version, err)
 		}
 	}
 
 	// Write index.json
-	indexPath := filepath.Join(providerDir, "index.json")
-	indexData, err := json.MarshalIndent(index, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshaling index.json: %w", err)
-	}
-
-	if err := os.WriteFile(indexPath, append(indexData, '\n'), 0o644); err != nil {
+	if err := writeJSON(indexPath, index); err != nil {
 		return fmt.Errorf("writing index.json: %w", err)
 	}
 
 	return nil
 }
 
+// writeJSON writes indented JSON to a file, replacing it atomically
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, append(data, '\n'))
+}
+
 // ComputePackageHash computes the h1: hash from a provider ZIP file content.
 func ComputePackageHash(zipPath string) (string, error) {
 	hash, err := dirhash.HashZip(zipPath, dirhash.Hash1)
@@ -302,9 +399,15 @@ type LockFileProvider struct {
 
 // LockFileVersion represents a version in the lock file
 type LockFileVersion struct {
-	Version         string             `json:"version"`
-	ManifestSources []string           `json:"manifest_sources"` // original source specs from manifest
-	Platforms       []LockFilePlatform `json:"platforms"`
+	Version          string             `json:"version"`
+	ManifestSources  []string           `json:"manifest_sources"` // original source specs from manifest
+	Platforms        []LockFilePlatform `json:"platforms"`
+	MissingPlatforms []string           `json:"missing_platforms,omitempty"` // requested but not published upstream
+	FallbackFrom     []string           `json:"fallback_from,omitempty"`     // newer versions lacking requested platforms
+	Origin           string             `json:"origin,omitempty"`            // upstream address, if published under another one
+	Protocols        []string           `json:"protocols,omitempty"`         // plugin protocol versions published upstream
+	SigningKeyID     string             `json:"signing_key_id,omitempty"`    // organization key signing SHA256SUMS
+	UpstreamKeyID    string             `json:"upstream_key_id,omitempty"`   // registry key that signed the upstream SHA256SUMS
 }
 
 // LockFilePlatform represents a platform in the lock file
@@ -316,26 +419,22 @@ type LockFilePlatform struct {
 	H1       string `json:"h1"`     // content hash (computed from package contents)
 }
 
-// writeLockFile writes the mirror.lock file
-func (w *Writer) writeLockFile(
+// buildLockFile builds the mirror.lock contents for download results
+func buildLockFile(
 	results []downloader.DownloadResult,
 	h1Hashes map[string]string,
-) error {
+	signingKeyID string,
+) *LockFile {
 	// Group results by provider
-	type providerKey struct {
-		hostname  string
-		namespace string
-		name      string
-	}
-
 	providerMap := make(map[providerKey]*LockFileProvider)
 	versionMap := make(map[providerKey]map[string]*LockFileVersion) // provider -> version -> data
 
 	for _, r := range results {
+		address := r.Task.Provider.Address()
 		pk := providerKey{
-			hostname:  r.Task.Provider.Source.Hostname,
-			namespace: r.Task.Provider.Source.Namespace,
-			name:      r.Task.Provider.Source.Name,
+			hostname:  address.Hostname,
+			namespace: address.Namespace,
+			name:      address.Name,
 		}
 
 		if providerMap[pk] == nil {
@@ -350,9 +449,20 @@ func (w *Writer) writeLockFile(
 		ver := r.Task.Version.Version
 		if versionMap[pk][ver] == nil {
 			versionMap[pk][ver] = &LockFileVersion{
-				Version:         ver,
-				ManifestSources: r.Task.Version.ManifestSources,
+				Version:          ver,
+				ManifestSources:  r.Task.Version.ManifestSources,
+				MissingPlatforms: r.Task.Version.MissingPlatforms,
+				FallbackFrom:     r.Task.Version.FallbackFrom,
+				Protocols:        r.Task.Version.Protocols,
+				SigningKeyID:     signingKeyID,
 			}
+			if r.Task.Provider.PublishAs != (manifest.ProviderSource{}) {
+				versionMap[pk][ver].Origin = r.Task.Provider.Source.String()
+			}
+		}
+
+		if r.Upstream != nil {
+			versionMap[pk][ver].UpstreamKeyID = r.Upstream.KeyID
 		}
 
 		h1Hash := h1Hashes[r.CachePath]
@@ -370,7 +480,7 @@ func (w *Writer) writeLockFile(
 	}
 
 	// Build lock file with stable ordering
-	lockFile := LockFile{
+	lockFile := &LockFile{
 		Version:     1,
 		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
 	}
@@ -419,21 +529,98 @@ func (w *Writer) writeLockFile(
 		lockFile.Providers = append(lockFile.Providers, *provider)
 	}
 
-	// Write lock file
-	lockData, err := json.MarshalIndent(lockFile, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshaling lock file: %w", err)
+	return lockFile
+}
+
+// mergeLockFiles adds the providers and versions of added to existing.
+// Versions in both are taken from added, keeping the platforms only existing
+// lists.
+func mergeLockFiles(existing, added *LockFile) *LockFile {
+	merged := *added
+	merged.Providers = nil
+
+	type key struct{ hostname, namespace, name string }
+	index := make(map[key]int)
+	for _, providers := range [][]LockFileProvider{existing.Providers, added.Providers} {
+		for _, p := range providers {
+			k := key{p.Hostname, p.Namespace, p.Name}
+			i, ok := index[k]
+			if !ok {
+				index[k] = len(merged.Providers)
+				merged.Providers = append(merged.Providers, LockFileProvider{
+					Hostname:  p.Hostname,
+					Namespace: p.Namespace,
+					Name:      p.Name,
+				})
+				i = index[k]
+			}
+			merged.Providers[i].Versions = mergeVersions(merged.Providers[i].Versions, p.Versions)
+		}
+	}
+
+	sort.Slice(merged.Providers, func(i, j int) bool {
+		a, b := merged.Providers[i], merged.Providers[j]
+		if a.Hostname != b.Hostname {
+			return a.Hostname < b.Hostname
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return &merged
+}
+
+// mergeVersions replaces or adds versions, keeping them sorted. A replaced
+// version keeps the platforms the new one does not list.
+func mergeVersions(versions, added []LockFileVersion) []LockFileVersion {
+	result := append([]LockFileVersion{}, versions...)
+	for _, v := range added {
+		i := slices.IndexFunc(result, func(existing LockFileVersion) bool { return existing.Version == v.Version })
+		if i >= 0 {
+			v.Platforms = mergePlatforms(result[i].Platforms, v.Platforms)
+			result[i] = v
+		} else {
+			result = append(result, v)
+		}
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result
+}
 
-	lockPath := filepath.Join(w.stagingDir, "mirror.lock")
-	if err := os.WriteFile(lockPath, append(lockData, '\n'), 0o644); err != nil {
-		return fmt.Errorf("writing lock file: %w", err)
+// mergePlatforms replaces or adds platforms, keeping them sorted
+func mergePlatforms(platforms, added []LockFilePlatform) []LockFilePlatform {
+	result := append([]LockFilePlatform{}, added...)
+	for _, p := range platforms {
+		if !slices.ContainsFunc(result, func(a LockFilePlatform) bool { return a.OS == p.OS && a.Arch == p.Arch }) {
+			result = append(result, p)
+		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].OS != result[j].OS {
+			return result[i].OS < result[j].OS
+		}
+		return result[i].Arch < result[j].Arch
+	})
+	return result
+}
 
+// version returns the lock file entry of a provider version, or nil
+func (l *LockFile) version(pk providerKey, version string) *LockFileVersion {
+	for i, p := range l.Providers {
+		if p.Hostname != pk.hostname || p.Namespace != pk.namespace || p.Name != pk.name {
+			continue
+		}
+		for j, v := range p.Versions {
+			if v.Version == version {
+				return &l.Providers[i].Versions[j]
+			}
+		}
+	}
 	return nil
 }
 
-// copyFile copies a file from src to dst
+// copyFile copies a file from src to dst, replacing dst atomically
 func copyFile(src, dst string) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
@@ -441,7 +628,8 @@ func copyFile(src, dst string) error {
 	}
 	defer srcFile.Close() //nolint:errcheck
 
-	dstFile, err := os.Create(dst)
+	tmp := dst + ".tmp"
+	dstFile, err := os.Create(tmp)
 	if err != nil {
 		return err
 	}
@@ -451,5 +639,8 @@ func copyFile(src, dst string) error {
 		return err
 	}
 
-	return dstFile.Close()
+	if err := dstFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
 }
@@ -10,6 +10,8 @@ import (
 	"testing"
 
 	"github.com/petroprotsakh/go-provider-mirror/internal/downloader"
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+	"github.com/petroprotsakh/go-provider-mirror/internal/resolver"
 )
 
 // --- NewWriter tests ---
@@ -550,3 +552,209 @@ func createTestZip(path string, files map[string]string) error {
 
 	return nil
 }
+
+func TestLockFileVersion_PlatformPolicyFieldsOmittedWhenEmpty(t *testing.T) {
+	data, err := json.Marshal(LockFileVersion{Version: "3.2.4"})
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	if strings.Contains(string(data), "missing_platforms") || strings.Contains(string(data), "fallback_from") {
+		t.Errorf("expected empty policy fields to be omitted, got %s", data)
+	}
+}
+
+// --- Write tests ---
+
+func TestWrite_RecordsPlatformPolicyOutcomes(t *testing.T) {
+	tmpDir := t.TempDir()
+	zipPath := filepath.Join(tmpDir, "terraform-provider-null_3.2.3_linux_amd64.zip")
+	if err := createTestZip(zipPath, map[string]string{"terraform-provider-null": "binary"}); err != nil {
+		t.Fatalf("failed to create test zip: %v", err)
+	}
+
+	provider := resolver.ResolvedProvider{
+		Source: manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "null"},
+	}
+	version := resolver.ResolvedVersion{
+		Version:          "3.2.3",
+		Platforms:        []string{"linux_amd64"},
+		ManifestSources:  []string{"hashicorp/null"},
+		MissingPlatforms: []string{"windows_arm64"},
+		FallbackFrom:     []string{"3.2.4"},
+	}
+
+	results := []downloader.DownloadResult{
+		{
+			Task: downloader.DownloadTask{
+				Provider: provider,
+				Version:  version,
+				Platform: "linux_amd64",
+				OS:       "linux",
+				Arch:     "amd64",
+			},
+			CachePath: zipPath,
+			Filename:  filepath.Base(zipPath),
+			SHA256Sum: "abc123",
+		},
+	}
+
+	outputDir := filepath.Join(tmpDir, "mirror")
+	if err := NewWriter(outputDir).Write(context.Background(), results); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "mirror.lock"))
+	if err != nil {
+		t.Fatalf("failed to read lock file: %v", err)
+	}
+
+	var lockFile LockFile
+	if err := json.Unmarshal(data, &lockFile); err != nil {
+		t.Fatalf("invalid lock file: %v", err)
+	}
+
+	lv := lockFile.Providers[0].Versions[0]
+	if len(lv.MissingPlatforms) != 1 || lv.MissingPlatforms[0] != "windows_arm64" {
+		t.Errorf("expected missing_platforms [windows_arm64], got %v", lv.MissingPlatforms)
+	}
+	if len(lv.FallbackFrom) != 1 || lv.FallbackFrom[0] != "3.2.4" {
+		t.Errorf("expected fallback_from [3.2.4], got %v", lv.FallbackFrom)
+	}
+}
+
+func TestWrite_PublishAs(t *testing.T) {
+	tmpDir := t.TempDir()
+	zipPath := filepath.Join(tmpDir, "terraform-provider-null_3.2.3_linux_amd64.zip")
+	if err := createTestZip(zipPath, map[string]string{"terraform-provider-null": "binary"}); err != nil {
+		t.Fatalf("failed to create test zip: %v", err)
+	}
+
+	provider := resolver.ResolvedProvider{
+		Source:    manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "null"},
+		PublishAs: manifest.ProviderSource{Hostname: "registry.corp.example", Namespace: "hashicorp", Name: "null"},
+	}
+	results := []downloader.DownloadResult{
+		{
+			Task: downloader.DownloadTask{
+				Provider: provider,
+				Version:  resolver.ResolvedVersion{Version: "3.2.3", Platforms: []string{"linux_amd64"}},
+				Platform: "linux_amd64",
+				OS:       "linux",
+				Arch:     "amd64",
+			},
+			CachePath: zipPath,
+			Filename:  filepath.Base(zipPath),
+			SHA256Sum: "abc123",
+		},
+	}
+
+	outputDir := filepath.Join(tmpDir, "mirror")
+	if err := NewWriter(outputDir).Write(context.Background(), results); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "registry.corp.example", "hashicorp", "null", "3.2.3.json")); err != nil {
+		t.Errorf("expected provider under the published address: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "registry.terraform.io")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing under the upstream address, got %v", err)
+	}
+
+	lockFile, err := ReadLockFile(filepath.Join(outputDir, LockFileName))
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	lp := lockFile.Providers[0]
+	if lp.Hostname != "registry.corp.example" || lp.Versions[0].Origin != "registry.terraform.io/hashicorp/null" {
+		t.Errorf("unexpected lock entry: %s with origin %q", lp.Hostname, lp.Versions[0].Origin)
+	}
+}
+
+// --- Add tests ---
+
+// nullResult returns a download result for a null provider version
+func nullResult(t *testing.T, dir, version string) downloader.DownloadResult {
+	t.Helper()
+	zipPath := filepath.Join(dir, "terraform-provider-null_"+version+"_linux_amd64.zip")
+	if err := createTestZip(zipPath, map[string]string{"terraform-provider-null": version}); err != nil {
+		t.Fatalf("failed to create test zip: %v", err)
+	}
+	return downloader.DownloadResult{
+		Task: downloader.DownloadTask{
+			Provider: resolver.ResolvedProvider{
+				Source: manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "null"},
+			},
+			Version:  resolver.ResolvedVersion{Version: version, Platforms: []string{"linux_amd64"}},
+			Platform: "linux_amd64",
+			OS:       "linux",
+			Arch:     "amd64",
+		},
+		CachePath: zipPath,
+		Filename:  filepath.Base(zipPath),
+		SHA256Sum: "sha-" + version,
+	}
+}
+
+func TestAdd_MergesIntoExistingMirror(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputDir := filepath.Join(tmpDir, "mirror")
+	w := NewWriter(outputDir)
+	ctx := context.Background()
+
+	if err := w.Write(ctx, []downloader.DownloadResult{nullResult(t, tmpDir, "3.2.3")}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Add(ctx, []downloader.DownloadResult{nullResult(t, tmpDir, "3.2.4")}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	providerDir := filepath.Join(outputDir, "registry.terraform.io", "hashicorp", "null")
+	data, err := os.ReadFile(filepath.Join(providerDir, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index IndexJSON
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := index.Versions["3.2.3"]; !ok || len(index.Versions) != 2 {
+		t.Errorf("expected both versions in index.json, got %v", index.Versions)
+	}
+	for _, name := range []string{"3.2.3.json", "3.2.4.json", "terraform-provider-null_3.2.4_linux_amd64.zip"} {
+		if _, err := os.Stat(filepath.Join(providerDir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+
+	lockFile, err := ReadLockFile(filepath.Join(outputDir, LockFileName))
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	if len(lockFile.Providers) != 1 || len(lockFile.Providers[0].Versions) != 2 {
+		t.Fatalf("expected one provider with two versions, got %+v", lockFile.Providers)
+	}
+	if v := lockFile.Providers[0].Versions[1]; v.Version != "3.2.4" || v.Platforms[0].SHA256 != "sha-3.2.4" {
+		t.Errorf("unexpected added version: %+v", v)
+	}
+}
+
+func TestAdd_EmptyMirror(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputDir := filepath.Join(tmpDir, "mirror")
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewWriter(outputDir).Add(context.Background(), []downloader.DownloadResult{nullResult(t, tmpDir, "3.2.3")}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	lockFile, err := ReadLockFile(filepath.Join(outputDir, LockFileName))
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	if len(lockFile.Providers) != 1 || lockFile.Providers[0].Versions[0].Version != "3.2.3" {
+		t.Errorf("unexpected lock file: %+v", lockFile.Providers)
+	}
+}
@@ -0,0 +1,192 @@
+package outdated
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/httpclient"
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
+)
+
+// Checker compares mirrored provider versions with the upstream registries
+type Checker struct {
+	manifest *manifest.Manifest
+	client   *registry.Client
+}
+
+// New creates a new checker for one or more manifest files. A nil transport
+// uses the environment proxy settings and system CAs.
+func New(opts manifest.Options, transport *httpclient.Transport, manifestPaths ...string) (*Checker, error) {
+	m, err := manifest.LoadWithOptions(opts, manifestPaths...)
+	if err != nil {
+		return nil, fmt.Errorf("loading manifest: %w", err)
+	}
+
+	return &Checker{
+		manifest: m,
+		client:   registry.NewClient(&registry.Config{Transport: transport}),
+	}, nil
+}
+
+// Status summarizes how far a mirrored constraint is behind upstream
+type Status string
+
+const (
+	StatusUpToDate        Status = "up-to-date"       // the newest release is mirrored
+	StatusUpdateAvailable Status = "update-available" // a newer version satisfies the constraint
+	StatusBehind          Status = "behind"           // newer releases are outside the constraint
+	StatusNotMirrored     Status = "not-mirrored"     // nothing matching is in the lock file
+)
+
+// Report lists every provider constraint with its mirrored and upstream versions
+type Report struct {
+	Providers []Entry `json:"providers"`
+}
+
+// Entry describes one version constraint of a provider on one registry
+type Entry struct {
+	Source     string `json:"source"`            // hostname/namespace/name
+	Constraint string `json:"constraint"`        // constraint from the manifest
+	Current    string `json:"current,omitempty"` // newest matching version in the lock file
+	Wanted     string `json:"wanted,omitempty"`  // newest upstream version satisfying the constraint
+	Latest     string `json:"latest,omitempty"`  // newest upstream release overall
+	Status     Status `json:"status"`
+}
+
+// Outdated returns the number of entries that are not up to date
+func (r *Report) Outdated() int {
+	n := 0
+	for _, e := range r.Providers {
+		if e.Status != StatusUpToDate {
+			n++
+		}
+	}
+	return n
+}
+
+// Check looks up the upstream versions of every provider constraint in the
+// manifest. Current versions are taken from lockFile, which may be nil.
+func (c *Checker) Check(ctx context.Context, lockFile *mirror.LockFile) (*Report, error) {
+	expanded, err := c.manifest.GetExpandedProviders()
+	if err != nil {
+		return nil, fmt.Errorf("expanding providers: %w", err)
+	}
+
+	report := &Report{}
+	versionsCache := make(map[string][]*version.Version)
+	seen := make(map[string]bool)
+
+	for _, ep := range expanded {
+		source := ep.Source.String()
+
+		available, ok := versionsCache[source]
+		if !ok {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			pvs, err := c.client.GetVersions(ctx, ep.Source.Hostname, ep.Source.Namespace, ep.Source.Name)
+			if err != nil {
+				return nil, fmt.Errorf("fetching versions for %s: %w", source, err)
+			}
+			available = parseVersions(pvs)
+			versionsCache[source] = available
+		}
+
+		for _, constraintStr := range ep.Versions {
+			key := source + " " + constraintStr
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			constraint, err := version.NewConstraint(constraintStr)
+			if err != nil {
+				return nil, fmt.Errorf("parsing constraint %q: %w", constraintStr, err)
+			}
+
+			var current string
+			if lockFile != nil {
+				current, _ = lockFile.LockedVersion(ep.Source, constraint)
+			}
+
+			report.Providers = append(report.Providers, newEntry(source, constraintStr, constraint, current, available))
+		}
+	}
+
+	return report, nil
+}
+
+// newEntry compares the current version of a constraint with the available
+// versions, which must be sorted newest first
+func newEntry(
+	source, constraintStr string,
+	constraint version.Constraints,
+	current string,
+	available []*version.Version,
+) Entry {
+	e := Entry{
+		Source:     source,
+		Constraint: constraintStr,
+		Current:    current,
+	}
+
+	var wanted, latest *version.Version
+	for _, v := range available {
+		if wanted == nil && constraint.Check(v) {
+			wanted = v
+		}
+		// Pre-releases only count as the latest release if nothing else is published
+		if latest == nil && v.Prerelease() == "" {
+			latest = v
+		}
+	}
+	if latest == nil && len(available) > 0 {
+		latest = available[0]
+	}
+	if wanted != nil {
+		e.Wanted = wanted.Original()
+		if latest == nil || wanted.GreaterThan(latest) {
+			latest = wanted
+		}
+	}
+	if latest != nil {
+		e.Latest = latest.Original()
+	}
+
+	cur, err := version.NewVersion(current)
+	switch {
+	case current == "" || err != nil:
+		e.Status = StatusNotMirrored
+	case wanted != nil && wanted.GreaterThan(cur):
+		e.Status = StatusUpdateAvailable
+	case latest != nil && latest.GreaterThan(cur):
+		e.Status = StatusBehind
+	default:
+		e.Status = StatusUpToDate
+	}
+
+	return e
+}
+
+// parseVersions returns the registry versions, newest first.
+// Versions the registry reports in an unparsable format are ignored.
+func parseVersions(pvs *registry.ProviderVersions) []*version.Version {
+	var versions []*version.Version
+	for _, pv := range pvs.Versions {
+		if v, err := version.NewVersion(pv.Version); err == nil {
+			versions = append(versions, v)
+		}
+	}
+
+	sort.Slice(
+		versions, func(i, j int) bool {
+			return versions[i].GreaterThan(versions[j])
+		},
+	)
+	return versions
+}
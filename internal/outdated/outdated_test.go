@@ -0,0 +1,127 @@
+package outdated
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
+)
+
+func testVersions(versions ...string) []*version.Version {
+	pvs := &registry.ProviderVersions{}
+	for _, v := range versions {
+		pvs.Versions = append(pvs.Versions, registry.ProviderVersion{Version: v})
+	}
+	return parseVersions(pvs)
+}
+
+// --- parseVersions tests ---
+
+func TestParseVersions_SortedNewestFirst(t *testing.T) {
+	versions := testVersions("5.9.0", "5.10.0", "not-a-version", "4.67.0")
+
+	var got []string
+	for _, v := range versions {
+		got = append(got, v.Original())
+	}
+
+	want := []string{"5.10.0", "5.9.0", "4.67.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseVersions() = %v, want %v", got, want)
+	}
+}
+
+// --- newEntry tests ---
+
+func TestNewEntry(t *testing.T) {
+	available := testVersions("4.67.0", "5.9.0", "5.10.0", "6.0.0", "6.1.0-beta1")
+
+	tests := []struct {
+		name       string
+		constraint string
+		current    string
+		want       Entry
+	}{
+		{
+			name:       "up to date",
+			constraint: ">= 5.0",
+			current:    "6.0.0",
+			want:       Entry{Current: "6.0.0", Wanted: "6.0.0", Latest: "6.0.0", Status: StatusUpToDate},
+		},
+		{
+			name:       "update available",
+			constraint: "~> 5.0",
+			current:    "5.9.0",
+			want:       Entry{Current: "5.9.0", Wanted: "5.10.0", Latest: "6.0.0", Status: StatusUpdateAvailable},
+		},
+		{
+			name:       "behind latest",
+			constraint: "~> 5.0",
+			current:    "5.10.0",
+			want:       Entry{Current: "5.10.0", Wanted: "5.10.0", Latest: "6.0.0", Status: StatusBehind},
+		},
+		{
+			name:       "not mirrored",
+			constraint: "~> 4.0",
+			want:       Entry{Wanted: "4.67.0", Latest: "6.0.0", Status: StatusNotMirrored},
+		},
+		{
+			name:       "no matching upstream version",
+			constraint: "~> 3.0",
+			current:    "3.1.0",
+			want:       Entry{Current: "3.1.0", Latest: "6.0.0", Status: StatusBehind},
+		},
+		{
+			name:       "pre-release constraint",
+			constraint: "6.1.0-beta1",
+			current:    "6.1.0-beta1",
+			want:       Entry{Current: "6.1.0-beta1", Wanted: "6.1.0-beta1", Latest: "6.1.0-beta1", Status: StatusUpToDate},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				constraint, err := version.NewConstraint(tt.constraint)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := newEntry("registry.terraform.io/hashicorp/aws", tt.constraint, constraint, tt.current, available)
+
+				tt.want.Source = "registry.terraform.io/hashicorp/aws"
+				tt.want.Constraint = tt.constraint
+				if got != tt.want {
+					t.Errorf("newEntry() = %+v, want %+v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestNewEntry_OnlyPrereleases(t *testing.T) {
+	constraint, _ := version.NewConstraint(">= 0.1.0")
+	got := newEntry("example.com/acme/widget", ">= 0.1.0", constraint, "", testVersions("0.2.0-rc1", "0.1.0-rc1"))
+
+	if got.Latest != "0.2.0-rc1" || got.Wanted != "" {
+		t.Errorf("unexpected entry: %+v", got)
+	}
+}
+
+// --- Report tests ---
+
+func TestReport_Outdated(t *testing.T) {
+	r := &Report{
+		Providers: []Entry{
+			{Status: StatusUpToDate},
+			{Status: StatusBehind},
+			{Status: StatusNotMirrored},
+		},
+	}
+
+	if got := r.Outdated(); got != 2 {
+		t.Errorf("Outdated() = %d, want 2", got)
+	}
+}
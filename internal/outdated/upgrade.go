@@ -0,0 +1,250 @@
+package outdated
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+)
+
+// Policy limits how far upgrade may move a version constraint
+type Policy string
+
+const (
+	PolicyPatch Policy = "patch" // newer patch releases of the same minor version
+	PolicyMinor Policy = "minor" // newer minor and patch releases of the same major version
+	PolicyMajor Policy = "major" // any newer release
+)
+
+// ParsePolicy parses an upgrade policy name
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicyPatch, PolicyMinor, PolicyMajor:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid upgrade policy %q (must be patch, minor or major)", s)
+	}
+}
+
+// allows reports whether the policy permits moving from base to v
+func (p Policy) allows(base, v *version.Version) bool {
+	if !v.GreaterThan(base) {
+		return false
+	}
+	b, s := base.Segments(), v.Segments()
+	switch p {
+	case PolicyPatch:
+		return s[0] == b[0] && s[1] == b[1]
+	case PolicyMinor:
+		return s[0] == b[0]
+	default:
+		return true
+	}
+}
+
+// simpleConstraint matches the constraints upgrade can rewrite: an exact
+// version, optionally with "=", or a pessimistic "~>" constraint
+var simpleConstraint = regexp.MustCompile(`^(\s*(?:=|~>)?\s*)v?(\d+(?:\.\d+){0,2})(\s*)$`)
+
+// UpgradeConstraint returns constraint rewritten to allow the newest release
+// the policy permits. available must be sorted newest first. The constraint is
+// returned unchanged if it already allows that release or none is newer.
+// Pre-releases are never upgraded to.
+func UpgradeConstraint(constraintStr string, available []*version.Version, policy Policy) (string, error) {
+	constraint, err := version.NewConstraint(constraintStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q: %w", constraintStr, err)
+	}
+
+	// The policy is applied from the newest version the constraint allows today
+	var base *version.Version
+	for _, v := range available {
+		if constraint.Check(v) {
+			base = v
+			break
+		}
+	}
+
+	match := simpleConstraint.FindStringSubmatch(constraintStr)
+	if base == nil {
+		if match == nil {
+			return "", fmt.Errorf("no available version matches %q", constraintStr)
+		}
+		base, _ = version.NewVersion(match[2])
+	}
+
+	var target *version.Version
+	for _, v := range available {
+		if v.Prerelease() == "" && policy.allows(base, v) {
+			target = v
+			break
+		}
+	}
+	if target == nil || constraint.Check(target) {
+		return constraintStr, nil
+	}
+
+	if match == nil {
+		return "", fmt.Errorf(
+			"constraint %q cannot be upgraded automatically; %s is available", constraintStr, target.Original(),
+		)
+	}
+
+	// Keep the operator and the precision of the original constraint
+	precision := strings.Count(match[2], ".") + 1
+	if !strings.Contains(match[1], "~>") {
+		precision = 3
+	}
+	segments := target.Segments()
+	parts := make([]string, precision)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%d", segments[i])
+	}
+
+	return match[1] + strings.Join(parts, ".") + match[3], nil
+}
+
+// FileUpgrade holds the upgraded content of one manifest file
+type FileUpgrade struct {
+	Path    string
+	Before  []byte
+	Changes []manifest.VersionChange
+
+	editor *manifest.Editor
+}
+
+// After returns the upgraded manifest
+func (f *FileUpgrade) After() []byte {
+	return f.editor.Bytes()
+}
+
+// Save writes the upgraded manifest back to its file
+func (f *FileUpgrade) Save() error {
+	return f.editor.Save()
+}
+
+// UpgradeResult lists the manifest files with upgraded constraints
+type UpgradeResult struct {
+	Files    []*FileUpgrade
+	Warnings []string // constraints that could not be upgraded
+}
+
+// Upgrade rewrites the version constraints of the manifest files to allow
+// the newest releases the policy permits. If sources is not empty, only those
+// providers are upgraded. Upgrades are computed in memory; call Save on the
+// returned files to apply them.
+func (c *Checker) Upgrade(ctx context.Context, policy Policy, sources ...string) (*UpgradeResult, error) {
+	expanded, err := c.manifest.GetExpandedProviders()
+	if err != nil {
+		return nil, fmt.Errorf("expanding providers: %w", err)
+	}
+
+	// Registries each source specification expands to
+	registries := make(map[string][]manifest.ProviderSource)
+	for _, ep := range expanded {
+		key := strings.ToLower(ep.SourceSpec)
+		if !containsProvider(registries[key], ep.Source) {
+			registries[key] = append(registries[key], ep.Source)
+		}
+	}
+
+	selected := make(map[string]bool)
+	for _, s := range sources {
+		key := strings.ToLower(s)
+		if len(registries[key]) == 0 {
+			return nil, fmt.Errorf("provider %s is not in the manifest", s)
+		}
+		selected[key] = true
+	}
+
+	versionsCache := make(map[string][]*version.Version)
+	rewrite := func(source, constraint string) (string, error) {
+		key := strings.ToLower(source)
+		if len(selected) > 0 && !selected[key] {
+			return constraint, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		// A constraint is only moved to versions every registry publishes
+		var available []*version.Version
+		for i, src := range registries[key] {
+			versions, ok := versionsCache[src.String()]
+			if !ok {
+				pvs, err := c.client.GetVersions(ctx, src.Hostname, src.Namespace, src.Name)
+				if err != nil {
+					return "", fmt.Errorf("fetching versions for %s: %w", src.String(), err)
+				}
+				versions = parseVersions(pvs)
+				versionsCache[src.String()] = versions
+			}
+			if i == 0 {
+				available = versions
+			} else {
+				available = intersectVersions(available, versions)
+			}
+		}
+
+		return UpgradeConstraint(constraint, available, policy)
+	}
+
+	result := &UpgradeResult{}
+
+	for _, path := range c.manifest.Files {
+		if manifest.FormatOf(path) != manifest.FormatYAML {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: only YAML manifests can be upgraded", path))
+			continue
+		}
+
+		ed, err := manifest.OpenEditor(path)
+		if err != nil {
+			return nil, err
+		}
+		before := ed.Bytes()
+
+		changes, err := ed.RewriteVersions(rewrite)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %s", path, line))
+			}
+		}
+		if len(changes) > 0 {
+			result.Files = append(result.Files, &FileUpgrade{Path: path, Before: before, Changes: changes, editor: ed})
+		}
+	}
+
+	return result, nil
+}
+
+// containsProvider reports whether list contains src
+func containsProvider(list []manifest.ProviderSource, src manifest.ProviderSource) bool {
+	for _, s := range list {
+		if s == src {
+			return true
+		}
+	}
+	return false
+}
+
+// intersectVersions returns the versions of a that are also in b, keeping
+// the order of a
+func intersectVersions(a, b []*version.Version) []*version.Version {
+	var result []*version.Version
+	for _, v := range a {
+		for _, w := range b {
+			if v.Equal(w) {
+				result = append(result, v)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package outdated
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-version"
+)
+
+// --- ParsePolicy tests ---
+
+func TestParsePolicy(t *testing.T) {
+	for _, s := range []string{"patch", "minor", "major"} {
+		if p, err := ParsePolicy(s); err != nil || string(p) != s {
+			t.Errorf("ParsePolicy(%q) = %q, %v", s, p, err)
+		}
+	}
+	if _, err := ParsePolicy("latest"); err == nil {
+		t.Error("expected error for invalid policy")
+	}
+}
+
+// --- UpgradeConstraint tests ---
+
+func TestUpgradeConstraint(t *testing.T) {
+	available := testVersions("4.66.0", "4.67.0", "5.9.0", "5.9.2", "5.10.0", "6.0.0", "6.1.0", "7.0.0-beta1")
+
+	tests := []struct {
+		constraint string
+		policy     Policy
+		want       string
+	}{
+		// Exact versions
+		{"5.9.0", PolicyPatch, "5.9.2"},
+		{"5.9.0", PolicyMinor, "5.10.0"},
+		{"5.9.0", PolicyMajor, "6.1.0"},
+		{"= 4.66.0", PolicyMinor, "= 4.67.0"},
+		{"6.1.0", PolicyMajor, "6.1.0"},
+
+		// Pessimistic constraints keep their precision
+		{"~> 5.0", PolicyMinor, "~> 5.0"},
+		{"~> 5.0", PolicyMajor, "~> 6.1"},
+		{"~> 5.9.0", PolicyPatch, "~> 5.9.0"},
+		{"~> 5.9.0", PolicyMinor, "~> 5.10.0"},
+		{"~>4.66", PolicyMajor, "~>6.1"},
+		{"~> 5", PolicyMajor, "~> 5"},
+
+		// Ranges that already allow the target are left alone
+		{">= 5.0", PolicyMajor, ">= 5.0"},
+
+		// A pinned version no longer published is upgraded from its own value
+		{"5.8.0", PolicyMinor, "5.10.0"},
+	}
+
+	for _, tt := range tests {
+		got, err := UpgradeConstraint(tt.constraint, available, tt.policy)
+		if err != nil {
+			t.Errorf("UpgradeConstraint(%q, %s) error = %v", tt.constraint, tt.policy, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("UpgradeConstraint(%q, %s) = %q, want %q", tt.constraint, tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestUpgradeConstraint_Errors(t *testing.T) {
+	available := testVersions("5.9.0", "6.0.0")
+
+	tests := []struct {
+		constraint string
+		wantErr    bool
+	}{
+		{">= 5.0, < 6.0", true},
+		{"not a constraint", true},
+		{">= 8.0", true},
+	}
+
+	for _, tt := range tests {
+		_, err := UpgradeConstraint(tt.constraint, available, PolicyMajor)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("UpgradeConstraint(%q) error = %v, wantErr %v", tt.constraint, err, tt.wantErr)
+		}
+	}
+}
+
+// --- intersectVersions tests ---
+
+func TestIntersectVersions(t *testing.T) {
+	got := intersectVersions(testVersions("5.10.0", "5.9.0", "5.8.0"), testVersions("5.9.0", "5.8.0", "5.7.0"))
+
+	var names []string
+	for _, v := range got {
+		names = append(names, v.Original())
+	}
+	if len(names) != 2 || names[0] != "5.9.0" || names[1] != "5.8.0" {
+		t.Errorf("intersectVersions() = %v", names)
+	}
+
+	if intersectVersions(nil, []*version.Version{version.Must(version.NewVersion("1.0.0"))}) != nil {
+		t.Error("expected empty intersection")
+	}
+}
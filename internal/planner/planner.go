@@ -3,8 +3,11 @@ package planner
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"github.com/petroprotsakh/go-provider-mirror/internal/httpclient"
 	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
 	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
 	"github.com/petroprotsakh/go-provider-mirror/internal/resolver"
 )
@@ -15,37 +18,123 @@ type Planner struct {
 	client   *registry.Client
 }
 
-// New creates a new planner
-func New(manifestPath string) (*Planner, error) {
-	m, err := manifest.Load(manifestPath)
+// New creates a new planner for one or more manifest files. A nil transport
+// uses the environment proxy settings and system CAs.
+func New(opts manifest.Options, transport *httpclient.Transport, manifestPaths ...string) (*Planner, error) {
+	m, err := manifest.LoadWithOptions(opts, manifestPaths...)
 	if err != nil {
 		return nil, fmt.Errorf("loading manifest: %w", err)
 	}
+	hostAuth, err := m.HostAuth()
+	if err != nil {
+		return nil, err
+	}
 
 	return &Planner{
 		manifest: m,
-		client:   registry.NewClient(nil), // use defaults
+		client:   registry.NewClient(&registry.Config{HostAuth: hostAuth, Transport: transport}),
 	}, nil
 }
 
 // Plan represents a build plan
 type Plan struct {
-	Providers      []PlannedProvider
-	TotalVersions  int
-	TotalDownloads int
+	Providers      []PlannedProvider   `json:"providers"`
+	TotalVersions  int                 `json:"total_versions"`
+	TotalDownloads int                 `json:"total_downloads"`
+	Decisions      []resolver.Decision `json:"-"`     // how each manifest constraint was resolved
+	Trace          []TraceEntry        `json:"trace"` // Decisions in report form
+	Warnings       []string            `json:"warnings,omitempty"`
 }
 
 // PlannedProvider represents a provider in the plan
 type PlannedProvider struct {
-	Source   string
-	Hostname string
-	Versions []PlannedVersion
+	Source    string           `json:"source"`
+	Hostname  string           `json:"hostname"`
+	PublishAs string           `json:"publish_as,omitempty"` // mirror address, if different from Source
+	Versions  []PlannedVersion `json:"versions"`
+	Skipped   []SkippedVersion `json:"skipped,omitempty"`
+}
+
+// Address returns the address the provider is published under in the mirror
+func (pp PlannedProvider) Address() string {
+	if pp.PublishAs != "" {
+		return pp.PublishAs
+	}
+	return pp.Source
 }
 
 // PlannedVersion represents a version in the plan
 type PlannedVersion struct {
-	Version   string
-	Platforms []string
+	Version          string   `json:"version"`
+	Platforms        []string `json:"platforms"`
+	MissingPlatforms []string `json:"missing_platforms,omitempty"` // requested but not published, skipped by policy
+	FallbackFrom     []string `json:"fallback_from,omitempty"`     // newer versions passed over because they lacked platforms
+}
+
+// SkippedVersion represents a matching version that will not be mirrored
+type SkippedVersion struct {
+	Version    string `json:"version"`
+	Constraint string `json:"constraint"`
+	HeldBack   bool   `json:"held_back"` // true if the version is only too new to mirror yet
+	Reason     string `json:"reason"`
+}
+
+// TraceEntry reports how one manifest constraint was resolved on one registry
+type TraceEntry struct {
+	Provider           string            `json:"provider"`
+	Registry           string            `json:"registry"`
+	ManifestSource     string            `json:"manifest_source"`
+	Engine             string            `json:"engine,omitempty"`
+	Constraint         string            `json:"constraint"`
+	VersionsReturned   []string          `json:"versions_returned"`
+	VersionsMatching   []string          `json:"versions_matching"`
+	Rejected           []RejectedVersion `json:"rejected,omitempty"`
+	Selected           string            `json:"selected,omitempty"`
+	RequestedPlatforms []string          `json:"requested_platforms,omitempty"`
+	Platforms          []string          `json:"platforms,omitempty"`
+	MissingPlatforms   []string          `json:"missing_platforms,omitempty"`
+	Error              string            `json:"error,omitempty"`
+}
+
+// RejectedVersion is a registry version a trace entry did not select
+type RejectedVersion struct {
+	Version string            `json:"version"`
+	Kind    resolver.SkipKind `json:"kind"`
+	Reason  string            `json:"reason"`
+}
+
+// NewTrace converts resolver decisions into trace entries
+func NewTrace(decisions []resolver.Decision) []TraceEntry {
+	trace := make([]TraceEntry, 0, len(decisions))
+	for _, d := range decisions {
+		te := TraceEntry{
+			Provider:           d.Provider.String(),
+			Registry:           d.Provider.Hostname,
+			ManifestSource:     d.ManifestSource,
+			Engine:             string(d.Engine),
+			Constraint:         d.Constraint,
+			VersionsReturned:   emptyIfNil(d.Versions),
+			VersionsMatching:   emptyIfNil(d.Candidates),
+			Selected:           d.Selected,
+			RequestedPlatforms: d.RequestedPlatforms,
+			Platforms:          d.Platforms,
+			MissingPlatforms:   d.MissingPlatforms,
+			Error:              d.Error,
+		}
+		for _, sv := range d.Rejected {
+			te.Rejected = append(te.Rejected, RejectedVersion{Version: sv.Version, Kind: sv.Kind, Reason: sv.Reason})
+		}
+		trace = append(trace, te)
+	}
+	return trace
+}
+
+// emptyIfNil returns an empty list instead of nil, so JSON reports show []
+func emptyIfNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
 }
 
 // Plan creates a build plan
@@ -56,18 +145,27 @@ func (p *Planner) Plan(ctx context.Context) (*Plan, error) {
 		return nil, fmt.Errorf("resolving versions: %w", err)
 	}
 
-	plan := &Plan{}
+	plan := &Plan{
+		Decisions: resolution.Decisions,
+		Trace:     NewTrace(resolution.Decisions),
+		Warnings:  resolution.Warnings,
+	}
 
 	for _, rp := range resolution.Providers {
 		pp := PlannedProvider{
 			Source:   rp.Source.String(),
 			Hostname: rp.Source.Hostname,
 		}
+		if rp.PublishAs != (manifest.ProviderSource{}) {
+			pp.PublishAs = rp.PublishAs.String()
+		}
 
 		for _, rv := range rp.Versions {
 			pv := PlannedVersion{
-				Version:   rv.Version,
-				Platforms: rv.Platforms,
+				Version:          rv.Version,
+				Platforms:        rv.Platforms,
+				MissingPlatforms: rv.MissingPlatforms,
+				FallbackFrom:     rv.FallbackFrom,
 			}
 			pp.Versions = append(pp.Versions, pv)
 			plan.TotalVersions++
@@ -77,5 +175,76 @@ func (p *Planner) Plan(ctx context.Context) (*Plan, error) {
 		plan.Providers = append(plan.Providers, pp)
 	}
 
+	for _, sv := range resolution.Skipped {
+		i := skippedProvider(resolution.Providers, sv)
+		if i < 0 {
+			continue
+		}
+		plan.Providers[i].Skipped = append(
+			plan.Providers[i].Skipped, SkippedVersion{
+				Version:    sv.Version,
+				Constraint: sv.Constraint,
+				HeldBack:   sv.Kind == resolver.SkipTooNew,
+				Reason:     sv.Reason,
+			},
+		)
+	}
+
 	return plan, nil
 }
+
+// skippedProvider returns the index of the provider a skipped version is
+// listed under: the one published at the same address from the same upstream,
+// or else the first one at that address, since only one upstream is kept when
+// several publish to the same address. It returns -1 if there is none.
+func skippedProvider(providers []resolver.ResolvedProvider, sv resolver.SkippedVersion) int {
+	first := -1
+	for i, rp := range providers {
+		if !strings.EqualFold(rp.Address().String(), sv.Address().String()) {
+			continue
+		}
+		if rp.Source == sv.Provider {
+			return i
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	return first
+}
+
+// LockFile returns the lock file entries a build of the plan would record.
+// Checksums are unknown until the archives are downloaded and are left empty.
+func (p *Plan) LockFile() *mirror.LockFile {
+	lockFile := &mirror.LockFile{Version: 1}
+
+	for _, pp := range p.Providers {
+		parts := strings.SplitN(pp.Address(), "/", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		lp := mirror.LockFileProvider{Hostname: parts[0], Namespace: parts[1], Name: parts[2]}
+		for _, pv := range pp.Versions {
+			lv := mirror.LockFileVersion{
+				Version:          pv.Version,
+				MissingPlatforms: pv.MissingPlatforms,
+				FallbackFrom:     pv.FallbackFrom,
+			}
+			if pp.PublishAs != "" {
+				lv.Origin = pp.Source
+			}
+			for _, platform := range pv.Platforms {
+				os, arch, err := registry.ParsePlatform(platform)
+				if err != nil {
+					continue
+				}
+				lv.Platforms = append(lv.Platforms, mirror.LockFilePlatform{OS: os, Arch: arch})
+			}
+			lp.Versions = append(lp.Versions, lv)
+		}
+		lockFile.Providers = append(lockFile.Providers, lp)
+	}
+
+	return lockFile
+}
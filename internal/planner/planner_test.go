@@ -0,0 +1,42 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+	"github.com/petroprotsakh/go-provider-mirror/internal/resolver"
+)
+
+// --- skippedProvider tests ---
+
+func TestSkippedProvider(t *testing.T) {
+	tf := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+	tofu := manifest.ProviderSource{Hostname: "registry.opentofu.org", Namespace: "hashicorp", Name: "aws"}
+	corp := manifest.ProviderSource{Hostname: "registry.corp.example", Namespace: "hashicorp", Name: "aws"}
+	legacy := manifest.ProviderSource{Hostname: "registry.corp.example", Namespace: "legacy", Name: "aws"}
+
+	// terraform.io versions are split between two addresses; opentofu.org
+	// versions were all consolidated into the terraform.io ones at corp
+	providers := []resolver.ResolvedProvider{
+		{Source: tf, PublishAs: corp},
+		{Source: tf, PublishAs: legacy},
+	}
+
+	tests := []struct {
+		name    string
+		skipped resolver.SkippedVersion
+		want    int
+	}{
+		{"split target", resolver.SkippedVersion{Provider: tf, PublishAs: legacy}, 1},
+		{"consolidated upstream", resolver.SkippedVersion{Provider: tofu, PublishAs: corp}, 0},
+		{"unpublished address", resolver.SkippedVersion{Provider: tf}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skippedProvider(providers, tt.skipped); got != tt.want {
+				t.Errorf("skippedProvider() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ type Config struct {
 	Timeout    time.Duration
 	Retries    int
 	MaxBackoff time.Duration
+	HostAuth   map[string]httpclient.HostAuth // credentials for archive hosts
+	Transport  *httpclient.Transport          // proxy and TLS settings
 }
 
 // DefaultConfig returns sensible defaults.
@@ -56,6 +58,8 @@ func NewClient(cfg *Config) *Client {
 				Timeout:    cfg.Timeout,
 				Retries:    cfg.Retries,
 				MaxBackoff: cfg.MaxBackoff,
+				HostAuth:   cfg.HostAuth,
+				Transport:  cfg.Transport,
 			},
 		),
 	}
@@ -193,6 +197,116 @@ func (c *Client) GetDownloadInfo(
 	return &info, nil
 }
 
+// releaseTimesResponse represents the v2 provider response with included versions.
+type releaseTimesResponse struct {
+	Included []struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			Version     string `json:"version"`
+			PublishedAt string `json:"published-at"`
+		} `json:"attributes"`
+	} `json:"included"`
+}
+
+// GetReleaseTimes retrieves publication timestamps of all versions of a provider,
+// keyed by version. Only registries implementing the v2 API (such as
+// registry.terraform.io) report them; for registries without it an empty map is
+// returned. Other failures, such as authentication errors, are returned.
+func (c *Client) GetReleaseTimes(
+	ctx context.Context,
+	hostname, namespace, name string,
+) (map[string]time.Time, error) {
+	endpoint := fmt.Sprintf(
+		"https://%s/v2/providers/%s/%s?include=provider-versions",
+		hostname,
+		namespace,
+		name,
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.http.Do(req, httpclient.WithRetry(), httpclient.WithAuth(hostname))
+	if err != nil {
+		return nil, fmt.Errorf("fetching release times: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return parseReleaseTimes(resp.Body)
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		// The registry does not serve the v2 API
+		return map[string]time.Time{}, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("registry returned %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// parseReleaseTimes decodes a v2 provider response into version publication times.
+func parseReleaseTimes(r io.Reader) (map[string]time.Time, error) {
+	var body releaseTimesResponse
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding release times: %w", err)
+	}
+
+	times := make(map[string]time.Time)
+	for _, item := range body.Included {
+		if item.Type != "provider-versions" || item.Attributes.PublishedAt == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, item.Attributes.PublishedAt)
+		if err != nil {
+			continue
+		}
+		times[item.Attributes.Version] = t
+	}
+
+	return times, nil
+}
+
+// GetArchiveLastModified returns the Last-Modified time of a provider archive.
+// It is used as the release time for registries that do not report one.
+func (c *Client) GetArchiveLastModified(
+	ctx context.Context,
+	hostname, namespace, name, version, os, arch string,
+) (time.Time, error) {
+	info, err := c.GetDownloadInfo(ctx, hostname, namespace, name, version, os, arch)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, info.DownloadURL, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.http.Do(req, httpclient.WithRetry())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fetching archive headers: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("archive host returned %d", resp.StatusCode)
+	}
+
+	lastModified := resp.Header.Get("Last-Modified")
+	if lastModified == "" {
+		return time.Time{}, fmt.Errorf("archive host did not report Last-Modified")
+	}
+
+	t, err := http.ParseTime(lastModified)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing Last-Modified %q: %w", lastModified, err)
+	}
+
+	return t, nil
+}
+
 // discoverService performs service discovery for a registry hostname.
 func (c *Client) discoverService(ctx context.Context, hostname string) (string, error) {
 	discoveryURL := fmt.Sprintf("https://%s/.well-known/terraform.json", hostname)
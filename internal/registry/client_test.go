@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -166,3 +167,37 @@ func TestDefaultServiceURL_PrivateRegistry(t *testing.T) {
 		t.Error("expected error for private registry without discovery")
 	}
 }
+
+// --- parseReleaseTimes tests ---
+
+func TestParseReleaseTimes(t *testing.T) {
+	body := `{
+  "data": {"type": "providers", "id": "323"},
+  "included": [
+    {"type": "provider-versions", "id": "1", "attributes": {"version": "5.0.0", "published-at": "2023-05-25T18:21:56Z"}},
+    {"type": "provider-versions", "id": "2", "attributes": {"version": "5.1.0", "published-at": "not a time"}},
+    {"type": "provider-versions", "id": "3", "attributes": {"version": "5.2.0"}},
+    {"type": "provider-docs", "id": "4", "attributes": {"version": "5.3.0", "published-at": "2023-06-01T00:00:00Z"}}
+  ]
+}`
+
+	times, err := parseReleaseTimes(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("parseReleaseTimes() error = %v", err)
+	}
+
+	if len(times) != 1 {
+		t.Fatalf("expected 1 release time, got %d: %v", len(times), times)
+	}
+
+	want := time.Date(2023, 5, 25, 18, 21, 56, 0, time.UTC)
+	if !times["5.0.0"].Equal(want) {
+		t.Errorf("expected %v, got %v", want, times["5.0.0"])
+	}
+}
+
+func TestParseReleaseTimes_InvalidJSON(t *testing.T) {
+	if _, err := parseReleaseTimes(strings.NewReader("{")); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-version"
 
@@ -14,31 +16,138 @@ import (
 // Resolver resolves provider version constraints against registries
 type Resolver struct {
 	client *registry.Client
+	now    func() time.Time
 }
 
 // New creates a new resolver
 func New(client *registry.Client) *Resolver {
 	return &Resolver{
 		client: client,
+		now:    time.Now,
 	}
 }
 
 // ResolvedProvider represents a provider with resolved concrete versions
 type ResolvedProvider struct {
-	Source   manifest.ProviderSource
-	Versions []ResolvedVersion
+	Source    manifest.ProviderSource // upstream address the versions are downloaded from
+	PublishAs manifest.ProviderSource // mirror address, if different from Source; zero otherwise
+	Versions  []ResolvedVersion
+}
+
+// Address returns the address the provider is published under in the mirror
+func (p ResolvedProvider) Address() manifest.ProviderSource {
+	if p.PublishAs != (manifest.ProviderSource{}) {
+		return p.PublishAs
+	}
+	return p.Source
 }
 
 // ResolvedVersion represents a single resolved version with platforms
 type ResolvedVersion struct {
-	Version         string
-	Platforms       []string // os_arch format
-	ManifestSources []string // original source specs from manifest that contributed to this version
+	Version          string
+	Platforms        []string // os_arch format
+	ManifestSources  []string // original source specs from manifest that contributed to this version
+	MissingPlatforms []string // requested platforms the version does not publish (skip policy)
+	FallbackFrom     []string // newer versions passed over because they lacked platforms (fallback policy)
+	Protocols        []string // plugin protocol versions published upstream
 }
 
 // Resolution represents the complete resolution result
 type Resolution struct {
 	Providers []ResolvedProvider
+	Skipped   []SkippedVersion // newer matching versions that were not selected
+	Decisions []Decision       // how each manifest constraint was resolved on each registry
+	Warnings  []string
+}
+
+// SkipKind classifies why a matching version was not selected
+type SkipKind string
+
+const (
+	SkipExcluded SkipKind = "excluded"  // manifest exclude list or security advisory
+	SkipTooNew   SkipKind = "held-back" // younger than min_age
+
+	SkipMissingPlatforms SkipKind = "missing-platforms" // lacks requested platforms (fallback policy)
+	SkipUnaligned        SkipKind = "unaligned"         // not available or eligible on every aligned registry
+	SkipProtocol         SkipKind = "protocol"          // speaks none of the allowed plugin protocols
+
+	// Kinds only recorded in decisions, for versions that never became candidates
+	SkipUnparsable SkipKind = "unparsable"  // the registry version is not a valid version
+	SkipPrerelease SkipKind = "pre-release" // a pre-release the constraint does not explicitly allow
+)
+
+// SkippedVersion records a version that satisfied a constraint but was
+// passed over in favour of an older one.
+type SkippedVersion struct {
+	Provider   manifest.ProviderSource
+	PublishAs  manifest.ProviderSource // mirror address, if different from Provider; zero otherwise
+	Version    string
+	Constraint string
+	Kind       SkipKind
+	Reason     string
+}
+
+// Address returns the address the skipped version would be published under
+func (sv SkippedVersion) Address() manifest.ProviderSource {
+	if sv.PublishAs != (manifest.ProviderSource{}) {
+		return sv.PublishAs
+	}
+	return sv.Provider
+}
+
+// Decision records how one version constraint of a manifest provider block
+// was resolved on one registry. Together the decisions form the resolution trace.
+type Decision struct {
+	Provider       manifest.ProviderSource
+	ManifestSource string          // manifest block, as in ResolvedVersion.ManifestSources
+	Engine         manifest.Engine // engine the registry was expanded for; empty for explicit hostnames
+	Constraint     string
+	Versions       []string         // versions returned by the registry, as listed
+	Candidates     []string         // versions matching the constraint, newest first
+	Rejected       []SkippedVersion // unusable versions and candidates passed over for older ones
+	Selected       string           // empty if resolution failed
+	Error          string           // why resolution failed
+
+	RequestedPlatforms []string // platform entries of the block, after platform set expansion
+	Platforms          []string // platforms mirrored for the selected version
+	MissingPlatforms   []string // requested platforms the selected version does not publish
+}
+
+// ResolutionError is returned by Resolve when a constraint cannot be
+// resolved. It carries the decisions made so far, ending with the failed one.
+type ResolutionError struct {
+	Err       error
+	Decisions []Decision
+}
+
+func (e *ResolutionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// Explain describes what the decision did with a version: selected it,
+// skipped it, passed it over for a newer one, or did not match it
+func (d Decision) Explain(v string) string {
+	if v == d.Selected {
+		return "selected"
+	}
+	for _, sv := range d.Rejected {
+		if sv.Version == v {
+			if sv.Kind == SkipTooNew {
+				return "held back: " + sv.Reason
+			}
+			return "skipped: " + sv.Reason
+		}
+	}
+	for _, c := range d.Candidates {
+		if c == v {
+			return fmt.Sprintf("matches, but %s was selected", d.Selected)
+		}
+	}
+	return "does not match the constraint"
 }
 
 // Resolve resolves all providers from the manifest to concrete versions.
@@ -60,34 +169,39 @@ func (r *Resolver) Resolve(ctx context.Context, m *manifest.Manifest) (*Resoluti
 	// Key: hostname/namespace/name/version -> platforms
 	versionsMap := make(map[versionKey]map[string]bool) // key -> set of platforms
 	sourcesMap := make(map[versionKey]map[string]bool)  // key -> set of manifest sources
+	notesMap := make(map[versionKey]*versionNotes)
+	publishMap := make(map[versionKey]manifest.ProviderSource) // key -> mirror address, if rewritten
+	orderMap := make(map[versionKey]int)                       // key -> first manifest position
+	var skipped []SkippedVersion
+	var decisions []Decision
+	var warnings []string
 
 	// Group expansions by provider identity and constraint for resolution
 	// Key: namespace/name + constraint string
 	type constraintGroup struct {
 		constraint string
 		expansions []manifest.ExpandedProvider
+		orders     []int // position of each expansion in the manifest
 	}
 	constraintGroups := make(map[string][]constraintGroup)
 
 	// First pass: group expansions by provider identity and constraint
-	for _, ep := range expanded {
+	for order, ep := range expanded {
 		providerKey := fmt.Sprintf("%s/%s", ep.Source.Namespace, ep.Source.Name)
 
 		for _, constraintStr := range ep.Versions {
+			single := ep
+			single.Versions = []string{constraintStr}
+
 			// Find or create group for this constraint
 			found := false
 			for i, cg := range constraintGroups[providerKey] {
 				if cg.constraint == constraintStr {
 					constraintGroups[providerKey][i].expansions = append(
 						constraintGroups[providerKey][i].expansions,
-						manifest.ExpandedProvider{
-							Source:     ep.Source,
-							Versions:   []string{constraintStr},
-							Platforms:  ep.Platforms,
-							Engine:     ep.Engine,
-							SourceSpec: ep.SourceSpec,
-						},
+						single,
 					)
+					constraintGroups[providerKey][i].orders = append(constraintGroups[providerKey][i].orders, order)
 					found = true
 					break
 				}
@@ -96,15 +210,8 @@ func (r *Resolver) Resolve(ctx context.Context, m *manifest.Manifest) (*Resoluti
 				constraintGroups[providerKey] = append(
 					constraintGroups[providerKey], constraintGroup{
 						constraint: constraintStr,
-						expansions: []manifest.ExpandedProvider{
-							{
-								Source:     ep.Source,
-								Versions:   []string{constraintStr},
-								Platforms:  ep.Platforms,
-								Engine:     ep.Engine,
-								SourceSpec: ep.SourceSpec,
-							},
-						},
+						expansions: []manifest.ExpandedProvider{single},
+						orders:     []int{order},
 					},
 				)
 			}
@@ -119,13 +226,17 @@ func (r *Resolver) Resolve(ctx context.Context, m *manifest.Manifest) (*Resoluti
 				return nil, ctx.Err()
 			}
 
-			resolvedVersion, err := r.resolveConstraintGroup(ctx, cg.constraint, cg.expansions)
+			resolvedVersion, groupSkipped, groupDecisions, err := r.resolveConstraintGroup(
+				ctx, cg.constraint, cg.expansions, m.Advisories,
+			)
+			decisions = append(decisions, groupDecisions...)
 			if err != nil {
-				return nil, err
+				return nil, &ResolutionError{Err: err, Decisions: sortDecisions(decisions)}
 			}
+			skipped = append(skipped, groupSkipped...)
 
-			// Add to results
-			for _, rv := range resolvedVersion {
+			// Add to results; there is one per expansion, in order
+			for i, rv := range resolvedVersion {
 				key := versionKey{
 					hostname:  rv.Provider.Hostname,
 					namespace: rv.Provider.Namespace,
@@ -133,6 +244,24 @@ func (r *Resolver) Resolve(ctx context.Context, m *manifest.Manifest) (*Resoluti
 					version:   rv.Version,
 				}
 
+				// Track where the version is published
+				target := rv.PublishAs
+				if strings.EqualFold(target.String(), rv.Provider.String()) {
+					target = manifest.ProviderSource{}
+				}
+				prevTarget, seen := publishMap[key]
+				if seen && !strings.EqualFold(prevTarget.String(), target.String()) {
+					err := fmt.Errorf(
+						"%s %s is published as both %s and %s",
+						rv.Provider.String(), rv.Version, publishedName(prevTarget, rv.Provider), publishedName(target, rv.Provider),
+					)
+					return nil, &ResolutionError{Err: err, Decisions: sortDecisions(decisions)}
+				}
+				publishMap[key] = target
+				if order, ok := orderMap[key]; !ok || cg.orders[i] < order {
+					orderMap[key] = cg.orders[i]
+				}
+
 				if versionsMap[key] == nil {
 					versionsMap[key] = make(map[string]bool)
 				}
@@ -145,43 +274,108 @@ func (r *Resolver) Resolve(ctx context.Context, m *manifest.Manifest) (*Resoluti
 					sourcesMap[key] = make(map[string]bool)
 				}
 				sourcesMap[key][rv.ManifestSource] = true
+
+				// Track platform policy outcomes and published protocols
+				if len(rv.MissingPlatforms) > 0 || len(rv.FallbackFrom) > 0 || len(rv.Protocols) > 0 {
+					if notesMap[key] == nil {
+						notesMap[key] = &versionNotes{}
+					}
+					notesMap[key].missing = append(notesMap[key].missing, rv.MissingPlatforms...)
+					notesMap[key].fallbackFrom = append(notesMap[key].fallbackFrom, rv.FallbackFrom...)
+					notesMap[key].protocols = append(notesMap[key].protocols, rv.Protocols...)
+				}
+				for _, missing := range rv.MissingPlatforms {
+					warnings = append(
+						warnings, fmt.Sprintf(
+							"%s %s does not have platform %s, skipping it",
+							rv.Provider.String(), rv.Version, missing,
+						),
+					)
+				}
 			}
 		}
 	}
 
 	// Build final result
-	return buildResolution(versionsMap, sourcesMap), nil
+	resolution := buildResolution(versionsMap, sourcesMap)
+	applyNotes(resolution, notesMap)
+	applyPublishAs(resolution, publishMap, orderMap)
+	resolution.Skipped = dedupeSkipped(skipped)
+	resolution.Decisions = sortDecisions(decisions)
+	warnings = append(warnings, divergenceWarnings(resolution.Decisions)...)
+	resolution.Warnings = dedupeStrings(warnings)
+
+	return resolution, nil
 }
 
 // resolvedVersionResult holds the result for a single version resolution
 type resolvedVersionResult struct {
-	Provider       manifest.ProviderSource
-	Version        string
-	Platforms      []string
-	ManifestSource string // original source spec from manifest (e.g., "hashicorp/null")
+	Provider         manifest.ProviderSource
+	Version          string
+	Platforms        []string
+	ManifestSource   string // original source spec from manifest (e.g., "hashicorp/null")
+	PublishAs        manifest.ProviderSource
+	MissingPlatforms []string
+	FallbackFrom     []string
+	Protocols        []string
 }
 
 // resolveConstraintGroup resolves a single constraint across multiple registry expansions.
 // Each registry resolves independently to its own latest matching version.
 // This allows registries to have different available versions without failing.
+// Expansions with align set instead share the newest version that every one
+// of their registries can provide.
+// Newer matching versions passed over because of exclusions, plugin protocols,
+// min_age or the fallback platform policy are returned as skipped. A decision is returned
+// for every expansion resolved, including the one that failed.
 func (r *Resolver) resolveConstraintGroup(
 	ctx context.Context,
 	constraintStr string,
 	expansions []manifest.ExpandedProvider,
-) ([]resolvedVersionResult, error) {
+	advisories []manifest.Advisory,
+) ([]resolvedVersionResult, []SkippedVersion, []Decision, error) {
 	if len(expansions) == 0 {
-		return nil, nil
+		return nil, nil, nil, nil
 	}
 
 	constraint, err := version.NewConstraint(constraintStr)
 	if err != nil {
-		return nil, fmt.Errorf("parsing constraint %q: %w", constraintStr, err)
+		return nil, nil, nil, fmt.Errorf("parsing constraint %q: %w", constraintStr, err)
 	}
 
-	var results []resolvedVersionResult
+	states := make([]*expansionState, 0, len(expansions))
+	fail := func(s *expansionState, err error) ([]resolvedVersionResult, []SkippedVersion, []Decision, error) {
+		s.decision.Error = err.Error()
+		var decisions []Decision
+		for _, st := range states {
+			if st == s || st.selected != nil {
+				decisions = append(decisions, st.decision)
+			}
+		}
+		return nil, nil, decisions, err
+	}
 
+	// Fetch the matching versions of every registry first, so that aligned
+	// expansions can be compared
 	for _, ep := range expansions {
-		// Fetch available versions from registry
+		s := &expansionState{
+			ep: ep,
+			decision: Decision{
+				Provider:           ep.Source,
+				ManifestSource:     manifestSource(ep),
+				Engine:             ep.Engine,
+				Constraint:         constraintStr,
+				RequestedPlatforms: ep.Platforms,
+			},
+			ages: &releaseAges{client: r.client, ep: ep, now: r.now()},
+		}
+		states = append(states, s)
+
+		s.exclusions, err = newExclusions(ep, advisories)
+		if err != nil {
+			return fail(s, err)
+		}
+
 		pvs, err := r.client.GetVersions(
 			ctx,
 			ep.Source.Hostname,
@@ -189,70 +383,690 @@ func (r *Resolver) resolveConstraintGroup(
 			ep.Source.Name,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("fetching versions for %s: %w", ep.Source.String(), err)
+			return fail(s, fmt.Errorf("fetching versions for %s: %w", ep.Source.String(), err))
+		}
+		for _, pv := range pvs.Versions {
+			s.decision.Versions = append(s.decision.Versions, pv.Version)
+		}
+		s.decision.Rejected = unmatchedVersions(ep.Source, pvs, constraint, constraintStr)
+
+		// Find all matching versions, newest first
+		s.candidates = matchCandidates(pvs, constraint)
+		for _, c := range s.candidates {
+			s.decision.Candidates = append(s.decision.Candidates, c.version.Original())
+		}
+		if len(s.candidates) == 0 {
+			return fail(
+				s, fmt.Errorf(
+					"no versions of %s match constraint %q",
+					ep.Source.String(), constraintStr,
+				),
+			)
 		}
+	}
 
-		// Find all matching versions
-		var matchingVersions []*version.Version
-		versionToPlatforms := make(map[string][]registry.ProviderPlatform)
+	// Select the latest eligible version, per registry or shared by the
+	// aligned registries
+	var aligned []*expansionState
+	for _, s := range states {
+		if s.ep.Align {
+			aligned = append(aligned, s)
+		}
+	}
+	if len(aligned) == 1 {
+		aligned = nil
+	}
 
-		for _, pv := range pvs.Versions {
-			v, err := version.NewVersion(pv.Version)
+	for _, s := range states {
+		if len(aligned) > 0 && s.ep.Align {
+			continue
+		}
+		if err := s.selectLatest(ctx); err != nil {
+			return fail(s, err)
+		}
+		if s.selected == nil {
+			return fail(
+				s, fmt.Errorf(
+					"no eligible version of %s matches constraint %q (%d matching version(s) skipped)",
+					s.ep.Source.String(), constraintStr, len(s.candidates),
+				),
+			)
+		}
+	}
+
+	if len(aligned) > 0 {
+		if failed, err := selectAligned(ctx, aligned); err != nil {
+			return fail(failed, err)
+		}
+		if aligned[0].selected == nil {
+			return fail(
+				aligned[0], fmt.Errorf(
+					"no version of %s/%s matching constraint %q is available and eligible on all of %s (align)",
+					aligned[0].ep.Source.Namespace, aligned[0].ep.Source.Name, constraintStr,
+					strings.Join(registryHosts(aligned), ", "),
+				),
+			)
+		}
+	}
+
+	var results []resolvedVersionResult
+	var skipped []SkippedVersion
+	for _, s := range states {
+		ep := s.ep
+		selectedVersion := s.selected.version.Original()
+		s.decision.Selected = selectedVersion
+
+		// Check platform availability for selected version
+		platforms, missing := matchPlatforms(ep.Platforms, s.selected.platforms)
+		s.decision.Platforms = platforms
+		s.decision.MissingPlatforms = missing
+		if len(missing) > 0 && ep.MissingPlatforms != manifest.PlatformPolicySkip {
+			return fail(
+				s, fmt.Errorf(
+					"provider %s version %s does not have platform %s",
+					ep.Source.String(), selectedVersion, missing[0],
+				),
+			)
+		}
+		if len(platforms) == 0 && len(ep.Platforms) > 0 {
+			return fail(
+				s, fmt.Errorf(
+					"provider %s version %s has none of the requested platforms",
+					ep.Source.String(), selectedVersion,
+				),
+			)
+		}
+
+		skipped = append(skipped, s.passedOver...)
+		results = append(
+			results, resolvedVersionResult{
+				Provider:         ep.Source,
+				Version:          selectedVersion,
+				Platforms:        platforms,
+				ManifestSource:   manifestSource(ep),
+				PublishAs:        ep.PublishAs,
+				MissingPlatforms: missing,
+				FallbackFrom:     s.fallbackFrom,
+				Protocols:        s.selected.protocols,
+			},
+		)
+	}
+
+	decisions := make([]Decision, 0, len(states))
+	for _, s := range states {
+		decisions = append(decisions, s.decision)
+	}
+	return results, skipped, decisions, nil
+}
+
+// expansionState tracks the resolution of one constraint on one registry
+type expansionState struct {
+	ep         manifest.ExpandedProvider
+	decision   Decision
+	exclusions exclusions
+	ages       *releaseAges
+	candidates []candidate // newest first
+
+	selected     *candidate
+	passedOver   []SkippedVersion // candidates newer than the selected one
+	fallbackFrom []string
+}
+
+// eligibility returns why a candidate cannot be selected on this registry,
+// or an empty kind if it can
+func (s *expansionState) eligibility(ctx context.Context, c candidate) (SkipKind, string, error) {
+	if reason := s.exclusions.check(c.version); reason != "" {
+		return SkipExcluded, reason, nil
+	}
+	if reason := protocolReason(s.ep.Protocols, c.protocols); reason != "" {
+		return SkipProtocol, reason, nil
+	}
+	if s.ep.MissingPlatforms == manifest.PlatformPolicyFallback {
+		if _, missing := matchPlatforms(s.ep.Platforms, c.platforms); len(missing) > 0 {
+			return SkipMissingPlatforms, "missing platforms " + strings.Join(missing, ", "), nil
+		}
+	}
+	reason, err := s.ages.check(ctx, c)
+	if err != nil {
+		return "", "", err
+	}
+	if reason != "" {
+		return SkipTooNew, reason, nil
+	}
+	return "", "", nil
+}
+
+// skip records a candidate passed over for an older one
+func (s *expansionState) skip(c candidate, kind SkipKind, reason string) {
+	sv := SkippedVersion{
+		Provider:   s.ep.Source,
+		PublishAs:  s.ep.PublishAs,
+		Version:    c.version.Original(),
+		Constraint: s.decision.Constraint,
+		Kind:       kind,
+		Reason:     reason,
+	}
+	s.passedOver = append(s.passedOver, sv)
+	s.decision.Rejected = append(s.decision.Rejected, sv)
+	if kind == SkipMissingPlatforms {
+		s.fallbackFrom = append(s.fallbackFrom, c.version.Original())
+	}
+}
+
+// selectLatest selects the latest candidate that is not excluded, speaks an
+// allowed plugin protocol, has every platform under the fallback policy and
+// is at least min_age old
+func (s *expansionState) selectLatest(ctx context.Context) error {
+	for i, c := range s.candidates {
+		kind, reason, err := s.eligibility(ctx, c)
+		if err != nil {
+			return err
+		}
+		if kind != "" {
+			s.skip(c, kind, reason)
+			continue
+		}
+		s.selected = &s.candidates[i]
+		return nil
+	}
+	return nil
+}
+
+// selectAligned selects the newest version that every aligned registry
+// publishes and can select. Versions one registry lacks or cannot select are
+// skipped on all of them. If no version qualifies, nothing is selected.
+// On error, the expansion that failed is returned with it.
+func selectAligned(ctx context.Context, states []*expansionState) (*expansionState, error) {
+	// Candidates of each registry by version, and every version newest first
+	byVersion := make([]map[string]candidate, len(states))
+	var versions []*version.Version
+	seen := make(map[string]bool)
+	for i, s := range states {
+		byVersion[i] = make(map[string]candidate, len(s.candidates))
+		for _, c := range s.candidates {
+			key := c.version.String()
+			byVersion[i][key] = c
+			if !seen[key] {
+				seen[key] = true
+				versions = append(versions, c.version)
+			}
+		}
+	}
+	sort.Slice(
+		versions, func(i, j int) bool {
+			return versions[i].GreaterThan(versions[j])
+		},
+	)
+
+	for _, v := range versions {
+		key := v.String()
+
+		var lacking []string
+		for i, s := range states {
+			if _, ok := byVersion[i][key]; !ok {
+				lacking = append(lacking, s.ep.Source.Hostname)
+			}
+		}
+		if len(lacking) > 0 {
+			reason := "not available on " + strings.Join(dedupeStrings(lacking), ", ")
+			for i, s := range states {
+				if c, ok := byVersion[i][key]; ok {
+					s.skip(c, SkipUnaligned, reason)
+				}
+			}
+			continue
+		}
+
+		kinds := make([]SkipKind, len(states))
+		reasons := make([]string, len(states))
+		blocker := -1
+		for i, s := range states {
+			kind, reason, err := s.eligibility(ctx, byVersion[i][key])
 			if err != nil {
-				continue
+				return s, err
 			}
-			if constraint.Check(v) {
-				matchingVersions = append(matchingVersions, v)
-				versionToPlatforms[pv.Version] = pv.Platforms
+			kinds[i], reasons[i] = kind, reason
+			if kind != "" && blocker < 0 {
+				blocker = i
 			}
 		}
 
-		if len(matchingVersions) == 0 {
-			return nil, fmt.Errorf(
-				"no versions of %s match constraint %q",
-				ep.Source.String(), constraintStr,
+		if blocker < 0 {
+			for i, s := range states {
+				c := byVersion[i][key]
+				s.selected = &c
+			}
+			return nil, nil
+		}
+
+		for i, s := range states {
+			if kinds[i] != "" {
+				s.skip(byVersion[i][key], kinds[i], reasons[i])
+				continue
+			}
+			s.skip(
+				byVersion[i][key], SkipUnaligned,
+				fmt.Sprintf("not eligible on %s: %s", states[blocker].ep.Source.Hostname, reasons[blocker]),
 			)
 		}
+	}
 
-		// Sort descending (newest first)
-		sort.Slice(
-			matchingVersions, func(i, j int) bool {
-				return matchingVersions[i].GreaterThan(matchingVersions[j])
-			},
+	return nil, nil
+}
+
+// registryHosts returns the distinct registries of the expansions
+func registryHosts(states []*expansionState) []string {
+	var hosts []string
+	for _, s := range states {
+		hosts = append(hosts, s.ep.Source.Hostname)
+	}
+	return dedupeStrings(hosts)
+}
+
+// divergenceWarnings reports manifest constraints that resolved to different
+// versions on the registries of different engines
+func divergenceWarnings(decisions []Decision) []string {
+	type blockKey struct {
+		source     string
+		constraint string
+	}
+	selected := make(map[blockKey][]Decision)
+	var keys []blockKey
+	for _, d := range decisions {
+		if d.Engine == "" || d.Selected == "" {
+			continue
+		}
+		key := blockKey{d.ManifestSource, d.Constraint}
+		if selected[key] == nil {
+			keys = append(keys, key)
+		}
+		selected[key] = append(selected[key], d)
+	}
+
+	var warnings []string
+	for _, key := range keys {
+		group := selected[key]
+		diverged := false
+		for _, d := range group[1:] {
+			if d.Selected != group[0].Selected {
+				diverged = true
+				break
+			}
+		}
+		if !diverged {
+			continue
+		}
+
+		var parts []string
+		for _, d := range group {
+			parts = append(parts, fmt.Sprintf("%s %s on %s", string(d.Engine), d.Selected, d.Provider.Hostname))
+		}
+		warnings = append(
+			warnings, fmt.Sprintf(
+				"%s %q resolves to different versions per engine (%s); set align: true to mirror the same version",
+				key.source, key.constraint, strings.Join(parts, ", "),
+			),
 		)
+	}
+	return warnings
+}
 
-		// Select latest matching version for THIS registry
-		selectedVersion := matchingVersions[0].Original()
+// manifestSource describes the manifest entry a version was resolved for,
+// including the fragment it came from when the manifest spans several files
+func manifestSource(ep manifest.ExpandedProvider) string {
+	if ep.Origin == "" {
+		return ep.SourceSpec
+	}
+	return fmt.Sprintf("%s (%s)", ep.SourceSpec, ep.Origin)
+}
 
-		// Check platform availability for selected version
-		availablePlatforms := make(map[string]bool)
-		for _, p := range versionToPlatforms[selectedVersion] {
-			availablePlatforms[p.String()] = true
+// matchPlatforms splits the requested platforms into those the version
+// publishes and those it does not. Wildcard patterns expand to every matching
+// published platform and are never reported as missing.
+func matchPlatforms(requested []string, available []registry.ProviderPlatform) (present, missing []string) {
+	availableSet := make(map[string]bool)
+	for _, p := range available {
+		availableSet[p.String()] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range requested {
+		if manifest.IsPlatformPattern(p) {
+			for _, a := range available {
+				if manifest.MatchPlatform(p, a.String()) && !seen[a.String()] {
+					seen[a.String()] = true
+					present = append(present, a.String())
+				}
+			}
+			continue
+		}
+		if availableSet[p] {
+			if !seen[p] {
+				seen[p] = true
+				present = append(present, p)
+			}
+		} else {
+			missing = append(missing, p)
 		}
+	}
 
-		var platforms []string
-		for _, requested := range ep.Platforms {
-			if availablePlatforms[requested] {
-				platforms = append(platforms, requested)
-			} else {
-				return nil, fmt.Errorf(
-					"provider %s version %s does not have platform %s",
-					ep.Source.String(), selectedVersion, requested,
-				)
+	return present, missing
+}
+
+// candidate is a registry version that satisfies a constraint
+type candidate struct {
+	version   *version.Version
+	protocols []string
+	platforms []registry.ProviderPlatform
+}
+
+// matchCandidates returns all versions satisfying the constraint, newest first.
+// Versions the registry reports in an unparsable format are ignored.
+func matchCandidates(pvs *registry.ProviderVersions, constraint version.Constraints) []candidate {
+	var candidates []candidate
+
+	for _, pv := range pvs.Versions {
+		v, err := version.NewVersion(pv.Version)
+		if err != nil {
+			continue
+		}
+		if constraint.Check(v) {
+			candidates = append(candidates, candidate{version: v, protocols: pv.Protocols, platforms: pv.Platforms})
+		}
+	}
+
+	// Sort descending (newest first)
+	sort.Slice(
+		candidates, func(i, j int) bool {
+			return candidates[i].version.GreaterThan(candidates[j].version)
+		},
+	)
+
+	return candidates
+}
+
+// unmatchedVersions returns the registry versions that cannot match the
+// constraint only because they are unparsable or pre-releases. Versions
+// outside the constraint's range are not listed.
+func unmatchedVersions(
+	src manifest.ProviderSource,
+	pvs *registry.ProviderVersions,
+	constraint version.Constraints,
+	constraintStr string,
+) []SkippedVersion {
+	var rejected []SkippedVersion
+	reject := func(v string, kind SkipKind, reason string) {
+		rejected = append(
+			rejected, SkippedVersion{
+				Provider:   src,
+				Version:    v,
+				Constraint: constraintStr,
+				Kind:       kind,
+				Reason:     reason,
+			},
+		)
+	}
+
+	for _, pv := range pvs.Versions {
+		v, err := version.NewVersion(pv.Version)
+		if err != nil {
+			reject(pv.Version, SkipUnparsable, "unparsable version")
+			continue
+		}
+		if v.Prerelease() != "" && !constraint.Check(v) && constraint.Check(v.Core()) {
+			reject(pv.Version, SkipPrerelease, "pre-release not explicitly allowed by the constraint")
+		}
+	}
+
+	return rejected
+}
+
+// protocolReason returns why a version's plugin protocols are incompatible
+// with the allowed ones, or an empty string. Versions that do not list their
+// protocols are assumed to be compatible.
+func protocolReason(allowed, published []string) string {
+	if len(allowed) == 0 || len(published) == 0 {
+		return ""
+	}
+
+	for _, p := range published {
+		major, err := manifest.ProtocolMajor(p)
+		if err != nil {
+			continue
+		}
+		for _, a := range allowed {
+			if am, err := manifest.ProtocolMajor(a); err == nil && am == major {
+				return ""
 			}
 		}
+	}
 
-		results = append(
-			results, resolvedVersionResult{
-				Provider:       ep.Source,
-				Version:        selectedVersion,
-				Platforms:      platforms,
-				ManifestSource: ep.SourceSpec,
+	return fmt.Sprintf(
+		"supports plugin protocol %s, not %s",
+		strings.Join(published, ", "), strings.Join(allowed, ", "),
+	)
+}
+
+// exclusion is a single rule that prevents matching versions from being selected
+type exclusion struct {
+	constraint version.Constraints
+	reason     string
+}
+
+// exclusions holds every rule that applies to one expanded provider
+type exclusions []exclusion
+
+// newExclusions collects the manifest exclude list and matching advisories for a provider
+func newExclusions(ep manifest.ExpandedProvider, advisories []manifest.Advisory) (exclusions, error) {
+	var result exclusions
+
+	for _, e := range ep.Exclude {
+		c, err := version.NewConstraint(e)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: parsing exclude %q: %w", ep.SourceSpec, e, err)
+		}
+		result = append(
+			result, exclusion{
+				constraint: c,
+				reason:     fmt.Sprintf("excluded by manifest (%s)", e),
 			},
 		)
 	}
 
-	return results, nil
+	for _, a := range advisories {
+		if !a.Matches(ep.Source) {
+			continue
+		}
+		c, err := version.NewConstraint(a.Versions)
+		if err != nil {
+			return nil, fmt.Errorf("advisory for %s: parsing versions %q: %w", a.Source, a.Versions, err)
+		}
+		reason := fmt.Sprintf("security advisory (%s)", a.Versions)
+		if a.Reason != "" {
+			reason = fmt.Sprintf("security advisory: %s", a.Reason)
+		}
+		result = append(result, exclusion{constraint: c, reason: reason})
+	}
+
+	return result, nil
+}
+
+// check returns the reason the version is excluded, or an empty string
+func (e exclusions) check(v *version.Version) string {
+	for _, ex := range e {
+		if ex.constraint.Check(v) {
+			return ex.reason
+		}
+	}
+	return ""
+}
+
+// releaseAges checks candidate versions against an expanded provider's min_age
+type releaseAges struct {
+	client *registry.Client
+	ep     manifest.ExpandedProvider
+	now    time.Time
+	times  map[string]time.Time // release times from the v2 API, loaded lazily
+}
+
+// check returns the reason the candidate is held back, or an empty string
+func (a *releaseAges) check(ctx context.Context, c candidate) (string, error) {
+	if a.ep.MinAge <= 0 {
+		return "", nil
+	}
+
+	published, err := a.releaseTime(ctx, c)
+	if err != nil {
+		return "", fmt.Errorf(
+			"determining release time of %s %s for min_age: %w",
+			a.ep.Source.String(), c.version.Original(), err,
+		)
+	}
+
+	return ageReason(published, a.now, a.ep.MinAge), nil
+}
+
+// releaseTime returns when a candidate was published. The v2 registry API is
+// preferred; registries without it fall back to the archive Last-Modified header.
+func (a *releaseAges) releaseTime(ctx context.Context, c candidate) (time.Time, error) {
+	if a.times == nil {
+		times, err := a.client.GetReleaseTimes(ctx, a.ep.Source.Hostname, a.ep.Source.Namespace, a.ep.Source.Name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// Versions missing here fall back to the archive Last-Modified header
+		a.times = times
+	}
+
+	if t, ok := a.times[c.version.Original()]; ok {
+		return t, nil
+	}
+
+	if len(c.platforms) == 0 {
+		return time.Time{}, fmt.Errorf("version has no platforms")
+	}
+
+	// Prefer a platform we are going to download anyway
+	platform := c.platforms[0]
+	if present, _ := matchPlatforms(a.ep.Platforms, c.platforms); len(present) > 0 {
+		if osName, arch, err := registry.ParsePlatform(present[0]); err == nil {
+			platform = registry.ProviderPlatform{OS: osName, Arch: arch}
+		}
+	}
+
+	return a.client.GetArchiveLastModified(
+		ctx,
+		a.ep.Source.Hostname,
+		a.ep.Source.Namespace,
+		a.ep.Source.Name,
+		c.version.Original(),
+		platform.OS,
+		platform.Arch,
+	)
+}
+
+// ageReason returns why a version published at the given time is held back, or
+// an empty string if it is at least minAge old.
+func ageReason(published, now time.Time, minAge time.Duration) string {
+	age := now.Sub(published)
+	if age >= minAge {
+		return ""
+	}
+	return fmt.Sprintf("released %s ago, younger than min_age %s", formatAge(age), formatAge(minAge))
+}
+
+// formatAge formats a duration with day precision for readability
+func formatAge(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < 0 {
+		d = 0
+	}
+
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// dedupeStrings removes duplicates while preserving order
+func dedupeStrings(list []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// dedupeSkipped removes duplicate skip records and sorts them for stable output
+func dedupeSkipped(skipped []SkippedVersion) []SkippedVersion {
+	seen := make(map[SkippedVersion]bool)
+	var result []SkippedVersion
+	for _, s := range skipped {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		result = append(result, s)
+	}
+
+	sort.Slice(
+		result, func(i, j int) bool {
+			if result[i].Provider.String() != result[j].Provider.String() {
+				return result[i].Provider.String() < result[j].Provider.String()
+			}
+			if result[i].Version != result[j].Version {
+				return versionLess(result[i].Version, result[j].Version)
+			}
+			return result[i].Constraint < result[j].Constraint
+		},
+	)
+
+	return result
+}
+
+// versionLess orders versions semantically, so 5.31.0 sorts before 5.100.0.
+// Unparsable versions sort after the others, by string.
+func versionLess(a, b string) bool {
+	va, errA := version.NewVersion(a)
+	vb, errB := version.NewVersion(b)
+	switch {
+	case errA == nil && errB == nil && !va.Equal(vb):
+		return va.LessThan(vb)
+	case errA == nil && errB != nil:
+		return true
+	case errA != nil && errB == nil:
+		return false
+	}
+	return a < b
+}
+
+// sortDecisions sorts decisions by provider, manifest block and constraint for stable output
+func sortDecisions(decisions []Decision) []Decision {
+	sort.SliceStable(
+		decisions, func(i, j int) bool {
+			a, b := decisions[i], decisions[j]
+			if a.Provider.String() != b.Provider.String() {
+				return a.Provider.String() < b.Provider.String()
+			}
+			if a.ManifestSource != b.ManifestSource {
+				return a.ManifestSource < b.ManifestSource
+			}
+			return a.Constraint < b.Constraint
+		},
+	)
+	return decisions
 }
 
 // versionKey identifies a unique provider version (artifact identity).
@@ -269,6 +1083,118 @@ type versionData struct {
 	sources   []string
 }
 
+// versionNotes holds platform policy outcomes and protocols for a version
+type versionNotes struct {
+	missing      []string
+	fallbackFrom []string
+	protocols    []string
+}
+
+// applyNotes records platform policy outcomes and protocols on the matching
+// resolved versions
+func applyNotes(resolution *Resolution, notes map[versionKey]*versionNotes) {
+	for i := range resolution.Providers {
+		rp := &resolution.Providers[i]
+		for j := range rp.Versions {
+			rv := &rp.Versions[j]
+			n := notes[versionKey{
+				hostname:  rp.Source.Hostname,
+				namespace: rp.Source.Namespace,
+				name:      rp.Source.Name,
+				version:   rv.Version,
+			}]
+			if n == nil {
+				continue
+			}
+			rv.MissingPlatforms = sortedUnique(n.missing)
+			rv.FallbackFrom = sortedUnique(n.fallbackFrom)
+			rv.Protocols = sortedUnique(n.protocols)
+		}
+	}
+}
+
+// publishedName names the mirror address of a provider, which is its upstream
+// address unless rewritten
+func publishedName(target, upstream manifest.ProviderSource) string {
+	if target == (manifest.ProviderSource{}) {
+		return upstream.String()
+	}
+	return target.String()
+}
+
+// applyPublishAs splits resolved providers by the address their versions are
+// published under. When several upstream providers publish the same version
+// to one address, only the one declared first in the manifest is kept.
+func applyPublishAs(resolution *Resolution, targets map[versionKey]manifest.ProviderSource, orders map[versionKey]int) {
+	rewritten := false
+	for _, target := range targets {
+		if target != (manifest.ProviderSource{}) {
+			rewritten = true
+			break
+		}
+	}
+	if !rewritten {
+		return
+	}
+
+	type published struct {
+		address string
+		version string
+	}
+	winners := make(map[published]versionKey)
+	keyOf := func(src manifest.ProviderSource, v string) versionKey {
+		return versionKey{hostname: src.Hostname, namespace: src.Namespace, name: src.Name, version: v}
+	}
+
+	for _, rp := range resolution.Providers {
+		for _, rv := range rp.Versions {
+			key := keyOf(rp.Source, rv.Version)
+			pk := published{strings.ToLower(publishedName(targets[key], rp.Source)), rv.Version}
+			if prev, ok := winners[pk]; !ok || orders[key] < orders[prev] {
+				winners[pk] = key
+			}
+		}
+	}
+
+	var providers []ResolvedProvider
+	index := make(map[[2]manifest.ProviderSource]int) // upstream and target -> position in providers
+	for _, rp := range resolution.Providers {
+		for _, rv := range rp.Versions {
+			key := keyOf(rp.Source, rv.Version)
+			target := targets[key]
+			if winners[published{strings.ToLower(publishedName(target, rp.Source)), rv.Version}] != key {
+				continue
+			}
+
+			ik := [2]manifest.ProviderSource{rp.Source, target}
+			i, ok := index[ik]
+			if !ok {
+				i = len(providers)
+				index[ik] = i
+				providers = append(providers, ResolvedProvider{Source: rp.Source, PublishAs: target})
+			}
+			providers[i].Versions = append(providers[i].Versions, rv)
+		}
+	}
+
+	sort.SliceStable(
+		providers, func(i, j int) bool {
+			return providers[i].Address().String() < providers[j].Address().String()
+		},
+	)
+	resolution.Providers = providers
+}
+
+// sortedUnique returns the sorted set of values, or nil if empty
+func sortedUnique(list []string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	result := dedupeStrings(list)
+	sort.Strings(result)
+	return result
+}
+
 // buildResolution converts the map-based results into the Resolution structure
 func buildResolution(
 	versionsMap map[versionKey]map[string]bool,
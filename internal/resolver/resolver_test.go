@@ -2,11 +2,24 @@ package resolver
 
 import (
 	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/go-version"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/httpclient"
 	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
 )
 
 // --- buildResolution tests ---
@@ -462,3 +475,717 @@ func TestResolve_ContextCancelled(t *testing.T) {
 		t.Errorf("expected context.Canceled, got %v", err)
 	}
 }
+
+func TestResolve_PublishConflictKeepsTrace(t *testing.T) {
+	client, host := newTestRegistry(t, http.StatusNotFound, time.Now())
+
+	m := &manifest.Manifest{
+		Defaults: manifest.Defaults{
+			Engines:   []manifest.Engine{manifest.EngineTerraform},
+			Platforms: []string{"linux_amd64"},
+		},
+		Providers: []manifest.Provider{
+			{Source: host + "/acme/widget", Versions: []string{"1.0.0"}, PublishAs: host + "/corp/widget"},
+			{Source: host + "/acme/widget", Versions: []string{">= 1.0"}, PublishAs: host + "/other/widget"},
+		},
+	}
+
+	_, err := New(client).Resolve(context.Background(), m)
+	var resErr *ResolutionError
+	if !errors.As(err, &resErr) {
+		t.Fatalf("expected ResolutionError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "is published as both") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(resErr.Decisions) == 0 {
+		t.Error("expected the decisions made so far in the trace")
+	}
+}
+
+// --- matchCandidates tests ---
+
+func testVersions(versions ...string) *registry.ProviderVersions {
+	pvs := &registry.ProviderVersions{}
+	for _, v := range versions {
+		pvs.Versions = append(
+			pvs.Versions, registry.ProviderVersion{
+				Version:   v,
+				Platforms: []registry.ProviderPlatform{{OS: "linux", Arch: "amd64"}},
+			},
+		)
+	}
+	return pvs
+}
+
+func TestMatchCandidates_SortedNewestFirst(t *testing.T) {
+	constraint, _ := version.NewConstraint("~> 5.30")
+	candidates := matchCandidates(testVersions("5.30.0", "5.32.1", "4.67.0", "5.31.0", "junk"), constraint)
+
+	var got []string
+	for _, c := range candidates {
+		got = append(got, c.version.Original())
+	}
+
+	expected := []string{"5.32.1", "5.31.0", "5.30.0"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestMatchCandidates_NoMatch(t *testing.T) {
+	constraint, _ := version.NewConstraint(">= 6.0")
+	if candidates := matchCandidates(testVersions("5.0.0"), constraint); len(candidates) != 0 {
+		t.Errorf("expected no candidates, got %d", len(candidates))
+	}
+}
+
+// --- exclusions tests ---
+
+func TestNewExclusions_ManifestAndAdvisories(t *testing.T) {
+	ep := manifest.ExpandedProvider{
+		Source:     manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"},
+		Exclude:    []string{"5.31.0"},
+		SourceSpec: "hashicorp/aws",
+	}
+	advisories := []manifest.Advisory{
+		{Source: "hashicorp/aws", Versions: ">= 5.40.0, < 5.41.0", Reason: "CVE-2099-0001"},
+		{Source: "hashicorp/google", Versions: ">= 0.0.0", Reason: "unrelated"},
+	}
+
+	ex, err := newExclusions(ep, advisories)
+	if err != nil {
+		t.Fatalf("newExclusions() error = %v", err)
+	}
+
+	if len(ex) != 2 {
+		t.Fatalf("expected 2 exclusions, got %d", len(ex))
+	}
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"5.31.0", "excluded by manifest (5.31.0)"},
+		{"5.40.2", "security advisory: CVE-2099-0001"},
+		{"5.32.0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := ex.check(version.Must(version.NewVersion(tt.version))); got != tt.want {
+				t.Errorf("check(%s) = %q, want %q", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewExclusions_InvalidConstraint(t *testing.T) {
+	ep := manifest.ExpandedProvider{Exclude: []string{"not a version"}, SourceSpec: "hashicorp/aws"}
+
+	if _, err := newExclusions(ep, nil); err == nil {
+		t.Error("expected error for invalid exclude constraint")
+	}
+}
+
+// --- dedupeSkipped tests ---
+
+func TestDedupeSkipped(t *testing.T) {
+	aws := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+	null := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "null"}
+
+	skipped := dedupeSkipped(
+		[]SkippedVersion{
+			{Provider: null, Version: "3.2.0", Constraint: "~> 3.0", Reason: "excluded"},
+			{Provider: aws, Version: "5.31.0", Constraint: "~> 5.0", Reason: "excluded"},
+			{Provider: null, Version: "3.2.0", Constraint: "~> 3.0", Reason: "excluded"},
+		},
+	)
+
+	if len(skipped) != 2 {
+		t.Fatalf("expected 2 skipped versions, got %d", len(skipped))
+	}
+
+	if skipped[0].Provider != aws {
+		t.Errorf("expected aws first, got %s", skipped[0].Provider)
+	}
+}
+
+func TestDedupeSkipped_SortsVersionsSemantically(t *testing.T) {
+	aws := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+
+	skipped := dedupeSkipped(
+		[]SkippedVersion{
+			{Provider: aws, Version: "5.100.0", Kind: SkipExcluded},
+			{Provider: aws, Version: "not-a-version", Kind: SkipUnparsable},
+			{Provider: aws, Version: "5.31.0", Kind: SkipExcluded},
+			{Provider: aws, Version: "5.9.0", Kind: SkipExcluded},
+		},
+	)
+
+	var got []string
+	for _, sv := range skipped {
+		got = append(got, sv.Version)
+	}
+	want := []string{"5.9.0", "5.31.0", "5.100.0", "not-a-version"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupeSkipped() order = %v, want %v", got, want)
+	}
+}
+
+// --- min_age tests ---
+
+func TestAgeReason(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		published time.Time
+		minAge    time.Duration
+		want      string
+	}{
+		{"old enough", now.Add(-96 * time.Hour), 72 * time.Hour, ""},
+		{"exactly min_age", now.Add(-72 * time.Hour), 72 * time.Hour, ""},
+		{"too new", now.Add(-5 * time.Hour), 72 * time.Hour, "released 5h0m ago, younger than min_age 3d0h"},
+		{"minutes old", now.Add(-42 * time.Minute), time.Hour, "released 42m ago, younger than min_age 1h0m"},
+		{"clock skew", now.Add(time.Hour), time.Hour, "released 0m ago, younger than min_age 1h0m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ageReason(tt.published, now, tt.minAge); got != tt.want {
+				t.Errorf("ageReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseAges_DisabledWithoutMinAge(t *testing.T) {
+	// No client calls are made when min_age is unset
+	ages := &releaseAges{ep: manifest.ExpandedProvider{}, now: time.Now()}
+	c := candidate{version: version.Must(version.NewVersion("1.0.0"))}
+
+	reason, err := ages.check(context.Background(), c)
+	if err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+
+	if reason != "" {
+		t.Errorf("expected no reason, got %q", reason)
+	}
+}
+
+func TestReleaseAges_UsesKnownReleaseTimes(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	ages := &releaseAges{
+		ep:  manifest.ExpandedProvider{MinAge: 48 * time.Hour},
+		now: now,
+		times: map[string]time.Time{
+			"5.1.0": now.Add(-2 * time.Hour),
+			"5.0.0": now.Add(-30 * 24 * time.Hour),
+		},
+	}
+
+	reason, err := ages.check(context.Background(), candidate{version: version.Must(version.NewVersion("5.1.0"))})
+	if err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+	if reason == "" {
+		t.Error("expected 5.1.0 to be held back")
+	}
+
+	reason, err = ages.check(context.Background(), candidate{version: version.Must(version.NewVersion("5.0.0"))})
+	if err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+	if reason != "" {
+		t.Errorf("expected 5.0.0 to be eligible, got %q", reason)
+	}
+}
+
+func TestReleaseAges_NoPlatformsForFallback(t *testing.T) {
+	ages := &releaseAges{
+		ep:    manifest.ExpandedProvider{MinAge: time.Hour},
+		now:   time.Now(),
+		times: map[string]time.Time{},
+	}
+
+	if _, err := ages.check(context.Background(), candidate{version: version.Must(version.NewVersion("1.0.0"))}); err == nil {
+		t.Error("expected error when release time cannot be determined")
+	}
+}
+
+// newTestRegistry starts a TLS registry serving acme/widget 1.0.0 for
+// linux_amd64 with the v2 API answering v2Status and the archive reporting
+// lastModified. It returns a client trusting the registry and its hostname.
+func newTestRegistry(t *testing.T, v2Status int, lastModified time.Time) (*registry.Client, string) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"providers.v1": "/v1/providers/"}`))
+	})
+	mux.HandleFunc("/v1/providers/acme/widget/versions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions": [{"version": "1.0.0", "protocols": ["5.0"],
+			"platforms": [{"os": "linux", "arch": "amd64"}]}]}`))
+	})
+	mux.HandleFunc("/v2/providers/acme/widget", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(v2Status)
+	})
+	mux.HandleFunc("/v1/providers/acme/widget/1.0.0/download/linux/amd64", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"os": "linux", "arch": "amd64", "download_url": "https://` + r.Host + `/widget.zip"}`))
+	})
+	mux.HandleFunc("/widget.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	})
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	transport, err := httpclient.TransportConfig{TLS: httpclient.TLSConfig{CAFile: caFile}}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return registry.NewClient(&registry.Config{Retries: 1, Transport: transport}), srv.Listener.Addr().String()
+}
+
+func TestReleaseAges_FallsBackWithoutV2API(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	client, host := newTestRegistry(t, http.StatusNotFound, now.Add(-time.Hour))
+
+	ages := &releaseAges{
+		client: client,
+		ep: manifest.ExpandedProvider{
+			Source: manifest.ProviderSource{Hostname: host, Namespace: "acme", Name: "widget"},
+			MinAge: 48 * time.Hour,
+		},
+		now: now,
+	}
+	c := candidate{
+		version:   version.Must(version.NewVersion("1.0.0")),
+		platforms: []registry.ProviderPlatform{{OS: "linux", Arch: "amd64"}},
+	}
+
+	reason, err := ages.check(context.Background(), c)
+	if err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+	if reason == "" {
+		t.Error("expected the archive Last-Modified time to hold 1.0.0 back")
+	}
+}
+
+func TestReleaseAges_V2APIErrorIsReturned(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	client, host := newTestRegistry(t, http.StatusUnauthorized, now.Add(-30*24*time.Hour))
+
+	ages := &releaseAges{
+		client: client,
+		ep: manifest.ExpandedProvider{
+			Source: manifest.ProviderSource{Hostname: host, Namespace: "acme", Name: "widget"},
+			MinAge: 48 * time.Hour,
+		},
+		now: now,
+	}
+	c := candidate{
+		version:   version.Must(version.NewVersion("1.0.0")),
+		platforms: []registry.ProviderPlatform{{OS: "linux", Arch: "amd64"}},
+	}
+
+	_, err := ages.check(context.Background(), c)
+	if err == nil || !strings.Contains(err.Error(), "registry returned 401") {
+		t.Fatalf("expected the v2 API error, got %v", err)
+	}
+}
+
+// --- platform policy tests ---
+
+func TestMatchPlatforms(t *testing.T) {
+	available := []registry.ProviderPlatform{
+		{OS: "linux", Arch: "amd64"},
+		{OS: "darwin", Arch: "arm64"},
+	}
+
+	present, missing := matchPlatforms([]string{"linux_amd64", "windows_arm64", "darwin_arm64"}, available)
+
+	if !reflect.DeepEqual(present, []string{"linux_amd64", "darwin_arm64"}) {
+		t.Errorf("unexpected present platforms: %v", present)
+	}
+
+	if !reflect.DeepEqual(missing, []string{"windows_arm64"}) {
+		t.Errorf("unexpected missing platforms: %v", missing)
+	}
+}
+
+func TestApplyNotes(t *testing.T) {
+	key := versionKey{
+		hostname:  "registry.terraform.io",
+		namespace: "hashicorp",
+		name:      "null",
+		version:   "3.2.3",
+	}
+
+	resolution := buildResolution(
+		map[versionKey]map[string]bool{key: {"linux_amd64": true}},
+		map[versionKey]map[string]bool{key: {"hashicorp/null": true}},
+	)
+
+	applyNotes(
+		resolution, map[versionKey]*versionNotes{
+			key: {
+				missing:      []string{"windows_arm64", "windows_386", "windows_arm64"},
+				fallbackFrom: []string{"3.2.4"},
+			},
+		},
+	)
+
+	rv := resolution.Providers[0].Versions[0]
+	if !reflect.DeepEqual(rv.MissingPlatforms, []string{"windows_386", "windows_arm64"}) {
+		t.Errorf("expected sorted unique missing platforms, got %v", rv.MissingPlatforms)
+	}
+	if !reflect.DeepEqual(rv.FallbackFrom, []string{"3.2.4"}) {
+		t.Errorf("unexpected fallback_from: %v", rv.FallbackFrom)
+	}
+}
+
+func TestMatchPlatforms_Patterns(t *testing.T) {
+	available := []registry.ProviderPlatform{
+		{OS: "linux", Arch: "amd64"},
+		{OS: "linux", Arch: "arm64"},
+		{OS: "darwin", Arch: "arm64"},
+	}
+
+	present, missing := matchPlatforms([]string{"linux_*", "linux_amd64", "windows_*"}, available)
+
+	if !reflect.DeepEqual(present, []string{"linux_amd64", "linux_arm64"}) {
+		t.Errorf("unexpected present platforms: %v", present)
+	}
+
+	// Patterns that match nothing are not reported as missing
+	if len(missing) != 0 {
+		t.Errorf("expected no missing platforms, got %v", missing)
+	}
+
+	present, _ = matchPlatforms([]string{"*"}, available)
+	if len(present) != 3 {
+		t.Errorf("expected * to match all 3 platforms, got %v", present)
+	}
+}
+
+func TestManifestSource_IncludesOrigin(t *testing.T) {
+	ep := manifest.ExpandedProvider{SourceSpec: "hashicorp/null"}
+	if got := manifestSource(ep); got != "hashicorp/null" {
+		t.Errorf("manifestSource() = %q, want %q", got, "hashicorp/null")
+	}
+
+	ep.Origin = "teams/net.yaml"
+	if got := manifestSource(ep); got != "hashicorp/null (teams/net.yaml)" {
+		t.Errorf("manifestSource() = %q, want %q", got, "hashicorp/null (teams/net.yaml)")
+	}
+}
+
+// --- Decision tests ---
+
+func TestDecision_Explain(t *testing.T) {
+	d := Decision{
+		Constraint: "~> 5.0",
+		Candidates: []string{"5.3.0", "5.2.0", "5.1.0", "5.0.0"},
+		Rejected: []SkippedVersion{
+			{Version: "5.3.0", Kind: SkipTooNew, Reason: "released 1d0h ago, younger than min_age 3d0h"},
+			{Version: "5.2.0", Kind: SkipExcluded, Reason: "excluded by manifest (5.2.0)"},
+		},
+		Selected: "5.1.0",
+	}
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"5.1.0", "selected"},
+		{"5.3.0", "held back: released 1d0h ago, younger than min_age 3d0h"},
+		{"5.2.0", "skipped: excluded by manifest (5.2.0)"},
+		{"5.0.0", "matches, but 5.1.0 was selected"},
+		{"4.67.0", "does not match the constraint"},
+	}
+
+	for _, tt := range tests {
+		if got := d.Explain(tt.version); got != tt.want {
+			t.Errorf("Explain(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestSortDecisions(t *testing.T) {
+	aws := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+	tofuAWS := manifest.ProviderSource{Hostname: "registry.opentofu.org", Namespace: "hashicorp", Name: "aws"}
+
+	decisions := sortDecisions(
+		[]Decision{
+			{Provider: aws, ManifestSource: "hashicorp/aws", Constraint: "~> 5.0"},
+			{Provider: aws, ManifestSource: "hashicorp/aws", Constraint: "~> 4.0"},
+			{Provider: tofuAWS, ManifestSource: "hashicorp/aws", Constraint: "~> 5.0"},
+		},
+	)
+
+	var got []string
+	for _, d := range decisions {
+		got = append(got, d.Provider.Hostname+" "+d.Constraint)
+	}
+	want := []string{"registry.opentofu.org ~> 5.0", "registry.terraform.io ~> 4.0", "registry.terraform.io ~> 5.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortDecisions() = %v, want %v", got, want)
+	}
+}
+
+func TestUnmatchedVersions(t *testing.T) {
+	src := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+	pvs := testVersions("5.1.0", "5.2.0-beta1", "not-a-version", "4.0.0-rc1", "5.0.0")
+	c, _ := version.NewConstraint("~> 5.0")
+
+	got := unmatchedVersions(src, pvs, c, "~> 5.0")
+
+	var kinds []string
+	for _, sv := range got {
+		kinds = append(kinds, sv.Version+" "+string(sv.Kind))
+	}
+	want := []string{"5.2.0-beta1 pre-release", "not-a-version unparsable"}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("unmatchedVersions() = %v, want %v", kinds, want)
+	}
+}
+
+func TestResolutionError_Unwrap(t *testing.T) {
+	cause := context.DeadlineExceeded
+	err := &ResolutionError{Err: cause, Decisions: []Decision{{Constraint: "~> 5.0", Error: cause.Error()}}}
+
+	if !errors.Is(err, cause) {
+		t.Error("expected ResolutionError to unwrap to its cause")
+	}
+	if err.Error() != cause.Error() {
+		t.Errorf("Error() = %q, want %q", err.Error(), cause.Error())
+	}
+}
+
+// --- align tests ---
+
+// testState returns the resolution state of a constraint on one registry
+func testState(t *testing.T, hostname string, engine manifest.Engine, exclude []string, versions ...string) *expansionState {
+	t.Helper()
+
+	ep := manifest.ExpandedProvider{
+		Source:    manifest.ProviderSource{Hostname: hostname, Namespace: "hashicorp", Name: "aws"},
+		Versions:  []string{"~> 5.0"},
+		Platforms: []string{"linux_amd64"},
+		Exclude:   exclude,
+		Engine:    engine,
+		Align:     true,
+	}
+	excl, err := newExclusions(ep, nil)
+	if err != nil {
+		t.Fatalf("newExclusions() error = %v", err)
+	}
+	constraint, _ := version.NewConstraint("~> 5.0")
+
+	return &expansionState{
+		ep:         ep,
+		decision:   Decision{Provider: ep.Source, Constraint: "~> 5.0"},
+		exclusions: excl,
+		ages:       &releaseAges{ep: ep, now: time.Now()},
+		candidates: matchCandidates(testVersions(versions...), constraint),
+	}
+}
+
+func TestSelectAligned_NewestCommonVersion(t *testing.T) {
+	tf := testState(t, "registry.terraform.io", manifest.EngineTerraform, nil, "5.100.0", "5.99.0", "5.98.0")
+	tofu := testState(t, "registry.opentofu.org", manifest.EngineOpenTofu, nil, "5.99.0", "5.98.0")
+
+	if _, err := selectAligned(context.Background(), []*expansionState{tf, tofu}); err != nil {
+		t.Fatalf("selectAligned() error = %v", err)
+	}
+
+	for _, s := range []*expansionState{tf, tofu} {
+		if s.selected == nil || s.selected.version.Original() != "5.99.0" {
+			t.Fatalf("%s: expected 5.99.0 to be selected, got %v", s.ep.Source.Hostname, s.selected)
+		}
+	}
+	if len(tf.passedOver) != 1 || tf.passedOver[0].Kind != SkipUnaligned ||
+		tf.passedOver[0].Reason != "not available on registry.opentofu.org" {
+		t.Errorf("unexpected terraform skips: %+v", tf.passedOver)
+	}
+	if len(tofu.passedOver) != 0 {
+		t.Errorf("unexpected opentofu skips: %+v", tofu.passedOver)
+	}
+}
+
+func TestSelectAligned_SkipsVersionIneligibleOnOneRegistry(t *testing.T) {
+	tf := testState(t, "registry.terraform.io", manifest.EngineTerraform, nil, "5.99.0", "5.98.0")
+	tofu := testState(t, "registry.opentofu.org", manifest.EngineOpenTofu, []string{"5.99.0"}, "5.99.0", "5.98.0")
+
+	if _, err := selectAligned(context.Background(), []*expansionState{tf, tofu}); err != nil {
+		t.Fatalf("selectAligned() error = %v", err)
+	}
+
+	if tf.selected == nil || tf.selected.version.Original() != "5.98.0" {
+		t.Fatalf("expected 5.98.0 to be selected, got %v", tf.selected)
+	}
+	if tofu.passedOver[0].Kind != SkipExcluded {
+		t.Errorf("expected opentofu to record the exclusion, got %+v", tofu.passedOver)
+	}
+	want := "not eligible on registry.opentofu.org: excluded by manifest (5.99.0)"
+	if tf.passedOver[0].Kind != SkipUnaligned || tf.passedOver[0].Reason != want {
+		t.Errorf("unexpected terraform skip: %+v", tf.passedOver[0])
+	}
+}
+
+func TestSelectAligned_NoCommonVersion(t *testing.T) {
+	tf := testState(t, "registry.terraform.io", manifest.EngineTerraform, nil, "5.100.0")
+	tofu := testState(t, "registry.opentofu.org", manifest.EngineOpenTofu, nil, "5.99.0")
+
+	if _, err := selectAligned(context.Background(), []*expansionState{tf, tofu}); err != nil {
+		t.Fatalf("selectAligned() error = %v", err)
+	}
+	if tf.selected != nil || tofu.selected != nil {
+		t.Errorf("expected no selection, got %v and %v", tf.selected, tofu.selected)
+	}
+}
+
+func TestDivergenceWarnings(t *testing.T) {
+	tf := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+	tofu := manifest.ProviderSource{Hostname: "registry.opentofu.org", Namespace: "hashicorp", Name: "aws"}
+	private := manifest.ProviderSource{Hostname: "registry.example.com", Namespace: "hashicorp", Name: "aws"}
+
+	decisions := []Decision{
+		{Provider: tf, ManifestSource: "hashicorp/aws", Engine: manifest.EngineTerraform, Constraint: "~> 5.0", Selected: "5.100.0"},
+		{Provider: tofu, ManifestSource: "hashicorp/aws", Engine: manifest.EngineOpenTofu, Constraint: "~> 5.0", Selected: "5.99.0"},
+		{Provider: tf, ManifestSource: "hashicorp/aws", Engine: manifest.EngineTerraform, Constraint: "~> 4.0", Selected: "4.67.0"},
+		{Provider: tofu, ManifestSource: "hashicorp/aws", Engine: manifest.EngineOpenTofu, Constraint: "~> 4.0", Selected: "4.67.0"},
+		{Provider: private, ManifestSource: "registry.example.com/hashicorp/aws", Constraint: "~> 5.0", Selected: "5.1.0"},
+	}
+
+	got := divergenceWarnings(decisions)
+	want := []string{
+		`hashicorp/aws "~> 5.0" resolves to different versions per engine ` +
+			`(terraform 5.100.0 on registry.terraform.io, opentofu 5.99.0 on registry.opentofu.org); ` +
+			`set align: true to mirror the same version`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("divergenceWarnings() = %v, want %v", got, want)
+	}
+}
+
+// --- protocol tests ---
+
+func TestProtocolReason(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowed   []string
+		published []string
+		want      string
+	}{
+		{"no restriction", nil, []string{"6.0"}, ""},
+		{"protocols unknown", []string{"5"}, nil, ""},
+		{"compatible", []string{"5"}, []string{"5.0"}, ""},
+		{"one of several", []string{"5", "6"}, []string{"6.0"}, ""},
+		{"incompatible", []string{"5"}, []string{"6.0"}, "supports plugin protocol 6.0, not 5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := protocolReason(tt.allowed, tt.published); got != tt.want {
+					t.Errorf("protocolReason() = %q, want %q", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestSelectLatest_SkipsIncompatibleProtocols(t *testing.T) {
+	s := testState(t, "registry.terraform.io", manifest.EngineTerraform, nil, "5.1.0", "5.0.0")
+	s.ep.Protocols = []string{"5"}
+	s.candidates[0].protocols = []string{"6.0"}
+	s.candidates[1].protocols = []string{"5.0"}
+
+	if err := s.selectLatest(context.Background()); err != nil {
+		t.Fatalf("selectLatest() error = %v", err)
+	}
+
+	if s.selected == nil || s.selected.version.Original() != "5.0.0" {
+		t.Fatalf("expected 5.0.0 to be selected, got %v", s.selected)
+	}
+	if len(s.passedOver) != 1 || s.passedOver[0].Kind != SkipProtocol {
+		t.Errorf("expected 5.1.0 to be skipped for its protocol, got %+v", s.passedOver)
+	}
+	if got := s.decision.Explain("5.1.0"); got != "skipped: supports plugin protocol 6.0, not 5" {
+		t.Errorf("Explain() = %q", got)
+	}
+}
+
+// --- publish_as tests ---
+
+func TestApplyPublishAs_ConsolidatesOrigins(t *testing.T) {
+	tf := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+	tofu := manifest.ProviderSource{Hostname: "registry.opentofu.org", Namespace: "hashicorp", Name: "aws"}
+	corp := manifest.ProviderSource{Hostname: "registry.corp.example", Namespace: "hashicorp", Name: "aws"}
+	null := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "null"}
+
+	key := func(src manifest.ProviderSource, v string) versionKey {
+		return versionKey{hostname: src.Hostname, namespace: src.Namespace, name: src.Name, version: v}
+	}
+
+	resolution := &Resolution{
+		Providers: []ResolvedProvider{
+			{Source: tofu, Versions: []ResolvedVersion{{Version: "5.99.0"}, {Version: "5.98.0"}}},
+			{Source: tf, Versions: []ResolvedVersion{{Version: "5.100.0"}, {Version: "5.99.0"}}},
+			{Source: null, Versions: []ResolvedVersion{{Version: "3.2.4"}}},
+		},
+	}
+	targets := map[versionKey]manifest.ProviderSource{
+		key(tf, "5.100.0"):  corp,
+		key(tf, "5.99.0"):   corp,
+		key(tofu, "5.99.0"): corp,
+		key(tofu, "5.98.0"): corp,
+		key(null, "3.2.4"):  {},
+	}
+	orders := map[versionKey]int{
+		key(tf, "5.100.0"):  0,
+		key(tf, "5.99.0"):   0,
+		key(tofu, "5.99.0"): 1,
+		key(tofu, "5.98.0"): 1,
+		key(null, "3.2.4"):  2,
+	}
+
+	applyPublishAs(resolution, targets, orders)
+
+	var got []string
+	for _, rp := range resolution.Providers {
+		for _, rv := range rp.Versions {
+			got = append(got, fmt.Sprintf("%s %s from %s", rp.Address(), rv.Version, rp.Source.Hostname))
+		}
+	}
+	want := []string{
+		"registry.corp.example/hashicorp/aws 5.98.0 from registry.opentofu.org",
+		"registry.corp.example/hashicorp/aws 5.100.0 from registry.terraform.io",
+		"registry.corp.example/hashicorp/aws 5.99.0 from registry.terraform.io",
+		"registry.terraform.io/hashicorp/null 3.2.4 from registry.terraform.io",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applyPublishAs() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestApplyPublishAs_NoRewrites(t *testing.T) {
+	tf := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+	resolution := &Resolution{Providers: []ResolvedProvider{{Source: tf, Versions: []ResolvedVersion{{Version: "5.0.0"}}}}}
+
+	applyPublishAs(resolution, map[versionKey]manifest.ProviderSource{}, nil)
+
+	if len(resolution.Providers) != 1 || resolution.Providers[0].Address() != tf {
+		t.Errorf("expected resolution to be unchanged, got %+v", resolution.Providers)
+	}
+}
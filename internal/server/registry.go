@@ -0,0 +1,307 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+)
+
+// providersPath is where the provider registry protocol is served
+const providersPath = "/v1/providers/"
+
+// defaultProtocols are advertised for versions mirrored before protocols
+// were recorded in mirror.lock
+var defaultProtocols = []string{"5.0"}
+
+// registryCache holds the verified mirror.lock and the SHA256SUMS signatures
+// served from it. Both are valid while mirror.lock and its signature are
+// unchanged.
+type registryCache struct {
+	mu         sync.Mutex
+	lockStamp  fileStamp
+	sigStamp   fileStamp
+	lockFile   *mirror.LockFile
+	signatures map[string][]byte // by provider address and version
+}
+
+// fileStamp identifies one version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(info fs.FileInfo) fileStamp {
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// serveRegistry serves the provider registry protocol from mirror.lock. It
+// returns false for paths outside the protocol.
+func (s *Server) serveRegistry(w http.ResponseWriter, r *http.Request, clean string) bool {
+	if clean == "/.well-known/terraform.json" {
+		writeJSON(w, http.StatusOK, registry.ServiceDiscovery{ProvidersV1: providersPath})
+		return true
+	}
+	if !strings.HasPrefix(clean, providersPath) {
+		return false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(clean, providersPath), "/")
+	if len(parts) < 3 {
+		registryError(w, http.StatusNotFound, "not found")
+		return true
+	}
+	provider, err := s.registryProvider(parts[0], parts[1])
+	if err != nil {
+		registryError(w, http.StatusInternalServerError, err.Error())
+		return true
+	}
+	if provider == nil {
+		registryError(w, http.StatusNotFound, fmt.Sprintf("provider %s/%s not found", parts[0], parts[1]))
+		return true
+	}
+
+	switch {
+	case len(parts) == 3 && parts[2] == "versions":
+		s.serveVersions(w, provider)
+	case len(parts) == 6 && parts[3] == "download":
+		s.serveDownload(w, r, provider, parts[2], parts[4], parts[5])
+	case len(parts) == 4 && parts[3] == "SHA256SUMS":
+		s.serveSHA256Sums(w, provider, parts[2], false)
+	case len(parts) == 4 && parts[3] == "SHA256SUMS.sig":
+		s.serveSHA256Sums(w, provider, parts[2], true)
+	default:
+		registryError(w, http.StatusNotFound, "not found")
+	}
+	return true
+}
+
+// registryProvider returns the mirrored provider served by the registry, or
+// nil if it is not mirrored
+func (s *Server) registryProvider(namespace, name string) (*mirror.LockFileProvider, error) {
+	lockFile, err := s.registryLockFile()
+	if lockFile == nil || err != nil {
+		return nil, err
+	}
+	for i, p := range lockFile.Providers {
+		if strings.EqualFold(p.Hostname, s.config.Registry) &&
+			strings.EqualFold(p.Namespace, namespace) &&
+			strings.EqualFold(p.Name, name) {
+			return &lockFile.Providers[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// registryLockFile returns mirror.lock, read again only after it or its
+// signature changed. It returns nil if the mirror has no lock file yet. The
+// lock file must be signed with the registry key, so that checksums edited
+// on disk are never signed and served.
+func (s *Server) registryLockFile() (*mirror.LockFile, error) {
+	lockPath := filepath.Join(s.config.MirrorDir, mirror.LockFileName)
+	sigPath := filepath.Join(s.config.MirrorDir, mirror.LockSignatureFileName)
+
+	lockInfo, err := os.Stat(lockPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading lock file: %w", err)
+	}
+	sigInfo, err := os.Stat(sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("mirror.lock is not signed: %w", err)
+	}
+
+	c := &s.registryCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lockFile != nil && c.lockStamp == stampOf(lockInfo) && c.sigStamp == stampOf(sigInfo) {
+		return c.lockFile, nil
+	}
+
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading lock file: %w", err)
+	}
+	sig, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("mirror.lock is not signed: %w", err)
+	}
+	if _, err := signing.Verify(s.config.Signer.PublicKey(), data, sig); err != nil {
+		return nil, fmt.Errorf("mirror.lock is not signed by the registry key: %w", err)
+	}
+
+	var lockFile mirror.LockFile
+	if err := json.Unmarshal(data, &lockFile); err != nil {
+		return nil, fmt.Errorf("parsing lock file: %w", err)
+	}
+	c.lockStamp, c.sigStamp, c.lockFile = stampOf(lockInfo), stampOf(sigInfo), &lockFile
+	c.signatures = make(map[string][]byte)
+	return &lockFile, nil
+}
+
+// signSHA256Sums returns the signature of a version's SHA256SUMS. Signatures
+// are kept until mirror.lock changes, so the signer runs once per version.
+func (s *Server) signSHA256Sums(provider *mirror.LockFileProvider, version string, sums []byte) ([]byte, error) {
+	key := strings.ToLower(provider.Namespace+"/"+provider.Name) + "/" + version
+
+	c := &s.registryCache
+	c.mu.Lock()
+	lockFile, sig := c.lockFile, c.signatures[key]
+	c.mu.Unlock()
+	if sig != nil {
+		return sig, nil
+	}
+
+	sig, err := s.config.Signer.Sign(sums)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the signature only if the lock file it was made from is current
+	c.mu.Lock()
+	if c.lockFile == lockFile && c.signatures != nil {
+		c.signatures[key] = sig
+	}
+	c.mu.Unlock()
+	return sig, nil
+}
+
+// serveVersions lists the mirrored versions and their platforms
+func (s *Server) serveVersions(w http.ResponseWriter, provider *mirror.LockFileProvider) {
+	result := registry.ProviderVersions{Versions: []registry.ProviderVersion{}}
+	for _, v := range provider.Versions {
+		pv := registry.ProviderVersion{Version: v.Version, Protocols: protocols(v)}
+		for _, p := range v.Platforms {
+			pv.Platforms = append(pv.Platforms, registry.ProviderPlatform{OS: p.OS, Arch: p.Arch})
+		}
+		result.Versions = append(result.Versions, pv)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// serveDownload describes the archive of one platform, pointing at the files
+// of the mirror and the signed SHA256SUMS of the version
+func (s *Server) serveDownload(
+	w http.ResponseWriter,
+	r *http.Request,
+	provider *mirror.LockFileProvider,
+	version, osName, arch string,
+) {
+	v := findVersion(provider, version)
+	if v == nil {
+		registryError(w, http.StatusNotFound, fmt.Sprintf("version %s not found", version))
+		return
+	}
+	for _, p := range v.Platforms {
+		if p.OS != osName || p.Arch != arch {
+			continue
+		}
+
+		base := baseURL(r)
+		versionURL := fmt.Sprintf("%s%s%s/%s/%s/", base, providersPath, provider.Namespace, provider.Name, v.Version)
+		writeJSON(w, http.StatusOK, registry.DownloadInfo{
+			Protocols:           protocols(*v),
+			OS:                  p.OS,
+			Arch:                p.Arch,
+			Filename:            p.Filename,
+			DownloadURL:         fmt.Sprintf("%s/%s/%s/%s/%s", base, provider.Hostname, provider.Namespace, provider.Name, p.Filename),
+			SHA256Sum:           p.SHA256,
+			SHA256SumsURL:       versionURL + "SHA256SUMS",
+			SHA256SumsSignature: versionURL + "SHA256SUMS.sig",
+			SigningKeys: registry.SigningKeys{
+				GPGPublicKeys: []registry.GPGPublicKey{{
+					KeyID:      s.config.Signer.KeyID(),
+					ASCIIArmor: s.config.Signer.PublicKey(),
+				}},
+			},
+		})
+		return
+	}
+	registryError(w, http.StatusNotFound, fmt.Sprintf("platform %s_%s not found for version %s", osName, arch, version))
+}
+
+// serveSHA256Sums serves the checksums of a version's archives, or their
+// detached signature
+func (s *Server) serveSHA256Sums(w http.ResponseWriter, provider *mirror.LockFileProvider, version string, signature bool) {
+	v := findVersion(provider, version)
+	if v == nil {
+		registryError(w, http.StatusNotFound, fmt.Sprintf("version %s not found", version))
+		return
+	}
+
+	checksums := make(map[string]string, len(v.Platforms))
+	for _, p := range v.Platforms {
+		if p.SHA256 != "" {
+			checksums[p.Filename] = p.SHA256
+		}
+	}
+	sums := signing.SHA256Sums(checksums)
+
+	if !signature {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(sums)
+		return
+	}
+
+	sig, err := s.signSHA256Sums(provider, v.Version, sums)
+	if err != nil {
+		registryError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(sig)
+}
+
+// findVersion returns a mirrored version of a provider, or nil
+func findVersion(provider *mirror.LockFileProvider, version string) *mirror.LockFileVersion {
+	for i, v := range provider.Versions {
+		if v.Version == version {
+			return &provider.Versions[i]
+		}
+	}
+	return nil
+}
+
+// protocols returns the plugin protocols advertised for a version
+func protocols(v mirror.LockFileVersion) []string {
+	if len(v.Protocols) == 0 {
+		return defaultProtocols
+	}
+	return v.Protocols
+}
+
+// baseURL returns the scheme and host clients used to reach the server
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// registryError writes an error in the registry protocol's format
+func registryError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string][]string{"errors": {msg}})
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing/signingtest"
+)
+
+// writeSignedLockFile writes mirror.lock and its signature to dir
+func writeSignedLockFile(t *testing.T, dir string, lockFile *mirror.LockFile, signer signing.Signer) {
+	t.Helper()
+
+	data, err := json.Marshal(lockFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signer.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, mirror.LockFileName), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, mirror.LockSignatureFileName), sig, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newRegistryServer serves a mirror holding hashicorp/null 3.2.3 from
+// registry.terraform.io over the registry protocol
+func newRegistryServer(t *testing.T) *Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	lockFile := mirror.LockFile{
+		Version: 1,
+		Providers: []mirror.LockFileProvider{
+			{
+				Hostname:  "registry.terraform.io",
+				Namespace: "hashicorp",
+				Name:      "null",
+				Versions: []mirror.LockFileVersion{{
+					Version:   "3.2.3",
+					Protocols: []string{"6.0"},
+					Platforms: []mirror.LockFilePlatform{
+						{OS: "linux", Arch: "amd64", Filename: "terraform-provider-null_3.2.3_linux_amd64.zip", SHA256: "aaa"},
+						{OS: "darwin", Arch: "arm64", Filename: "terraform-provider-null_3.2.3_darwin_arm64.zip", SHA256: "bbb"},
+					},
+				}},
+			},
+			{
+				Hostname:  "registry.opentofu.org",
+				Namespace: "hashicorp",
+				Name:      "random",
+				Versions:  []mirror.LockFileVersion{{Version: "3.6.0"}},
+			},
+		},
+	}
+	signer := signingtest.NewSigner(t)
+	writeSignedLockFile(t, dir, &lockFile, signer)
+
+	s, err := New(Config{MirrorDir: dir, Registry: "registry.terraform.io", Signer: signer}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// --- Registry protocol tests ---
+
+func TestNew_RegistryRequiresSigner(t *testing.T) {
+	if _, err := New(Config{MirrorDir: t.TempDir(), Registry: "registry.terraform.io"}, nil, nil); err == nil {
+		t.Error("expected error without a signer")
+	}
+}
+
+func TestRegistry_Discovery(t *testing.T) {
+	s := newRegistryServer(t)
+
+	rec := get(s, "/.well-known/terraform.json")
+	var discovery registry.ServiceDiscovery
+	if err := json.Unmarshal(rec.Body.Bytes(), &discovery); err != nil || discovery.ProvidersV1 != "/v1/providers/" {
+		t.Errorf("unexpected discovery document %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRegistry_Versions(t *testing.T) {
+	s := newRegistryServer(t)
+
+	rec := get(s, "/v1/providers/HashiCorp/null/versions")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("versions: %d %s", rec.Code, rec.Body)
+	}
+	var versions registry.ProviderVersions
+	if err := json.Unmarshal(rec.Body.Bytes(), &versions); err != nil {
+		t.Fatal(err)
+	}
+	if len(versions.Versions) != 1 {
+		t.Fatalf("expected one version, got %+v", versions.Versions)
+	}
+	v := versions.Versions[0]
+	if v.Version != "3.2.3" || len(v.Platforms) != 2 || len(v.Protocols) != 1 || v.Protocols[0] != "6.0" {
+		t.Errorf("unexpected version %+v", v)
+	}
+
+	// Providers of other mirrored hostnames are not part of the registry
+	if rec := get(s, "/v1/providers/hashicorp/random/versions"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another hostname, got %d", rec.Code)
+	}
+}
+
+func TestRegistry_Download(t *testing.T) {
+	s := newRegistryServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/null/3.2.3/download/linux/amd64", nil)
+	req.Host = "mirror.example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download: %d %s", rec.Code, rec.Body)
+	}
+
+	var info registry.DownloadInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	wantURL := "https://mirror.example.com/registry.terraform.io/hashicorp/null/terraform-provider-null_3.2.3_linux_amd64.zip"
+	if info.DownloadURL != wantURL || info.SHA256Sum != "aaa" || info.Protocols[0] != "6.0" {
+		t.Errorf("unexpected download info %+v", info)
+	}
+	if info.SHA256SumsURL != "https://mirror.example.com/v1/providers/hashicorp/null/3.2.3/SHA256SUMS" ||
+		info.SHA256SumsSignature != info.SHA256SumsURL+".sig" {
+		t.Errorf("unexpected SHA256SUMS URLs %s, %s", info.SHA256SumsURL, info.SHA256SumsSignature)
+	}
+	if len(info.SigningKeys.GPGPublicKeys) != 1 {
+		t.Fatalf("expected one signing key, got %+v", info.SigningKeys)
+	}
+	key := info.SigningKeys.GPGPublicKeys[0]
+
+	sums := get(s, "/v1/providers/hashicorp/null/3.2.3/SHA256SUMS")
+	if !strings.Contains(sums.Body.String(), "aaa  terraform-provider-null_3.2.3_linux_amd64.zip\n") {
+		t.Errorf("unexpected SHA256SUMS %q", sums.Body)
+	}
+	sig := get(s, "/v1/providers/hashicorp/null/3.2.3/SHA256SUMS.sig")
+	keyID, err := signing.Verify(key.ASCIIArmor, sums.Body.Bytes(), sig.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if keyID != key.KeyID {
+		t.Errorf("signed by %s, want %s", keyID, key.KeyID)
+	}
+}
+
+// countingSigner counts the signatures it makes
+type countingSigner struct {
+	signing.Signer
+	signed int
+}
+
+func (c *countingSigner) Sign(data []byte) ([]byte, error) {
+	c.signed++
+	return c.Signer.Sign(data)
+}
+
+func TestRegistry_CachesUntilLockFileChanges(t *testing.T) {
+	s := newRegistryServer(t)
+	signer := &countingSigner{Signer: s.config.Signer}
+	s.config.Signer = signer
+
+	for i := 0; i < 2; i++ {
+		if rec := get(s, "/v1/providers/hashicorp/null/3.2.3/SHA256SUMS.sig"); rec.Code != http.StatusOK {
+			t.Fatalf("signature: %d %s", rec.Code, rec.Body)
+		}
+	}
+	if signer.signed != 1 {
+		t.Errorf("expected one signature for repeated requests, got %d", signer.signed)
+	}
+
+	// A rewritten lock file is read again and signed again
+	lockPath := filepath.Join(s.config.MirrorDir, mirror.LockFileName)
+	lockFile, err := mirror.ReadLockFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockFile.Providers[0].Versions[0].Platforms[0].SHA256 = "ccc"
+	writeSignedLockFile(t, s.config.MirrorDir, lockFile, signer.Signer)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(lockPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if sums := get(s, "/v1/providers/hashicorp/null/3.2.3/SHA256SUMS"); !strings.Contains(sums.Body.String(), "ccc  ") {
+		t.Errorf("expected checksums of the rewritten lock file, got %q", sums.Body)
+	}
+	get(s, "/v1/providers/hashicorp/null/3.2.3/SHA256SUMS.sig")
+	if signer.signed != 2 {
+		t.Errorf("expected a new signature after the lock file changed, got %d", signer.signed)
+	}
+}
+
+func TestRegistry_RejectsTamperedLockFile(t *testing.T) {
+	s := newRegistryServer(t)
+	lockPath := filepath.Join(s.config.MirrorDir, mirror.LockFileName)
+
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockPath, []byte(strings.Replace(string(data), `"aaa"`, `"evil"`, 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		"/v1/providers/hashicorp/null/3.2.3/SHA256SUMS",
+		"/v1/providers/hashicorp/null/3.2.3/SHA256SUMS.sig",
+	} {
+		rec := get(s, path)
+		if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "evil") {
+			t.Errorf("%s: expected a 500 for a tampered lock file, got %d %s", path, rec.Code, rec.Body)
+		}
+	}
+
+	// An unsigned lock file is refused as well
+	if err := os.Remove(filepath.Join(s.config.MirrorDir, mirror.LockSignatureFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if rec := get(s, "/v1/providers/hashicorp/null/versions"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected a 500 for an unsigned lock file, got %d", rec.Code)
+	}
+}
+
+func TestRegistry_NotFound(t *testing.T) {
+	s := newRegistryServer(t)
+
+	paths := []string{
+		"/v1/providers/hashicorp/null/9.9.9/download/linux/amd64",
+		"/v1/providers/hashicorp/null/3.2.3/download/windows/amd64",
+		"/v1/providers/hashicorp/null/9.9.9/SHA256SUMS",
+		"/v1/providers/hashicorp/aws/versions",
+		"/v1/providers/hashicorp/null/other",
+		"/v1/providers/hashicorp",
+	}
+	for _, path := range paths {
+		rec := get(s, path)
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"errors"`) {
+			t.Errorf("%s: expected a 404 registry error, got %d %s", path, rec.Code, rec.Body)
+		}
+	}
+}
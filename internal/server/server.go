@@ -0,0 +1,360 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/downloader"
+	"github.com/petroprotsakh/go-provider-mirror/internal/logging"
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
+	"github.com/petroprotsakh/go-provider-mirror/internal/resolver"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+)
+
+// Config configures the mirror server
+type Config struct {
+	MirrorDir string
+
+	// Upstreams lists the registry hostnames providers missing from the
+	// mirror may be fetched from. Empty serves the mirror as is.
+	Upstreams []string
+
+	// Allow lists the providers that may be fetched, as namespace,
+	// namespace/name or hostname/namespace/name, where name may be *
+	Allow []string
+
+	// Platforms limits the platforms fetched on demand; empty fetches every
+	// platform a version publishes. Entries may be wildcard patterns.
+	Platforms []string
+
+	// Registry is the mirror hostname whose providers are also served over
+	// the provider registry protocol. Empty serves the network mirror
+	// protocol only.
+	Registry string
+
+	// Signer signs the SHA256SUMS files served by the registry and those of
+	// versions fetched from upstream
+	Signer signing.Signer
+}
+
+// Server serves a mirror directory using the provider network mirror
+// protocol, optionally fetching missing provider versions from upstream
+// registries on first request. It can also act as a provider registry for
+// one mirrored hostname.
+type Server struct {
+	config     Config
+	allow      []allowRule
+	client     *registry.Client
+	downloader *downloader.Downloader
+	writer     *mirror.Writer
+	files      http.Handler
+	log        *logging.Logger
+
+	mu       sync.Mutex               // guards fetching
+	fetching map[string]*versionFetch // by provider version, while requested
+	writing  sync.Mutex               // serializes writes to the mirror
+
+	registryCache registryCache // mirror.lock as served by the registry
+}
+
+// versionFetch serializes the requests for one provider version
+type versionFetch struct {
+	sync.Mutex
+	waiters int // requests holding or waiting for the lock; guarded by Server.mu
+}
+
+// New creates a server. The client and downloader are used only when
+// upstreams are configured.
+func New(config Config, client *registry.Client, dl *downloader.Downloader) (*Server, error) {
+	if len(config.Upstreams) > 0 && len(config.Allow) == 0 {
+		return nil, errors.New("fetching from upstream registries requires at least one allowed provider")
+	}
+	if config.Registry != "" && config.Signer == nil {
+		return nil, errors.New("serving the registry protocol requires a signing key")
+	}
+	// Unsigned writes would drop the signature of mirror.lock
+	if len(config.Upstreams) > 0 && config.Signer == nil && mirror.IsSigned(config.MirrorDir) {
+		return nil, errors.New("fetching from upstream registries into a signed mirror requires its signing key")
+	}
+
+	s := &Server{
+		config:     config,
+		client:     client,
+		downloader: dl,
+		writer:     mirror.NewWriter(config.MirrorDir).WithSigner(config.Signer),
+		files:      http.FileServer(http.Dir(config.MirrorDir)),
+		log:        logging.Default(),
+		fetching:   make(map[string]*versionFetch),
+	}
+	for i, host := range s.config.Upstreams {
+		s.config.Upstreams[i] = strings.ToLower(host)
+	}
+	for _, pattern := range config.Allow {
+		rule, err := parseAllowRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+		s.allow = append(s.allow, rule)
+	}
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Directory listings are not part of the protocol
+	clean := path.Clean("/" + r.URL.Path)
+	if info, err := os.Stat(filepath.Join(s.config.MirrorDir, filepath.FromSlash(clean))); err == nil && info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	if s.config.Registry != "" && s.serveRegistry(w, r, clean) {
+		return
+	}
+
+	if src, file, ok := s.upstreamRequest(clean); ok {
+		switch {
+		case file == "index.json":
+			s.serveIndex(w, r, src)
+			return
+		case strings.HasSuffix(file, ".json"):
+			if err := s.ensureVersion(r.Context(), src, strings.TrimSuffix(file, ".json")); err != nil {
+				s.fetchError(w, src, err)
+				return
+			}
+		}
+	}
+
+	s.files.ServeHTTP(w, r)
+}
+
+// upstreamRequest returns the provider of a request path that may be fetched
+// from an upstream registry
+func (s *Server) upstreamRequest(clean string) (manifest.ProviderSource, string, bool) {
+	parts := strings.Split(strings.TrimPrefix(clean, "/"), "/")
+	if len(parts) != 4 {
+		return manifest.ProviderSource{}, "", false
+	}
+
+	src, err := manifest.ParseProviderSource(strings.Join(parts[:3], "/"))
+	if err != nil || !slices.Contains(s.config.Upstreams, strings.ToLower(src.Hostname)) {
+		return manifest.ProviderSource{}, "", false
+	}
+	src.Hostname = strings.ToLower(src.Hostname)
+	if !slices.ContainsFunc(s.allow, func(rule allowRule) bool { return rule.matches(src) }) {
+		return manifest.ProviderSource{}, "", false
+	}
+	return src, parts[3], true
+}
+
+// serveIndex lists the versions available upstream along with those already
+// mirrored. When the upstream registry fails, the mirrored versions are
+// listed alone.
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request, src manifest.ProviderSource) {
+	index := mirror.IndexJSON{Versions: make(map[string]struct{})}
+
+	local, localErr := os.ReadFile(s.providerPath(src, "index.json"))
+	if localErr == nil {
+		if err := json.Unmarshal(local, &index); err != nil || index.Versions == nil {
+			index.Versions = make(map[string]struct{})
+		}
+	}
+
+	versions, err := s.client.GetVersions(r.Context(), src.Hostname, src.Namespace, src.Name)
+	if err != nil {
+		if localErr != nil {
+			s.fetchError(w, src, err)
+			return
+		}
+		s.warn("listing upstream versions", src, err)
+	} else {
+		for _, v := range versions.Versions {
+			if len(s.platforms(v)) > 0 {
+				index.Versions[v.Version] = struct{}{}
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(index)
+}
+
+// ensureVersion fetches a provider version into the mirror unless it is
+// already there. Concurrent requests for the same version wait for a single
+// fetch.
+func (s *Server) ensureVersion(ctx context.Context, src manifest.ProviderSource, version string) error {
+	versionPath := s.providerPath(src, version+".json")
+	if _, err := os.Stat(versionPath); err == nil {
+		return nil
+	}
+
+	key := src.String() + "@" + version
+	s.mu.Lock()
+	vf, ok := s.fetching[key]
+	if !ok {
+		vf = &versionFetch{}
+		s.fetching[key] = vf
+	}
+	vf.waiters++
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		if vf.waiters--; vf.waiters == 0 {
+			delete(s.fetching, key)
+		}
+		s.mu.Unlock()
+	}()
+
+	vf.Lock()
+	defer vf.Unlock()
+	if _, err := os.Stat(versionPath); err == nil {
+		return nil
+	}
+
+	// Finish the fetch for other waiting clients if this one disconnects
+	return s.fetch(context.WithoutCancel(ctx), src, version)
+}
+
+// fetch downloads a provider version from upstream and adds it to the mirror
+func (s *Server) fetch(ctx context.Context, src manifest.ProviderSource, version string) error {
+	versions, err := s.client.GetVersions(ctx, src.Hostname, src.Namespace, src.Name)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(versions.Versions, func(v registry.ProviderVersion) bool { return v.Version == version })
+	if i < 0 {
+		return errNotFound
+	}
+	platforms := s.platforms(versions.Versions[i])
+	if len(platforms) == 0 {
+		return errNotFound
+	}
+
+	if s.log.IsNormal() {
+		s.log.Print("Fetching %s %s (%d platforms)\n", src.String(), version, len(platforms))
+	} else {
+		s.log.Info("fetching provider", "provider", src.String(), "version", version, "platforms", platforms)
+	}
+
+	resolution := &resolver.Resolution{
+		Providers: []resolver.ResolvedProvider{{
+			Source: src,
+			Versions: []resolver.ResolvedVersion{{
+				Version:   version,
+				Platforms: platforms,
+				Protocols: versions.Versions[i].Protocols,
+			}},
+		}},
+	}
+	results, err := s.downloader.Download(ctx, resolution)
+	if err != nil {
+		return err
+	}
+
+	s.writing.Lock()
+	defer s.writing.Unlock()
+	return s.writer.Add(ctx, results)
+}
+
+// platforms returns the platforms of a version that are fetched on demand
+func (s *Server) platforms(v registry.ProviderVersion) []string {
+	var platforms []string
+	for _, p := range v.Platforms {
+		platform := p.String()
+		if len(s.config.Platforms) == 0 || slices.ContainsFunc(s.config.Platforms, func(pattern string) bool {
+			return manifest.MatchPlatform(pattern, platform)
+		}) {
+			platforms = append(platforms, platform)
+		}
+	}
+	slices.Sort(platforms)
+	return platforms
+}
+
+// providerPath returns the path of a file in a provider's mirror directory
+func (s *Server) providerPath(src manifest.ProviderSource, file string) string {
+	return filepath.Join(s.config.MirrorDir, src.Hostname, src.Namespace, src.Name, file)
+}
+
+var errNotFound = errors.New("not found upstream")
+
+// fetchError reports a failed upstream request to the client
+func (s *Server) fetchError(w http.ResponseWriter, src manifest.ProviderSource, err error) {
+	if errors.Is(err, errNotFound) {
+		http.Error(w, fmt.Sprintf("%s: %v", src.String(), err), http.StatusNotFound)
+		return
+	}
+	s.warn("fetching from upstream", src, err)
+	http.Error(w, fmt.Sprintf("%s: fetching from upstream failed", src.String()), http.StatusBadGateway)
+}
+
+// warn reports an upstream problem
+func (s *Server) warn(msg string, src manifest.ProviderSource, err error) {
+	if s.log.IsNormal() {
+		s.log.Print("Warning: %s %s: %v\n", msg, src.String(), err)
+	} else {
+		s.log.Warn(msg, "provider", src.String(), "error", err)
+	}
+}
+
+// allowRule matches providers that may be fetched from upstream
+type allowRule struct {
+	hostname  string // empty matches any upstream
+	namespace string
+	name      string // * matches any name
+}
+
+// parseAllowRule parses namespace, namespace/name or hostname/namespace/name
+func parseAllowRule(pattern string) (allowRule, error) {
+	invalid := fmt.Errorf(
+		"invalid allowed provider %q: expected namespace, namespace/name or hostname/namespace/name", pattern,
+	)
+
+	parts := strings.Split(pattern, "/")
+	if len(parts) == 1 {
+		parts = append(parts, "*")
+	}
+	name := parts[len(parts)-1]
+	if name == "*" {
+		parts[len(parts)-1] = "any" // validated as a literal name
+	}
+
+	src, err := manifest.ParseProviderSource(strings.Join(parts, "/"))
+	if err != nil {
+		return allowRule{}, invalid
+	}
+	if name == "*" {
+		src.Name = name
+	}
+
+	return allowRule{
+		hostname:  strings.ToLower(src.Hostname),
+		namespace: strings.ToLower(src.Namespace),
+		name:      strings.ToLower(src.Name),
+	}, nil
+}
+
+// matches returns true if the rule allows a provider
+func (r allowRule) matches(src manifest.ProviderSource) bool {
+	return (r.hostname == "" || r.hostname == strings.ToLower(src.Hostname)) &&
+		r.namespace == strings.ToLower(src.Namespace) &&
+		(r.name == "*" || r.name == strings.ToLower(src.Name))
+}
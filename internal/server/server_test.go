@@ -0,0 +1,416 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/downloader"
+	"github.com/petroprotsakh/go-provider-mirror/internal/httpclient"
+	"github.com/petroprotsakh/go-provider-mirror/internal/manifest"
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+	"github.com/petroprotsakh/go-provider-mirror/internal/registry"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing/signingtest"
+)
+
+// upstream is a fake registry serving hashicorp/null 3.2.3 for two platforms
+type upstream struct {
+	*httptest.Server
+	archive   []byte
+	downloads atomic.Int32
+
+	signer    signing.Signer // signs SHA256SUMS when set
+	signature []byte         // served instead of the signature when set
+}
+
+func newUpstream(t *testing.T) *upstream {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, _ := zw.Create("terraform-provider-null_v3.2.3")
+	_, _ = f.Write([]byte("binary"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	u := &upstream{archive: buf.Bytes()}
+	sum := sha256.Sum256(u.archive)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"providers.v1": "/v1/providers/"}`))
+	})
+	mux.HandleFunc("/v1/providers/hashicorp/null/versions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions": [{"version": "3.2.3", "protocols": ["5.0"], "platforms": [
+			{"os": "linux", "arch": "amd64"}, {"os": "windows", "arch": "amd64"}]}]}`))
+	})
+	mux.HandleFunc("/v1/providers/hashicorp/null/3.2.3/download/{os}/{arch}", func(w http.ResponseWriter, r *http.Request) {
+		filename := fmt.Sprintf("terraform-provider-null_3.2.3_%s_%s.zip", r.PathValue("os"), r.PathValue("arch"))
+		info := registry.DownloadInfo{
+			OS:          r.PathValue("os"),
+			Arch:        r.PathValue("arch"),
+			Filename:    filename,
+			DownloadURL: u.URL + "/archives/" + filename,
+			SHA256Sum:   hex.EncodeToString(sum[:]),
+		}
+		if u.signer != nil {
+			info.SHA256SumsURL = u.URL + "/archives/SHA256SUMS"
+			info.SHA256SumsSignature = u.URL + "/archives/SHA256SUMS.sig"
+			info.SigningKeys.GPGPublicKeys = []registry.GPGPublicKey{
+				{KeyID: u.signer.KeyID(), ASCIIArmor: u.signer.PublicKey()},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(info)
+	})
+	mux.HandleFunc("/archives/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(u.sha256Sums())
+	})
+	mux.HandleFunc("/archives/SHA256SUMS.sig", func(w http.ResponseWriter, r *http.Request) {
+		if u.signature != nil {
+			_, _ = w.Write(u.signature)
+			return
+		}
+		sig, _ := u.signer.Sign(u.sha256Sums())
+		_, _ = w.Write(sig)
+	})
+	mux.HandleFunc("/archives/", func(w http.ResponseWriter, r *http.Request) {
+		u.downloads.Add(1)
+		_, _ = w.Write(u.archive)
+	})
+
+	u.Server = httptest.NewTLSServer(mux)
+	t.Cleanup(u.Close)
+	return u
+}
+
+// sha256Sums returns the SHA256SUMS file of the upstream release
+func (u *upstream) sha256Sums() []byte {
+	sum := sha256.Sum256(u.archive)
+	return signing.SHA256Sums(map[string]string{
+		"terraform-provider-null_3.2.3_linux_amd64.zip":   hex.EncodeToString(sum[:]),
+		"terraform-provider-null_3.2.3_windows_amd64.zip": hex.EncodeToString(sum[:]),
+	})
+}
+
+// host returns the upstream registry hostname
+func (u *upstream) host() string {
+	return u.Listener.Addr().String()
+}
+
+// newTestServer creates a server fetching from the fake upstream
+func newTestServer(t *testing.T, u *upstream, cfg Config) *Server {
+	t.Helper()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: u.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	transport, err := httpclient.TransportConfig{TLS: httpclient.TLSConfig{CAFile: caFile}}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := registry.NewClient(&registry.Config{Retries: 1, Transport: transport})
+	dl := downloader.New(downloader.Config{CacheDir: t.TempDir(), Retries: 1, Transport: transport}, client)
+
+	if cfg.MirrorDir == "" {
+		cfg.MirrorDir = t.TempDir()
+	}
+	s, err := New(cfg, client, dl)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return s
+}
+
+// get performs a request against the server
+func get(s *Server, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+// --- Allowlist tests ---
+
+func TestAllowRule(t *testing.T) {
+	src := manifest.ProviderSource{Hostname: "registry.terraform.io", Namespace: "hashicorp", Name: "aws"}
+
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"hashicorp", true},
+		{"HashiCorp/*", true},
+		{"hashicorp/aws", true},
+		{"hashicorp/google", false},
+		{"registry.terraform.io/hashicorp/aws", true},
+		{"registry.opentofu.org/hashicorp/aws", false},
+		{"registry.terraform.io/hashicorp/*", true},
+		{"integrations", false},
+	}
+
+	for _, tt := range tests {
+		rule, err := parseAllowRule(tt.pattern)
+		if err != nil {
+			t.Errorf("parseAllowRule(%q) error = %v", tt.pattern, err)
+			continue
+		}
+		if got := rule.matches(src); got != tt.want {
+			t.Errorf("%q matches %s = %v, want %v", tt.pattern, src, got, tt.want)
+		}
+	}
+
+	for _, pattern := range []string{"", "hashicorp/", "*/aws", "a/b/c/d", "hashicorp/a*"} {
+		if _, err := parseAllowRule(pattern); err == nil {
+			t.Errorf("expected error for %q", pattern)
+		}
+	}
+}
+
+func TestNew_UpstreamRequiresAllowlist(t *testing.T) {
+	if _, err := New(Config{MirrorDir: t.TempDir(), Upstreams: []string{"registry.terraform.io"}}, nil, nil); err == nil {
+		t.Error("expected error without allowed providers")
+	}
+}
+
+func TestNew_UpstreamIntoSignedMirrorRequiresSigner(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, mirror.LockSignatureFileName), []byte("sig"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{MirrorDir: dir, Upstreams: []string{"registry.terraform.io"}, Allow: []string{"hashicorp/*"}}
+	if _, err := New(cfg, nil, nil); err == nil || !strings.Contains(err.Error(), "signed mirror") {
+		t.Errorf("expected error without a signer, got %v", err)
+	}
+
+	cfg.Signer = signingtest.NewSigner(t)
+	if _, err := New(cfg, nil, nil); err != nil {
+		t.Errorf("New() error = %v", err)
+	}
+}
+
+// --- Static serving tests ---
+
+func TestServe_LocalMirror(t *testing.T) {
+	dir := t.TempDir()
+	providerDir := filepath.Join(dir, "registry.terraform.io", "hashicorp", "null")
+	if err := os.MkdirAll(providerDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(providerDir, "index.json"), []byte(`{"versions":{"3.2.3":{}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(Config{MirrorDir: dir}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := get(s, "/registry.terraform.io/hashicorp/null/index.json"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "3.2.3") {
+		t.Errorf("expected index.json, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := get(s, "/registry.terraform.io/hashicorp/"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected no directory listing, got %d", rec.Code)
+	}
+	if rec := get(s, "/registry.terraform.io/hashicorp/null/3.2.4.json"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without upstreams, got %d", rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/registry.terraform.io/hashicorp/null/index.json", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for POST, got %d", rec.Code)
+	}
+}
+
+// --- Pull-through tests ---
+
+func TestServe_PullThrough(t *testing.T) {
+	u := newUpstream(t)
+	s := newTestServer(t, u, Config{
+		Upstreams: []string{u.host()},
+		Allow:     []string{"hashicorp"},
+		Platforms: []string{"linux_*"},
+	})
+	base := "/" + u.host() + "/hashicorp/null/"
+
+	rec := get(s, base+"index.json")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("index.json: %d %s", rec.Code, rec.Body)
+	}
+	var index mirror.IndexJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &index); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := index.Versions["3.2.3"]; !ok {
+		t.Errorf("expected upstream versions in index.json, got %v", index.Versions)
+	}
+
+	rec = get(s, base+"3.2.3.json")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("3.2.3.json: %d %s", rec.Code, rec.Body)
+	}
+	var version mirror.VersionJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &version); err != nil {
+		t.Fatal(err)
+	}
+	archive, ok := version.Archives["linux_amd64"]
+	if !ok || len(version.Archives) != 1 || !strings.HasPrefix(archive.Hashes[0], "h1:") {
+		t.Fatalf("expected only the linux_amd64 archive with an h1 hash, got %+v", version.Archives)
+	}
+
+	rec = get(s, base+archive.URL)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), u.archive) {
+		t.Errorf("expected the archive to be served, got %d", rec.Code)
+	}
+
+	// A second request is served from the mirror
+	if rec := get(s, base+"3.2.3.json"); rec.Code != http.StatusOK {
+		t.Errorf("expected cached version, got %d", rec.Code)
+	}
+	if n := u.downloads.Load(); n != 1 {
+		t.Errorf("expected one upstream download, got %d", n)
+	}
+
+	lockFile, err := mirror.ReadLockFile(filepath.Join(s.config.MirrorDir, mirror.LockFileName))
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	if len(lockFile.Providers) != 1 || lockFile.Providers[0].Hostname != u.host() {
+		t.Fatalf("unexpected lock file: %+v", lockFile.Providers)
+	}
+	if got := lockFile.Providers[0].Versions[0].Protocols; len(got) != 1 || got[0] != "5.0" {
+		t.Errorf("expected upstream protocols in lock file, got %v", got)
+	}
+}
+
+func TestServe_PullThroughConcurrentRequests(t *testing.T) {
+	u := newUpstream(t)
+	s := newTestServer(t, u, Config{
+		Upstreams: []string{u.host()},
+		Allow:     []string{"hashicorp"},
+		Platforms: []string{"linux_amd64"},
+	})
+	path := "/" + u.host() + "/hashicorp/null/3.2.3.json"
+
+	var wg sync.WaitGroup
+	codes := make([]int, 4)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = get(s, path).Code
+		}()
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: got %d", i, code)
+		}
+	}
+	if n := u.downloads.Load(); n != 1 {
+		t.Errorf("expected one upstream download, got %d", n)
+	}
+	if len(s.fetching) != 0 {
+		t.Errorf("expected no fetch locks left, got %d", len(s.fetching))
+	}
+}
+
+func TestServe_PullThroughRejected(t *testing.T) {
+	u := newUpstream(t)
+	s := newTestServer(t, u, Config{
+		Upstreams: []string{u.host()},
+		Allow:     []string{"integrations"},
+	})
+
+	tests := []string{
+		"/" + u.host() + "/hashicorp/null/3.2.3.json",           // not allowed
+		"/registry.terraform.io/integrations/github/index.json", // not an upstream
+	}
+	for _, path := range tests {
+		if rec := get(s, path); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+
+	s = newTestServer(t, u, Config{Upstreams: []string{u.host()}, Allow: []string{"hashicorp/null"}})
+	if rec := get(s, "/"+u.host()+"/hashicorp/null/9.9.9.json"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown version, got %d", rec.Code)
+	}
+	if n := u.downloads.Load(); n != 0 {
+		t.Errorf("expected no downloads, got %d", n)
+	}
+}
+
+func TestServe_PullThroughVerifiesUpstreamSignature(t *testing.T) {
+	u := newUpstream(t)
+	u.signer = signingtest.NewSigner(t)
+	org := signingtest.NewSigner(t)
+	s := newTestServer(t, u, Config{
+		Upstreams: []string{u.host()},
+		Allow:     []string{"hashicorp"},
+		Platforms: []string{"linux_amd64"},
+		Signer:    org,
+	})
+
+	if rec := get(s, "/"+u.host()+"/hashicorp/null/3.2.3.json"); rec.Code != http.StatusOK {
+		t.Fatalf("3.2.3.json: %d %s", rec.Code, rec.Body)
+	}
+
+	lockFile, err := mirror.ReadLockFile(filepath.Join(s.config.MirrorDir, mirror.LockFileName))
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	lv := lockFile.Providers[0].Versions[0]
+	if lv.UpstreamKeyID != u.signer.KeyID() || lv.SigningKeyID != org.KeyID() {
+		t.Errorf("unexpected key IDs %q, %q", lv.UpstreamKeyID, lv.SigningKeyID)
+	}
+
+	sumsPath := s.providerPath(
+		manifest.ProviderSource{Hostname: u.host(), Namespace: "hashicorp", Name: "null"},
+		mirror.SHA256SumsFilename("null", "3.2.3"),
+	)
+	upstreamSums, err := os.ReadFile(sumsPath + mirror.UpstreamSuffix)
+	if err != nil || !bytes.Equal(upstreamSums, u.sha256Sums()) {
+		t.Errorf("expected the upstream SHA256SUMS to be retained, got %q, %v", upstreamSums, err)
+	}
+	sums, _ := os.ReadFile(sumsPath)
+	sig, _ := os.ReadFile(sumsPath + mirror.SignatureSuffix)
+	if _, err := signing.Verify(org.PublicKey(), sums, sig); err != nil {
+		t.Errorf("expected SHA256SUMS signed by the organization key: %v", err)
+	}
+}
+
+func TestServe_PullThroughRejectsBadUpstreamSignature(t *testing.T) {
+	u := newUpstream(t)
+	u.signer = signingtest.NewSigner(t)
+	sig, err := signingtest.NewSigner(t).Sign(u.sha256Sums())
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.signature = sig
+	s := newTestServer(t, u, Config{Upstreams: []string{u.host()}, Allow: []string{"hashicorp"}})
+
+	if rec := get(s, "/"+u.host()+"/hashicorp/null/3.2.3.json"); rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 for a bad upstream signature, got %d", rec.Code)
+	}
+	if _, err := os.Stat(filepath.Join(s.config.MirrorDir, mirror.LockFileName)); !os.IsNotExist(err) {
+		t.Errorf("expected nothing written to the mirror, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package signing
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// gpgProgram is the gpg executable GPG signers run
+var gpgProgram = "gpg"
+
+// gpgSigner signs through gpg, so the private key can stay in the gpg agent
+// or on a smartcard
+type gpgSigner struct {
+	user      string
+	keyID     string
+	publicKey string
+}
+
+// NewGPGSigner signs with a key of the gpg keyring, selected by key ID,
+// fingerprint or user ID
+func NewGPGSigner(user string) (Signer, error) {
+	publicKey, err := runGPG(nil, "--armor", "--export", user)
+	if err != nil {
+		return nil, err
+	}
+	if len(publicKey) == 0 {
+		return nil, fmt.Errorf("no key %q in the gpg keyring", user)
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
+	if err != nil {
+		return nil, fmt.Errorf("parsing gpg key %q: %w", user, err)
+	}
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("gpg key %q is ambiguous: it matches %d keys", user, len(entities))
+	}
+
+	return &gpgSigner{
+		user:      user,
+		keyID:     fmt.Sprintf("%016X", entities[0].PrimaryKey.KeyId),
+		publicKey: string(publicKey),
+	}, nil
+}
+
+func (s *gpgSigner) KeyID() string {
+	return s.keyID
+}
+
+func (s *gpgSigner) PublicKey() string {
+	return s.publicKey
+}
+
+func (s *gpgSigner) Sign(data []byte) ([]byte, error) {
+	return runGPG(data, "--local-user", s.user, "--detach-sign")
+}
+
+// runGPG runs gpg non-interactively and returns its output
+func runGPG(stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(gpgProgram, append([]string{"--batch", "--no-tty"}, args...)...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running gpg %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,169 @@
+package signing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// PassphraseEnv names the environment variable holding the passphrase of an
+// encrypted signing key
+const PassphraseEnv = "PM_SIGNING_KEY_PASSPHRASE"
+
+// Signer produces detached OpenPGP signatures
+type Signer interface {
+	// KeyID returns the ID of the signing key as 16 uppercase hex digits
+	KeyID() string
+
+	// PublicKey returns the ASCII-armored public key
+	PublicKey() string
+
+	// Sign returns a binary detached signature of data
+	Sign(data []byte) ([]byte, error)
+}
+
+// keySigner signs with a private key held in memory
+type keySigner struct {
+	entity    *openpgp.Entity
+	publicKey string
+}
+
+// LoadKey reads an ASCII-armored private key. An encrypted key is decrypted
+// with the passphrase in PM_SIGNING_KEY_PASSPHRASE.
+func LoadKey(path string) (Signer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	entities, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing key %s: %w", path, err)
+	}
+	if len(entities) != 1 || entities[0].PrivateKey == nil {
+		return nil, fmt.Errorf("signing key %s must contain exactly one private key", path)
+	}
+	entity := entities[0]
+
+	if entity.PrivateKey.Encrypted {
+		passphrase, ok := os.LookupEnv(PassphraseEnv)
+		if !ok {
+			return nil, fmt.Errorf("signing key %s is encrypted; set %s", path, PassphraseEnv)
+		}
+		if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+			return nil, fmt.Errorf("decrypting signing key: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := entity.Serialize(w); err != nil {
+		return nil, fmt.Errorf("serializing public key: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return &keySigner{entity: entity, publicKey: buf.String() + "\n"}, nil
+}
+
+func (s *keySigner) KeyID() string {
+	return fmt.Sprintf("%016X", s.entity.PrimaryKey.KeyId)
+}
+
+func (s *keySigner) PublicKey() string {
+	return s.publicKey
+}
+
+func (s *keySigner) Sign(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := openpgp.DetachSign(&buf, s.entity, bytes.NewReader(data), nil); err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Verify checks a detached signature (binary or armored) against one or
+// more ASCII-armored public keys and returns the ID of the signing key
+func Verify(publicKeys string, data, signature []byte) (string, error) {
+	keyring, err := readKeyRing(publicKeys)
+	if err != nil {
+		return "", err
+	}
+
+	if block, err := armor.Decode(bytes.NewReader(signature)); err == nil {
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(block.Body); err != nil {
+			return "", fmt.Errorf("reading signature: %w", err)
+		}
+		signature = buf.Bytes()
+	}
+
+	signer, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(data), bytes.NewReader(signature), nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid signature: %w", err)
+	}
+	if signer == nil {
+		return "", errors.New("invalid signature: unknown signer")
+	}
+	return fmt.Sprintf("%016X", signer.PrimaryKey.KeyId), nil
+}
+
+// publicKeyHeader starts an ASCII-armored public key block
+const publicKeyHeader = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+
+// readKeyRing reads concatenated ASCII-armored public key blocks
+func readKeyRing(publicKeys string) (openpgp.EntityList, error) {
+	var keyring openpgp.EntityList
+	blocks := strings.Split(publicKeys, publicKeyHeader)
+	for _, block := range blocks[1:] {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(publicKeyHeader + block))
+		if err != nil {
+			return nil, fmt.Errorf("parsing public key: %w", err)
+		}
+		keyring = append(keyring, entities...)
+	}
+	if len(keyring) == 0 {
+		return nil, errors.New("parsing public key: no public key block found")
+	}
+	return keyring, nil
+}
+
+// ParseSHA256Sums reads a SHA256SUMS file into checksums by filename
+func ParseSHA256Sums(data []byte) map[string]string {
+	checksums := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return checksums
+}
+
+// SHA256Sums formats archive checksums, by filename, in the layout of a
+// release SHA256SUMS file
+func SHA256Sums(checksums map[string]string) []byte {
+	filenames := make([]string, 0, len(checksums))
+	for filename := range checksums {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var buf bytes.Buffer
+	for _, filename := range filenames {
+		fmt.Fprintf(&buf, "%s  %s\n", checksums[filename], filename)
+	}
+	return buf.Bytes()
+}
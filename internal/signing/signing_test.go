@@ -0,0 +1,232 @@
+package signing_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing/signingtest"
+)
+
+// --- signing.LoadKey tests ---
+
+func TestLoadKey_SignAndVerify(t *testing.T) {
+	signer, err := signing.LoadKey(signingtest.WriteKey(t, ""))
+	if err != nil {
+		t.Fatalf("LoadKey() error = %v", err)
+	}
+	if len(signer.KeyID()) != 16 || strings.ToUpper(signer.KeyID()) != signer.KeyID() {
+		t.Errorf("unexpected key ID %q", signer.KeyID())
+	}
+	if !strings.Contains(signer.PublicKey(), "BEGIN PGP PUBLIC KEY BLOCK") ||
+		strings.Contains(signer.PublicKey(), "PRIVATE") {
+		t.Errorf("expected an armored public key, got %q", signer.PublicKey())
+	}
+
+	data := []byte("abc  terraform-provider-null_3.2.3_linux_amd64.zip\n")
+	sig, err := signer.Sign(data)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	keyID, err := signing.Verify(signer.PublicKey(), data, sig)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if keyID != signer.KeyID() {
+		t.Errorf("Verify() key ID = %s, want %s", keyID, signer.KeyID())
+	}
+
+	if _, err := signing.Verify(signer.PublicKey(), []byte("tampered"), sig); err == nil {
+		t.Error("expected error for tampered data")
+	}
+
+	other, err := signing.LoadKey(signingtest.WriteKey(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signing.Verify(other.PublicKey(), data, sig); err == nil {
+		t.Error("expected error for a signature by another key")
+	}
+}
+
+func TestVerify_SeveralKeys(t *testing.T) {
+	first, err := signing.LoadKey(signingtest.WriteKey(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := signing.LoadKey(signingtest.WriteKey(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("data")
+	sig, err := second.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyID, err := signing.Verify(first.PublicKey()+"\n"+second.PublicKey(), data, sig)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if keyID != second.KeyID() {
+		t.Errorf("Verify() key ID = %s, want %s", keyID, second.KeyID())
+	}
+
+	if _, err := signing.Verify("no keys here", data, sig); err == nil {
+		t.Error("expected error without public keys")
+	}
+}
+
+func TestVerify_ArmoredSignature(t *testing.T) {
+	signer, err := signing.LoadKey(signingtest.WriteKey(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("data")
+	sig, err := signer.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.SignatureType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write(sig)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := signing.Verify(signer.PublicKey(), data, buf.Bytes()); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestLoadKey_Encrypted(t *testing.T) {
+	path := signingtest.WriteKey(t, "secret")
+
+	t.Setenv(signing.PassphraseEnv, "wrong")
+	if _, err := signing.LoadKey(path); err == nil {
+		t.Error("expected error for a wrong passphrase")
+	}
+
+	t.Setenv(signing.PassphraseEnv, "secret")
+	signer, err := signing.LoadKey(path)
+	if err != nil {
+		t.Fatalf("LoadKey() error = %v", err)
+	}
+	if _, err := signer.Sign([]byte("data")); err != nil {
+		t.Errorf("Sign() error = %v", err)
+	}
+}
+
+func TestLoadKey_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	public, err := signing.LoadKey(signingtest.WriteKey(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPath := filepath.Join(dir, "public.asc")
+	if err := os.WriteFile(publicPath, []byte(public.PublicKey()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	garbagePath := filepath.Join(dir, "garbage.asc")
+	if err := os.WriteFile(garbagePath, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing.asc"), publicPath, garbagePath} {
+		if _, err := signing.LoadKey(path); err == nil {
+			t.Errorf("expected error for %s", filepath.Base(path))
+		}
+	}
+}
+
+// --- SHA256SUMS tests ---
+
+func TestSHA256Sums(t *testing.T) {
+	got := string(signing.SHA256Sums(map[string]string{
+		"terraform-provider-null_3.2.3_linux_amd64.zip":  "bbb",
+		"terraform-provider-null_3.2.3_darwin_arm64.zip": "aaa",
+	}))
+	want := "aaa  terraform-provider-null_3.2.3_darwin_arm64.zip\n" +
+		"bbb  terraform-provider-null_3.2.3_linux_amd64.zip\n"
+	if got != want {
+		t.Errorf("SHA256Sums() = %q, want %q", got, want)
+	}
+}
+
+func TestParseSHA256Sums(t *testing.T) {
+	data := "AAA  terraform-provider-null_3.2.3_darwin_arm64.zip\n" +
+		"bbb *terraform-provider-null_3.2.3_linux_amd64.zip\n" +
+		"\n" +
+		"malformed\n"
+	got := signing.ParseSHA256Sums([]byte(data))
+
+	want := map[string]string{
+		"terraform-provider-null_3.2.3_darwin_arm64.zip": "aaa",
+		"terraform-provider-null_3.2.3_linux_amd64.zip":  "bbb",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseSHA256Sums() = %v, want %v", got, want)
+	}
+	for filename, sum := range want {
+		if got[filename] != sum {
+			t.Errorf("%s: got %q, want %q", filename, got[filename], sum)
+		}
+	}
+
+	if round := signing.ParseSHA256Sums(signing.SHA256Sums(want)); len(round) != 2 || round["terraform-provider-null_3.2.3_linux_amd64.zip"] != "bbb" {
+		t.Errorf("expected SHA256Sums output to parse back, got %v", round)
+	}
+}
+
+// --- GPG signer tests ---
+
+func TestGPGSigner(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	t.Setenv("GNUPGHOME", t.TempDir())
+	t.Cleanup(func() { _ = exec.Command("gpgconf", "--kill", "gpg-agent").Run() })
+
+	keyPath := signingtest.WriteKey(t, "")
+	if out, err := exec.Command("gpg", "--batch", "--import", keyPath).CombinedOutput(); err != nil {
+		t.Fatalf("importing key: %v: %s", err, out)
+	}
+	want, err := signing.LoadKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := signing.NewGPGSigner("mirror@example.com")
+	if err != nil {
+		t.Fatalf("NewGPGSigner() error = %v", err)
+	}
+	if signer.KeyID() != want.KeyID() {
+		t.Errorf("KeyID() = %s, want %s", signer.KeyID(), want.KeyID())
+	}
+
+	data := []byte("data")
+	sig, err := signer.Sign(data)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if keyID, err := signing.Verify(signer.PublicKey(), data, sig); err != nil || keyID != want.KeyID() {
+		t.Errorf("Verify() = %s, %v; want %s", keyID, err, want.KeyID())
+	}
+
+	if _, err := signing.NewGPGSigner("unknown@example.com"); err == nil {
+		t.Error("expected error for a key missing from the keyring")
+	}
+}
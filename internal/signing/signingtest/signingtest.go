@@ -0,0 +1,65 @@
+// Package signingtest provides OpenPGP keys for tests
+package signingtest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+)
+
+// NewSigner loads a generated unencrypted signing key
+func NewSigner(t testing.TB) signing.Signer {
+	t.Helper()
+	signer, err := signing.LoadKey(WriteKey(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// WriteKey generates an ASCII-armored private key, encrypted with passphrase
+// unless it is empty, and returns the path of the key file. EdDSA keys are
+// used because they are quick to generate.
+func WriteKey(t testing.TB, passphrase string) string {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("Mirror", "", "mirror@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != "" {
+		if err := entity.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != "" {
+		err = entity.SerializePrivateWithoutSigning(w, nil)
+	} else {
+		err = entity.SerializePrivate(w, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.asc")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,172 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change
+const contextLines = 3
+
+// Unified returns a unified diff turning old into new, or an empty string if
+// they are equal. The result can be applied with patch or git apply.
+func Unified(oldName, newName string, old, new []byte) string {
+	ops := diffLines(splitLines(string(old)), splitLines(string(new)))
+
+	var b strings.Builder
+	for _, h := range hunks(ops) {
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(h.oldStart, h.oldCount), hunkRange(h.newStart, h.newCount))
+		for _, o := range h.ops {
+			b.WriteByte(o.kind)
+			b.WriteString(o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// op is a single line of an edit script
+type op struct {
+	kind byte // ' ' unchanged, '-' removed, '+' added
+	line string
+}
+
+// splitLines splits text into lines, each keeping its line terminator
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns a minimal edit script from a to b, based on their
+// longest common subsequence
+func diffLines(a, b []string) []op {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{'+', b[j]})
+	}
+	return ops
+}
+
+// hunk is a group of nearby changes with their surrounding context
+type hunk struct {
+	oldStart, oldCount int
+	newStart, newCount int
+	ops                []op
+}
+
+// hunks groups an edit script into hunks. Changes separated by no more than
+// twice the context are merged into one hunk.
+func hunks(ops []op) []hunk {
+	var result []hunk
+	oldLine, newLine := 1, 1 // line numbers at ops[pos]
+	pos := 0
+
+	advance := func(to int) {
+		for ; pos < to; pos++ {
+			if ops[pos].kind != '+' {
+				oldLine++
+			}
+			if ops[pos].kind != '-' {
+				newLine++
+			}
+		}
+	}
+
+	for {
+		// Find the next change
+		next := pos
+		for next < len(ops) && ops[next].kind == ' ' {
+			next++
+		}
+		if next == len(ops) {
+			return result
+		}
+		advance(max(pos, next-contextLines))
+
+		// Extend the hunk while the following change is close enough
+		end := next
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			k := end
+			for k < len(ops) && ops[k].kind == ' ' {
+				k++
+			}
+			if k == len(ops) || k-end > 2*contextLines {
+				end = min(end+contextLines, len(ops))
+				break
+			}
+			end = k
+		}
+
+		h := hunk{oldStart: oldLine, newStart: newLine, ops: ops[pos:end]}
+		for _, o := range h.ops {
+			if o.kind != '+' {
+				h.oldCount++
+			}
+			if o.kind != '-' {
+				h.newCount++
+			}
+		}
+		result = append(result, h)
+		advance(end)
+	}
+}
+
+// hunkRange formats the start and length of a hunk side
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty side is positioned after the preceding line
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
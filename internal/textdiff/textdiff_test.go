@@ -0,0 +1,116 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// --- Unified tests ---
+
+func TestUnified_Equal(t *testing.T) {
+	if got := Unified("a", "b", []byte("x\ny\n"), []byte("x\ny\n")); got != "" {
+		t.Errorf("expected empty diff, got:\n%s", got)
+	}
+}
+
+func TestUnified_SingleChange(t *testing.T) {
+	old := "providers:\n  - source: hashicorp/aws\n    versions: [\"~> 5.0\"]\n"
+	new := "providers:\n  - source: hashicorp/aws\n    versions: [\"~> 6.1\"]\n"
+
+	want := `--- a/mirror.yaml
++++ b/mirror.yaml
+@@ -1,3 +1,3 @@
+ providers:
+   - source: hashicorp/aws
+-    versions: ["~> 5.0"]
++    versions: ["~> 6.1"]
+`
+	if got := Unified("a/mirror.yaml", "b/mirror.yaml", []byte(old), []byte(new)); got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func numberedLines(n int, replace map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if r, ok := replace[i]; ok {
+			b.WriteString(r + "\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	return b.String()
+}
+
+func TestUnified_Hunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace map[int]string
+		headers []string
+	}{
+		{
+			name:    "close changes share a hunk",
+			replace: map[int]string{5: "five", 10: "ten"},
+			headers: []string{"@@ -2,12 +2,12 @@"},
+		},
+		{
+			name:    "distant changes get separate hunks",
+			replace: map[int]string{5: "five", 20: "twenty"},
+			headers: []string{"@@ -2,7 +2,7 @@", "@@ -17,7 +17,7 @@"},
+		},
+		{
+			name:    "change at the start and end",
+			replace: map[int]string{1: "one", 30: "thirty"},
+			headers: []string{"@@ -1,4 +1,4 @@", "@@ -27,4 +27,4 @@"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				diff := Unified("a", "b", []byte(numberedLines(30, nil)), []byte(numberedLines(30, tt.replace)))
+
+				var headers []string
+				for _, line := range strings.Split(diff, "\n") {
+					if strings.HasPrefix(line, "@@") {
+						headers = append(headers, line)
+					}
+				}
+				if strings.Join(headers, "|") != strings.Join(tt.headers, "|") {
+					t.Errorf("hunk headers = %v, want %v\n%s", headers, tt.headers, diff)
+				}
+			},
+		)
+	}
+}
+
+func TestUnified_InsertAndDelete(t *testing.T) {
+	old := "a\nb\nc\n"
+	new := "a\nc\nd\n"
+
+	want := `--- old
++++ new
+@@ -1,3 +1,3 @@
+ a
+-b
+ c
++d
+`
+	if got := Unified("old", "new", []byte(old), []byte(new)); got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_EmptyAndNoTrailingNewline(t *testing.T) {
+	want := `--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+\ No newline at end of file
+`
+	if got := Unified("old", "new", nil, []byte("a\nb")); got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package verifier
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+)
+
+// verifySignatures checks the signed SHA256SUMS files of a version against
+// the archive checksums in the lock file. publicKey is the organization
+// signing key: the trusted keys, or else the key stored in the mirror.
+func verifySignatures(
+	providerDir string,
+	provider mirror.LockFileProvider,
+	version mirror.LockFileVersion,
+	publicKey string,
+) []string {
+	var errs []string
+	sumsPath := filepath.Join(providerDir, mirror.SHA256SumsFilename(provider.Name, version.Version))
+
+	if version.SigningKeyID != "" {
+		if publicKey == "" {
+			errs = append(errs, fmt.Sprintf(
+				"%s is signed but %s is missing", filepath.Base(sumsPath), mirror.SigningKeyFileName,
+			))
+		} else {
+			errs = append(errs, checkSignedSums(sumsPath, publicKey, version, version.SigningKeyID, true)...)
+		}
+	}
+
+	if version.UpstreamKeyID != "" {
+		keys, err := os.ReadFile(sumsPath + mirror.UpstreamSuffix + mirror.PublicKeySuffix)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("cannot read upstream signing keys: %v", err))
+		} else {
+			errs = append(errs, checkSignedSums(
+				sumsPath+mirror.UpstreamSuffix, string(keys), version, version.UpstreamKeyID, false,
+			)...)
+		}
+	}
+
+	return errs
+}
+
+// checkSignedSums verifies the signature of a SHA256SUMS file and that it
+// lists every archive of the version with its locked checksum. An exact file
+// lists nothing else.
+func checkSignedSums(
+	sumsPath, publicKeys string,
+	version mirror.LockFileVersion,
+	keyID string,
+	exact bool,
+) []string {
+	name := filepath.Base(sumsPath)
+
+	sums, err := os.ReadFile(sumsPath)
+	if err != nil {
+		return []string{fmt.Sprintf("cannot read %s: %v", name, err)}
+	}
+	sig, err := os.ReadFile(sumsPath + mirror.SignatureSuffix)
+	if err != nil {
+		return []string{fmt.Sprintf("cannot read signature of %s: %v", name, err)}
+	}
+
+	signer, err := signing.Verify(publicKeys, sums, sig)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %v", name, err)}
+	}
+	if signer != keyID {
+		return []string{fmt.Sprintf("%s is signed by %s, lock file records %s", name, signer, keyID)}
+	}
+
+	var errs []string
+	checksums := signing.ParseSHA256Sums(sums)
+	for _, platform := range version.Platforms {
+		if sum := checksums[platform.Filename]; sum != platform.SHA256 {
+			errs = append(errs, fmt.Sprintf(
+				"%s lists %q for %s, lock file records %q", name, sum, platform.Filename, platform.SHA256,
+			))
+		}
+	}
+	if exact && len(checksums) != len(version.Platforms) {
+		errs = append(errs, fmt.Sprintf(
+			"%s lists %d archives, lock file records %d", name, len(checksums), len(version.Platforms),
+		))
+	}
+	return errs
+}
@@ -0,0 +1,255 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing/signingtest"
+)
+
+// createSignedMirror creates a valid mirror whose SHA256SUMS is signed by an
+// organization key, with retained upstream checksums signed by another key
+func createSignedMirror(t *testing.T, dir string) (org, upstream signing.Signer) {
+	t.Helper()
+
+	if err := createValidMirror(dir); err != nil {
+		t.Fatal(err)
+	}
+	org, upstream = signingtest.NewSigner(t), signingtest.NewSigner(t)
+
+	lockPath := filepath.Join(dir, mirror.LockFileName)
+	lockFile, err := mirror.ReadLockFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := &lockFile.Providers[0].Versions[0]
+	version.SigningKeyID = org.KeyID()
+	version.UpstreamKeyID = upstream.KeyID()
+	platform := version.Platforms[0]
+
+	sums := signing.SHA256Sums(map[string]string{platform.Filename: platform.SHA256})
+	upstreamSums := signing.SHA256Sums(map[string]string{
+		platform.Filename: platform.SHA256,
+		"terraform-provider-null_3.2.4_windows_amd64.zip": "ccc",
+	})
+	orgSig, err := org.Sign(sums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreamSig, err := upstream.Sign(upstreamSums)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sumsPath := filepath.Join(dir, "registry.terraform.io", "hashicorp", "null", mirror.SHA256SumsFilename("null", "3.2.4"))
+	files := map[string][]byte{
+		filepath.Join(dir, mirror.SigningKeyFileName): []byte(org.PublicKey()),
+		sumsPath:                          sums,
+		sumsPath + mirror.SignatureSuffix: orgSig,
+		sumsPath + mirror.UpstreamSuffix:  upstreamSums,
+		sumsPath + mirror.UpstreamSuffix + mirror.SignatureSuffix: upstreamSig,
+		sumsPath + mirror.UpstreamSuffix + mirror.PublicKeySuffix: []byte(upstream.PublicKey()),
+	}
+	lockData, _ := json.MarshalIndent(lockFile, "", "  ")
+	files[lockPath] = lockData
+	for path, data := range files {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return org, upstream
+}
+
+// --- Signature tests ---
+
+func TestVerify_SignedMirror(t *testing.T) {
+	dir := t.TempDir()
+	createSignedMirror(t, dir)
+
+	result, err := New(dir).Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !result.Valid {
+		t.Errorf("expected Valid to be true, errors: %v", result.Errors)
+	}
+}
+
+func TestVerify_SignatureProblems(t *testing.T) {
+	sumsFile := filepath.Join("registry.terraform.io", "hashicorp", "null", mirror.SHA256SumsFilename("null", "3.2.4"))
+
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, dir string)
+		want   string
+	}{
+		{
+			name: "tampered SHA256SUMS",
+			modify: func(t *testing.T, dir string) {
+				writeTestFile(t, filepath.Join(dir, sumsFile), "0000  terraform-provider-null_3.2.4_linux_amd64.zip\n")
+			},
+			want: "invalid signature",
+		},
+		{
+			name: "missing signature",
+			modify: func(t *testing.T, dir string) {
+				_ = os.Remove(filepath.Join(dir, sumsFile+mirror.SignatureSuffix))
+			},
+			want: "cannot read signature",
+		},
+		{
+			name: "missing signing key",
+			modify: func(t *testing.T, dir string) {
+				_ = os.Remove(filepath.Join(dir, mirror.SigningKeyFileName))
+			},
+			want: mirror.SigningKeyFileName + " is missing",
+		},
+		{
+			name: "signed by another key",
+			modify: func(t *testing.T, dir string) {
+				other := signingtest.NewSigner(t)
+				sums, _ := os.ReadFile(filepath.Join(dir, sumsFile))
+				sig, err := other.Sign(sums)
+				if err != nil {
+					t.Fatal(err)
+				}
+				writeTestFile(t, filepath.Join(dir, sumsFile+mirror.SignatureSuffix), string(sig))
+				writeTestFile(t, filepath.Join(dir, mirror.SigningKeyFileName), other.PublicKey())
+			},
+			want: "lock file records",
+		},
+		{
+			name: "tampered upstream SHA256SUMS",
+			modify: func(t *testing.T, dir string) {
+				writeTestFile(t, filepath.Join(dir, sumsFile+mirror.UpstreamSuffix), "tampered\n")
+			},
+			want: "invalid signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			createSignedMirror(t, dir)
+			tt.modify(t, dir)
+
+			result, err := New(dir).Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if result.Valid {
+				t.Fatal("expected Valid to be false")
+			}
+			if !strings.Contains(strings.Join(result.Errors, "\n"), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, result.Errors)
+			}
+		})
+	}
+}
+
+// signLock signs the mirror's lock file
+func signLock(t *testing.T, dir string, signer signing.Signer) {
+	t.Helper()
+	lockData, err := os.ReadFile(filepath.Join(dir, mirror.LockFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signer.Sign(lockData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, mirror.LockSignatureFileName), string(sig))
+}
+
+// --- Trusted key tests ---
+
+func TestVerify_TrustedKey(t *testing.T) {
+	dir := t.TempDir()
+	org, _ := createSignedMirror(t, dir)
+	signLock(t, dir, org)
+
+	// The key in the mirror is ignored in favor of the trusted one
+	writeTestFile(t, filepath.Join(dir, mirror.SigningKeyFileName), signingtest.NewSigner(t).PublicKey())
+
+	other := signingtest.NewSigner(t)
+	result, err := New(dir).WithTrustedKeys(other.PublicKey() + org.PublicKey()).Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !result.Valid {
+		t.Errorf("expected Valid to be true, errors: %v", result.Errors)
+	}
+	if result.SignedBy != org.KeyID() {
+		t.Errorf("SignedBy = %q, want %q", result.SignedBy, org.KeyID())
+	}
+}
+
+func TestVerify_TrustedKeyRefusesLockFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, dir string, org signing.Signer)
+		want   string
+	}{
+		{
+			name:   "unsigned",
+			modify: func(t *testing.T, dir string, org signing.Signer) {},
+			want:   "mirror.lock is not signed",
+		},
+		{
+			name: "signed by an untrusted key",
+			modify: func(t *testing.T, dir string, org signing.Signer) {
+				signLock(t, dir, signingtest.NewSigner(t))
+			},
+			want: "not signed by a trusted key",
+		},
+		{
+			name: "edited after signing",
+			modify: func(t *testing.T, dir string, org signing.Signer) {
+				signLock(t, dir, org)
+				lockPath := filepath.Join(dir, mirror.LockFileName)
+				data, _ := os.ReadFile(lockPath)
+				writeTestFile(t, lockPath, strings.Replace(string(data), "2024-01-01", "2024-01-02", 1))
+			},
+			want: "not signed by a trusted key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			org, _ := createSignedMirror(t, dir)
+			tt.modify(t, dir, org)
+
+			// A missing archive is not reported: nothing is checked past the lock file
+			providerDir := filepath.Join(dir, "registry.terraform.io", "hashicorp", "null")
+			_ = os.Remove(filepath.Join(providerDir, "terraform-provider-null_3.2.4_linux_amd64.zip"))
+
+			result, err := New(dir).WithTrustedKeys(org.PublicKey()).Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if result.Valid {
+				t.Fatal("expected Valid to be false")
+			}
+			if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], tt.want) {
+				t.Errorf("expected only an error containing %q, got %v", tt.want, result.Errors)
+			}
+			if result.ProviderCount != 0 {
+				t.Errorf("expected no providers checked, got %d", result.ProviderCount)
+			}
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -12,11 +12,13 @@ import (
 	"strings"
 
 	"github.com/petroprotsakh/go-provider-mirror/internal/mirror"
+	"github.com/petroprotsakh/go-provider-mirror/internal/signing"
 )
 
 // Verifier validates provider mirror
 type Verifier struct {
-	mirrorDir string
+	mirrorDir   string
+	trustedKeys string
 }
 
 // New creates a new verifier
@@ -26,6 +28,14 @@ func New(mirrorDir string) *Verifier {
 	}
 }
 
+// WithTrustedKeys makes the verifier require mirror.lock to be signed by one
+// of the ASCII-armored public keys. The keys also verify the SHA256SUMS
+// signatures in place of the key stored in the mirror.
+func (v *Verifier) WithTrustedKeys(keys string) *Verifier {
+	v.trustedKeys = keys
+	return v
+}
+
 // Result represents the verification result
 type Result struct {
 	Valid         bool
@@ -33,6 +43,7 @@ type Result struct {
 	ProviderCount int
 	VersionCount  int
 	FileCount     int
+	SignedBy      string // ID of the trusted key that signed mirror.lock
 }
 
 // Verify validates the mirror
@@ -55,6 +66,23 @@ func (v *Verifier) Verify(ctx context.Context) (*Result, error) {
 		return result, nil
 	}
 
+	// Refuse an unsigned or mis-signed lock file before trusting its contents
+	if v.trustedKeys != "" {
+		sig, err := os.ReadFile(filepath.Join(v.mirrorDir, mirror.LockSignatureFileName))
+		if err != nil {
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("mirror.lock is not signed: %v", err))
+			return result, nil
+		}
+		keyID, err := signing.Verify(v.trustedKeys, lockData, sig)
+		if err != nil {
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("mirror.lock is not signed by a trusted key: %v", err))
+			return result, nil
+		}
+		result.SignedBy = keyID
+	}
+
 	var lockFile mirror.LockFile
 	if err := json.Unmarshal(lockData, &lockFile); err != nil {
 		result.Valid = false
@@ -62,6 +90,14 @@ func (v *Verifier) Verify(ctx context.Context) (*Result, error) {
 		return result, nil
 	}
 
+	// The organization signing key, if the mirror was signed
+	publicKey := v.trustedKeys
+	if publicKey == "" {
+		if data, err := os.ReadFile(filepath.Join(v.mirrorDir, mirror.SigningKeyFileName)); err == nil {
+			publicKey = string(data)
+		}
+	}
+
 	// Verify each provider
 	for _, provider := range lockFile.Providers {
 		// Check for cancellation
@@ -143,6 +179,11 @@ func (v *Verifier) Verify(ctx context.Context) (*Result, error) {
 				continue
 			}
 
+			if errs := verifySignatures(providerDir, provider, version, publicKey); len(errs) > 0 {
+				result.Valid = false
+				result.Errors = append(result.Errors, errs...)
+			}
+
 			// Verify each platform
 			for _, platform := range version.Platforms {
 				result.FileCount++